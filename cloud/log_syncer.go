@@ -74,7 +74,7 @@ func (s *LogSyncer) NewBuffer(channel LogChannel, out io.Writer) io.Writer {
 		var pending []byte
 		errs := errors.L()
 		for {
-			lines, rest, readErr := readLines(r, pending)
+			lines, rest, readErr := ReadLines(r, pending)
 			if readErr != nil && readErr != io.EOF {
 				errs.Append(readErr)
 				break
@@ -173,7 +173,13 @@ func (s *LogSyncer) enqueue(l *DeploymentLog) {
 	}
 }
 
-func readLines(r io.Reader, pending []byte) (line [][]byte, rest []byte, err error) {
+// ReadLines reads from r until at least one full line is available and returns
+// all the full lines read (including the line terminator) and the rest of the
+// data read that doesn't form a full line yet. The pending is the rest returned
+// by a previous call and it's prepended to the data read.
+// When the reader reaches EOF, the returned error is io.EOF and rest contains
+// the last line, if it has no line terminator.
+func ReadLines(r io.Reader, pending []byte) (line [][]byte, rest []byte, err error) {
 	const readSize = 1024
 
 	var buf [readSize]byte
@@ -11,7 +11,9 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
 	"strings"
+	"text/template"
 	"time"
 
 	"github.com/google/uuid"
@@ -113,6 +115,11 @@ type cliSpec struct {
 	DisableCheckGitUntracked   bool `optional:"true" default:"false" help:"Disable git check for untracked files"`
 	DisableCheckGitUncommitted bool `optional:"true" default:"false" help:"Disable git check for uncommitted files"`
 
+	ChangedIncludeWorktree bool `optional:"true" default:"false" help:"Consider the staged, uncommitted and untracked files as changed in --changed"`
+
+	IncludeDependents   bool `optional:"true" default:"false" help:"Include the stacks that must run after the selected stacks, transitively"`
+	IncludeDependencies bool `optional:"true" default:"false" help:"Include the stacks that must run before the selected stacks, transitively"`
+
 	DisableCheckpoint          bool `optional:"true" default:"false" help:"Disable checkpoint checks for updates"`
 	DisableCheckpointSignature bool `optional:"true" default:"false" help:"Disable checkpoint signature"`
 
@@ -128,6 +135,9 @@ type cliSpec struct {
 		AllTerraform   bool     `help:"initialize all Terraform directories containing terraform.backend blocks defined"`
 		EnsureStackIds bool     `help:"generate an UUID for the stack.id of all stacks which does not define it"`
 		NoGenerate     bool     `help:"Disable code generation for the newly created stacks"`
+
+		Template string            `predictor:"file" help:"Create the stack from the files of the given template directory"`
+		Var      map[string]string `mapsep:"none" help:"Set a template variable, available as var.<name> in the template files"`
 	} `cmd:"" help:"Creates a stack on the project"`
 
 	Fmt struct {
@@ -137,22 +147,59 @@ type cliSpec struct {
 	List struct {
 		Why                bool   `help:"Shows the reason why the stack has changed"`
 		ExperimentalStatus string `help:"Filter by status"`
+
+		Format   string   `default:"text" enum:"text,json,yaml,csv" help:"Output format: 'text', 'json', 'yaml' or 'csv'"`
+		Fields   []string `help:"Fields of the json, yaml and csv formats: id, name, description, tags, path, after, before, wants, watch and reason"`
+		Template string   `help:"Go template used to print a line for each stack"`
 	} `cmd:"" help:"List stacks"`
 
 	Run struct {
-		CloudSyncDeployment        bool     `default:"false" help:"Enable synchronization of stack execution with the Terramate Cloud"`
-		CloudSyncDriftStatus       bool     `default:"false" help:"Enable drift detection and synchronization with the Terramate Cloud"`
-		CloudSyncTerraformPlanFile string   `default:"" help:"Enable sync of Terraform plan file"`
-		DisableCheckGenCode        bool     `default:"false" help:"Disable outdated generated code check"`
-		DisableCheckGitRemote      bool     `default:"false" help:"Disable checking if local default branch is updated with remote"`
-		ContinueOnError            bool     `default:"false" help:"Continue executing in other stacks in case of error"`
-		NoRecursive                bool     `default:"false" help:"Do not recurse into child stacks"`
-		DryRun                     bool     `default:"false" help:"Plan the execution but do not execute it"`
-		Reverse                    bool     `default:"false" help:"Reverse the order of execution"`
-		Eval                       bool     `default:"false" help:"Evaluate command line arguments as HCL strings"`
-		Command                    []string `arg:"" name:"cmd" predictor:"file" passthrough:"" help:"Command to execute"`
+		CloudSyncDeployment        bool          `default:"false" help:"Enable synchronization of stack execution with the Terramate Cloud"`
+		CloudSyncDriftStatus       bool          `default:"false" help:"Enable drift detection and synchronization with the Terramate Cloud"`
+		CloudSyncTerraformPlanFile string        `default:"" help:"Enable sync of Terraform plan file"`
+		DisableCheckGenCode        bool          `default:"false" help:"Disable outdated generated code check"`
+		DisableCheckGitRemote      bool          `default:"false" help:"Disable checking if local default branch is updated with remote"`
+		ContinueOnError            bool          `default:"false" help:"Continue executing in other stacks in case of error"`
+		NoRecursive                bool          `default:"false" help:"Do not recurse into child stacks"`
+		DryRun                     bool          `default:"false" help:"Plan the execution but do not execute it"`
+		Reverse                    bool          `default:"false" help:"Reverse the order of execution"`
+		Eval                       bool          `default:"false" help:"Evaluate command line arguments as HCL strings"`
+		Parallel                   int           `short:"j" default:"1" help:"Run independent stacks in parallel, with at most N stacks running at the same time"`
+		PrefixOutput               bool          `default:"false" help:"Prefix each line of the stacks output with the stack path"`
+		OutputDir                  string        `predictor:"file" default:"" help:"Write the combined output of each stack to <dir>/<stack path>/output.log"`
+		Resume                     bool          `default:"false" help:"Resume the last run, running only the stacks that didn't succeed"`
+		ReportJSON                 string        `predictor:"file" default:"" help:"Write a JSON report of the execution to the given file"`
+		RetryMaxAttempts           int           `default:"0" help:"Maximum number of attempts of each stack command, including the first one"`
+		RetryBackoff               time.Duration `default:"0s" help:"Time to wait before retrying a failed stack command, doubled on each retry"`
+		RetryOn                    []string      `help:"Only retry the failures which stderr matches the regular expression"`
+		Timeout                    time.Duration `default:"0s" help:"Maximum time a command can run in each stack, zero means no timeout"`
+		TimeoutInterruptGrace      time.Duration `default:"0s" help:"Time to wait after sending SIGINT to a timed out command before sending SIGTERM"`
+		TimeoutTerminateGrace      time.Duration `default:"0s" help:"Time to wait after sending SIGTERM to a timed out command before sending SIGKILL"`
+		Command                    []string      `arg:"" name:"cmd" predictor:"file" passthrough:"" help:"Command to execute"`
 	} `cmd:"" help:"Run command in the stacks"`
 
+	Script struct {
+		Run struct {
+			CloudSyncDeployment        bool          `default:"false" help:"Enable synchronization of stack execution with the Terramate Cloud"`
+			CloudSyncDriftStatus       bool          `default:"false" help:"Enable drift detection and synchronization with the Terramate Cloud"`
+			CloudSyncTerraformPlanFile string        `default:"" help:"Enable sync of Terraform plan file"`
+			DisableCheckGenCode        bool          `default:"false" help:"Disable outdated generated code check"`
+			DisableCheckGitRemote      bool          `default:"false" help:"Disable checking if local default branch is updated with remote"`
+			ContinueOnError            bool          `default:"false" help:"Continue executing in other stacks in case of error"`
+			NoRecursive                bool          `default:"false" help:"Do not recurse into child stacks"`
+			DryRun                     bool          `default:"false" help:"Plan the execution but do not execute it"`
+			Reverse                    bool          `default:"false" help:"Reverse the order of execution"`
+			Parallel                   int           `short:"j" default:"1" help:"Run independent stacks in parallel, with at most N stacks running at the same time"`
+			RetryMaxAttempts           int           `default:"0" help:"Maximum number of attempts of each stack command, including the first one"`
+			RetryBackoff               time.Duration `default:"0s" help:"Time to wait before retrying a failed stack command, doubled on each retry"`
+			RetryOn                    []string      `help:"Only retry the failures which stderr matches the regular expression"`
+			Timeout                    time.Duration `default:"0s" help:"Maximum time a command can run in each stack, zero means no timeout"`
+			TimeoutInterruptGrace      time.Duration `default:"0s" help:"Time to wait after sending SIGINT to a timed out command before sending SIGTERM"`
+			TimeoutTerminateGrace      time.Duration `default:"0s" help:"Time to wait after sending SIGTERM to a timed out command before sending SIGKILL"`
+			Name                       string        `arg:"" name:"name" help:"Name of the script"`
+		} `cmd:"" help:"Run a script in the stacks"`
+	} `cmd:"" help:"Terramate script commands"`
+
 	Generate struct{} `cmd:"" help:"Generate terraform code for stacks"`
 
 	InstallCompletions kongplete.InstallCompletions `cmd:"" help:"Install shell completions"`
@@ -163,11 +210,35 @@ type cliSpec struct {
 			DestDir string `arg:"" name:"destdir" predictor:"file" help:"Path of the new stack"`
 		} `cmd:"" help:"Clones a stack"`
 
+		Stack struct {
+			Move struct {
+				Src string `arg:"" name:"src" predictor:"file" help:"Path of the stack being moved"`
+				Dst string `arg:"" name:"dst" predictor:"file" help:"New path of the stack"`
+			} `cmd:"" help:"Moves a stack and rewrites the references to it"`
+
+			Delete struct {
+				Path         string `arg:"" name:"path" predictor:"file" help:"Path of the stack being deleted"`
+				Recursive    bool   `help:"Delete the child stacks too"`
+				NonStackDirs bool   `help:"Delete the directories inside the stacks which are not stacks too"`
+				ForceRewrite bool   `help:"Remove the references to the deleted stacks from the other stacks"`
+			} `cmd:"" help:"Deletes a stack if no other stacks refer to it"`
+		} `cmd:"" help:"Experimental stack commands"`
+
 		Trigger struct {
-			Stack              string `arg:"" optional:"true" name:"stack" predictor:"file" help:"Path of the stack being triggered"`
-			Reason             string `default:"" name:"reason" help:"Reason for the stack being triggered"`
-			ExperimentalStatus string `help:"Filter by status"`
-		} `cmd:"" help:"Triggers a stack"`
+			Create struct {
+				Stack              string        `arg:"" optional:"true" name:"stack" predictor:"file" help:"Path of the stack being triggered"`
+				Reason             string        `default:"" name:"reason" help:"Reason for the stack being triggered"`
+				ExpiresIn          time.Duration `help:"Duration after which the trigger expires and is ignored by the change detection"`
+				ExperimentalStatus string        `help:"Filter by status"`
+			} `cmd:"" default:"withargs" help:"Triggers a stack"`
+
+			List struct{} `cmd:"" help:"List the triggers of the project"`
+
+			Clean struct {
+				OlderThan time.Duration `help:"Remove the triggers created before the given duration"`
+				Stack     string        `predictor:"file" help:"Remove the triggers of the given stack"`
+			} `cmd:"" help:"Remove the expired triggers and the triggers matching the filters"`
+		} `cmd:"" help:"Manage stack triggers"`
 
 		Metadata struct{} `cmd:"" help:"Shows metadata available on the project"`
 
@@ -178,12 +249,16 @@ type cliSpec struct {
 		} `cmd:"" help:"Experimental generate commands"`
 
 		RunGraph struct {
-			Outfile string `short:"o" predictor:"file" default:"" help:"Output .dot file"`
-			Label   string `short:"l" default:"stack.name" help:"Label used in graph nodes (it could be either \"stack.name\" or \"stack.dir\""`
+			Outfile  string `short:"o" predictor:"file" default:"" help:"Output file"`
+			Label    string `short:"l" default:"stack.name" help:"Label used in graph nodes (it could be either \"stack.name\" or \"stack.dir\""`
+			Format   string `default:"dot" enum:"dot,mermaid,json,graphml" help:"Output format: 'dot', 'mermaid', 'json' or 'graphml'"`
+			Inferred bool   `help:"Show the ordering inferred from the Terraform remote states, even if not enabled in the configuration"`
 		} `cmd:"" help:"Generate a graph of the execution order"`
 
 		RunOrder struct {
-			Basedir string `arg:"" optional:"true" help:"Base directory to search stacks"`
+			Basedir      string `arg:"" optional:"true" help:"Base directory to search stacks"`
+			Levels       bool   `help:"Group the stacks into levels of stacks which can run in parallel"`
+			CriticalPath string `predictor:"file" help:"Show the longest chain of dependent stacks using the durations of the given run report file"`
 		} `cmd:"" help:"Show the topological ordering of the stacks"`
 
 		RunEnv struct{} `cmd:"" help:"List run environment variables for all stacks"`
@@ -259,6 +334,9 @@ type cli struct {
 	httpClient http.Client
 	cloud      cloudConfig
 	uimode     UIMode
+	runState   *runState
+	runReport  *runReport
+	cloudSync  cloudSyncOptions
 
 	checkpointResults chan *checkpoint.CheckResponse
 
@@ -473,6 +551,10 @@ func newCLI(version string, args []string, stdin io.Reader, stdout, stderr io.Wr
 		log.Fatal().Msg("flag --changed provided but no git repository found")
 	}
 
+	if parsedArgs.ChangedIncludeWorktree && !parsedArgs.Changed {
+		log.Fatal().Msg("flag --changed-include-worktree must be used together with --changed")
+	}
+
 	uimode := HumanMode
 	if val := os.Getenv("CI"); envVarIsSet(val) {
 		uimode = AutomationMode
@@ -530,14 +612,25 @@ func (c *cli) run() {
 	case "run <cmd>":
 		c.setupGit()
 		c.runOnStacks()
+	case "script run <name>":
+		c.setupGit()
+		c.runScript()
 	case "generate":
 		c.generate()
 	case "experimental clone <srcdir> <destdir>":
 		c.cloneStack()
-	case "experimental trigger":
+	case "experimental stack move <src> <dst>":
+		c.moveStack()
+	case "experimental stack delete <path>":
+		c.deleteStack()
+	case "experimental trigger create":
 		c.triggerStackByFilter()
-	case "experimental trigger <stack>":
-		c.triggerStack(c.parsedArgs.Experimental.Trigger.Stack)
+	case "experimental trigger create <stack>":
+		c.triggerStack(c.parsedArgs.Experimental.Trigger.Create.Stack)
+	case "experimental trigger list":
+		c.listTriggers()
+	case "experimental trigger clean":
+		c.cleanTriggers()
 	case "experimental vendor download <source> <ref>":
 		c.vendorDownload()
 	case "experimental globals":
@@ -727,12 +820,12 @@ func hasVendorDirConfig(cfg hcl.Config) bool {
 }
 
 func (c *cli) triggerStackByFilter() {
-	if c.parsedArgs.Experimental.Trigger.ExperimentalStatus == "" {
+	if c.parsedArgs.Experimental.Trigger.Create.ExperimentalStatus == "" {
 		fatal(errors.E("trigger command expects either a stack path or the --experimental-status flag"))
 	}
 
 	mgr := stack.NewManager(c.cfg(), c.prj.baseRef)
-	status := parseStatusFilter(c.parsedArgs.Experimental.Trigger.ExperimentalStatus)
+	status := parseStatusFilter(c.parsedArgs.Experimental.Trigger.Create.ExperimentalStatus)
 	stacksReport, err := c.listStacks(mgr, false, status)
 	if err != nil {
 		fatal(err)
@@ -744,7 +837,7 @@ func (c *cli) triggerStackByFilter() {
 }
 
 func (c *cli) triggerStack(stack string) {
-	reason := c.parsedArgs.Experimental.Trigger.Reason
+	reason := c.parsedArgs.Experimental.Trigger.Create.Reason
 	if reason == "" {
 		reason = "Created using Terramate CLI without setting specific reason."
 	}
@@ -770,14 +863,90 @@ func (c *cli) triggerStack(stack string) {
 		errlog.Fatal(logger, errors.E("stack %s is outside project", stack))
 	}
 
+	var expiresAt time.Time
+	if expiresIn := c.parsedArgs.Experimental.Trigger.Create.ExpiresIn; expiresIn != 0 {
+		if expiresIn < 0 {
+			errlog.Fatal(logger, errors.E("flag --expires-in must be a positive duration"))
+		}
+		expiresAt = time.Now().Add(expiresIn)
+	}
+
 	stackPath := prj.PrjAbsPath(c.rootdir(), stack)
-	if err := trigger.Create(c.cfg(), stackPath, reason); err != nil {
+	if err := trigger.CreateWithExpiration(c.cfg(), stackPath, reason, expiresAt); err != nil {
 		errlog.Fatal(logger, err)
 	}
 
 	c.output.MsgStdOut("Created trigger for stack %q", stackPath)
 }
 
+func (c *cli) listTriggers() {
+	triggers, err := trigger.List(c.cfg())
+	if err != nil {
+		fatal(err, "listing triggers")
+	}
+
+	now := time.Now()
+	for _, t := range triggers {
+		age := t.Info.Age(now).Round(time.Second).String()
+		if t.Info.Expired(now) {
+			age += " (expired)"
+		}
+		c.output.MsgStdOut("%s\t%s\t%s", t.Stack, age, t.Info.Reason)
+	}
+}
+
+func (c *cli) cleanTriggers() {
+	logger := log.With().
+		Str("action", "cli.cleanTriggers()").
+		Logger()
+
+	olderThan := c.parsedArgs.Experimental.Trigger.Clean.OlderThan
+	if olderThan < 0 {
+		fatal(errors.E("flag --older-than must be a positive duration"))
+	}
+
+	var stackPath prj.Path
+	filterStack := c.parsedArgs.Experimental.Trigger.Clean.Stack != ""
+	if filterStack {
+		stack := c.parsedArgs.Experimental.Trigger.Clean.Stack
+		if path.IsAbs(stack) {
+			stackPath = prj.NewPath(stack)
+		} else {
+			stackPath = prj.PrjAbsPath(c.rootdir(), filepath.Join(c.wd(), filepath.FromSlash(stack)))
+		}
+	}
+
+	triggers, err := trigger.List(c.cfg())
+	if err != nil {
+		fatal(err, "listing triggers")
+	}
+
+	now := time.Now()
+	for _, t := range triggers {
+		if filterStack && t.Stack != stackPath {
+			continue
+		}
+		remove := t.Info.Expired(now)
+		switch {
+		case olderThan != 0:
+			remove = remove || t.Info.Age(now) > olderThan
+		case filterStack:
+			remove = true
+		}
+		if !remove {
+			logger.Debug().
+				Stringer("trigger", t.Path).
+				Msg("keeping trigger")
+			continue
+		}
+
+		if err := trigger.Remove(c.cfg(), t); err != nil {
+			fatal(err, "cleaning triggers")
+		}
+		c.output.MsgStdOut("Removed trigger %s of stack %s", t.Path, t.Stack)
+	}
+}
+
 func (c *cli) cloneStack() {
 	srcstack := c.parsedArgs.Experimental.Clone.SrcDir
 	deststack := c.parsedArgs.Experimental.Clone.DestDir
@@ -803,6 +972,98 @@ func (c *cli) cloneStack() {
 	c.generate()
 }
 
+func (c *cli) moveStack() {
+	src := c.projectPathArg(c.parsedArgs.Experimental.Stack.Move.Src)
+	dst := c.projectPathArg(c.parsedArgs.Experimental.Stack.Move.Dst)
+
+	logger := log.With().
+		Str("action", "cli.moveStack()").
+		Stringer("src", src).
+		Stringer("dst", dst).
+		Logger()
+
+	logger.Trace().Msg("moving stack")
+
+	report, err := stack.Move(c.cfg(), src, dst)
+	if err != nil {
+		fatal(err, "moving stack %s to %s", src, dst)
+	}
+
+	c.output.MsgStdOut("Moved stack %s to %s", src, dst)
+	for _, file := range report.UpdatedFiles {
+		c.output.MsgStdOut("Updated stack references in %s", file)
+	}
+	if report.MovedTriggers {
+		c.output.MsgStdOut("Moved triggers of stack %s to %s", src, dst)
+	}
+
+	root, err := config.LoadRoot(c.rootdir())
+	if err != nil {
+		fatal(err, "reloading the configuration")
+	}
+
+	c.prj.root = *root
+
+	c.output.MsgStdOut("Generating code on the moved stack")
+
+	c.generate()
+}
+
+func (c *cli) deleteStack() {
+	dir := c.projectPathArg(c.parsedArgs.Experimental.Stack.Delete.Path)
+
+	logger := log.With().
+		Str("action", "cli.deleteStack()").
+		Stringer("stack", dir).
+		Logger()
+
+	logger.Trace().Msg("deleting stack")
+
+	report, err := stack.Delete(c.cfg(), dir, stack.DeleteOptions{
+		Recursive:    c.parsedArgs.Experimental.Stack.Delete.Recursive,
+		NonStackDirs: c.parsedArgs.Experimental.Stack.Delete.NonStackDirs,
+		ForceRewrite: c.parsedArgs.Experimental.Stack.Delete.ForceRewrite,
+	})
+	if err != nil {
+		switch {
+		case errors.IsKind(err, stack.ErrStackReferenced):
+			fatal(err, "stack %s is referenced by other stacks (use --force-rewrite to remove the references)", dir)
+		case errors.IsKind(err, stack.ErrStackHasChildren):
+			fatal(err, "stack %s has child stacks (use --recursive to delete them too)", dir)
+		case errors.IsKind(err, stack.ErrStackHasNonStackDirs):
+			fatal(err, "stack %s has non-stack directories (use --non-stack-dirs to delete them too)", dir)
+		}
+		fatal(err, "deleting stack %s", dir)
+	}
+
+	for _, file := range report.UpdatedFiles {
+		c.output.MsgStdOut("Removed stack references in %s", file)
+	}
+	if report.RemovedTriggers {
+		c.output.MsgStdOut("Removed triggers of stack %s", dir)
+	}
+	for _, st := range report.DeletedStacks {
+		c.output.MsgStdOut("Deleted stack %s", st)
+	}
+}
+
+// projectPathArg returns the project path of the path given in the command
+// line, which is either relative to the working dir or absolute to the project
+// root.
+func (c *cli) projectPathArg(arg string) prj.Path {
+	var abspath string
+	if path.IsAbs(arg) {
+		abspath = filepath.Join(c.rootdir(), filepath.FromSlash(arg))
+	} else {
+		abspath = filepath.Join(c.wd(), filepath.FromSlash(arg))
+	}
+	if abspath != c.rootdir() &&
+		!strings.HasPrefix(abspath, c.rootdir()+string(filepath.Separator)) {
+		fatal(errors.E("path %s is outside project", arg))
+	}
+	return prj.PrjAbsPath(c.rootdir(), abspath)
+}
+
 func (c *cli) generate() {
 	report, vendorReport := c.gencodeWithVendor()
 
@@ -914,6 +1175,11 @@ func (c *cli) gitFileSafeguards(shouldAbort bool) {
 	debugFiles(c.prj.git.repoChecks.UntrackedFiles, "untracked file")
 	debugFiles(c.prj.git.repoChecks.UncommittedFiles, "uncommitted file")
 
+	if c.parsedArgs.Changed && c.parsedArgs.ChangedIncludeWorktree {
+		// the uncommitted and untracked files are explicitly part of the changes.
+		return
+	}
+
 	if c.checkGitUntracked() && len(c.prj.git.repoChecks.UntrackedFiles) > 0 {
 		const msg = "repository has untracked files"
 		if shouldAbort {
@@ -965,6 +1231,7 @@ func (c *cli) listStacks(mgr *stack.Manager, isChanged bool, status cloudstack.F
 			Str("workingDir", c.wd()).
 			Msg("Listing changed stacks")
 
+		mgr.IncludeWorktree(c.parsedArgs.ChangedIncludeWorktree)
 		report, err = mgr.ListChanged()
 	} else {
 		log.Trace().
@@ -1046,12 +1313,14 @@ func (c *cli) scanCreate() {
 		c.parsedArgs.Create.Path != "" ||
 		c.parsedArgs.Create.Description != "" ||
 		c.parsedArgs.Create.IgnoreExisting ||
+		c.parsedArgs.Create.Template != "" ||
+		len(c.parsedArgs.Create.Var) != 0 ||
 		len(c.parsedArgs.Create.After) != 0 ||
 		len(c.parsedArgs.Create.Before) != 0 ||
 		len(c.parsedArgs.Create.Import) != 0 {
 
 		fatal(errors.E(
-			"The %s flag is incompatible with path and the flags: --id, --name, --description, --after, --before, --import and --ignore-existing",
+			"The %s flag is incompatible with path and the flags: --id, --name, --description, --after, --before, --import, --template, --var and --ignore-existing",
 			flagname,
 		))
 	}
@@ -1233,7 +1502,24 @@ func (c *cli) createStack() {
 		Tags:        tags,
 	}
 
-	err := stack.Create(c.cfg(), stackSpec, c.parsedArgs.Create.Import...)
+	if len(c.parsedArgs.Create.Var) != 0 && c.parsedArgs.Create.Template == "" {
+		fatal(errors.E("flag --var must be used together with --template"))
+	}
+
+	var err error
+	if c.parsedArgs.Create.Template != "" {
+		templateDir := c.parsedArgs.Create.Template
+		if !filepath.IsAbs(templateDir) {
+			templateDir = filepath.Join(c.wd(), templateDir)
+		}
+		if _, err := os.Stat(filepath.Join(templateDir, stack.DefaultFilename)); err == nil {
+			c.checkNoStackFileFlags()
+		}
+		err = stack.CreateFromTemplate(c.cfg(), stackSpec, templateDir,
+			c.parsedArgs.Create.Var, c.parsedArgs.Create.Import...)
+	} else {
+		err = stack.Create(c.cfg(), stackSpec, c.parsedArgs.Create.Import...)
+	}
 	if err != nil {
 		logger := log.With().
 			Stringer("stack", stackSpec.Dir).
@@ -1286,6 +1572,28 @@ func (c *cli) createStack() {
 	c.output.MsgStdOutV(vendorReport.String())
 }
 
+// checkNoStackFileFlags fails if any of the flags defining the stack file
+// attributes are given, as they can't be applied when the template provides
+// the stack file.
+func (c *cli) checkNoStackFileFlags() {
+	args := c.parsedArgs.Create
+	for _, flag := range []struct {
+		name string
+		set  bool
+	}{
+		{"--name", args.Name != ""},
+		{"--description", args.Description != ""},
+		{"--tags", len(c.parsedArgs.Tags) > 0},
+		{"--after", len(args.After) > 0},
+		{"--before", len(args.Before) > 0},
+	} {
+		if flag.set {
+			fatal(errors.E("flag %s can't be used with a template providing the %s file, define it in the template instead",
+				flag.name, stack.DefaultFilename))
+		}
+	}
+}
+
 func (c *cli) format() {
 	logger := log.With().
 		Str("workingDir", c.wd()).
@@ -1337,7 +1645,41 @@ func (c *cli) printStacks() {
 		log.Fatal().Msg("the --why flag must be used together with --changed")
 	}
 
+	format := c.parsedArgs.List.Format
+	structured := format != "text" || c.parsedArgs.List.Template != ""
+	if c.parsedArgs.List.Why && structured {
+		fatal(errors.E("the --why flag can't be used together with --format or --template, use the reason field instead"))
+	}
+	if len(c.parsedArgs.List.Fields) > 0 && format == "text" {
+		fatal(errors.E("the --fields flag requires the json, yaml or csv --format"))
+	}
+	if c.parsedArgs.List.Template != "" && format != "text" {
+		fatal(errors.E("the --template and --format flags are incompatible"))
+	}
+
+	fields, err := parseListFields(c.parsedArgs.List.Fields)
+	if err != nil {
+		fatal(err, "parsing --fields")
+	}
+
+	var tmpl *template.Template
+	if c.parsedArgs.List.Template != "" {
+		tmpl, err = parseListTemplate(c.parsedArgs.List.Template)
+		if err != nil {
+			fatal(err, "parsing --template")
+		}
+	}
+
+	// the remote module bumps are only needed to explain the changes.
+	showReason := c.parsedArgs.List.Why || tmpl != nil
+	for _, field := range fields {
+		if structured && field == "reason" {
+			showReason = true
+		}
+	}
+
 	mgr := stack.NewManager(c.cfg(), c.prj.baseRef)
+	mgr.ExplainModuleBumps(showReason)
 
 	status := parseStatusFilter(c.parsedArgs.List.ExperimentalStatus)
 	report, err := c.listStacks(mgr, c.parsedArgs.Changed, status)
@@ -1347,7 +1689,32 @@ func (c *cli) printStacks() {
 
 	c.gitFileSafeguards(false)
 
-	for _, entry := range c.filterStacks(report.Stacks) {
+	entries, err := c.addOrderedOf(mgr, c.filterStacks(report.Stacks))
+	if err != nil {
+		fatal(err, "adding ordered stacks")
+	}
+
+	if structured {
+		var stacks []listStack
+		for _, entry := range entries {
+			if _, ok := c.friendlyFmtDir(entry.Stack.Dir.String()); !ok {
+				continue
+			}
+			stacks = append(stacks, newListStack(entry))
+		}
+
+		if tmpl != nil {
+			err = writeStacksTemplate(c.stdout, tmpl, stacks)
+		} else {
+			err = writeStacksList(c.stdout, format, fields, stacks)
+		}
+		if err != nil {
+			fatal(err, "printing stacks")
+		}
+		return
+	}
+
+	for _, entry := range entries {
 		stack := entry.Stack
 
 		log.Debug().Msgf("printing stack %s", stack.Dir)
@@ -1426,17 +1793,24 @@ func (c *cli) generateGraph() {
 		fatal(err, "listing stacks to build graph")
 	}
 
+	var inferred run.InferredOrder
+	if c.parsedArgs.Experimental.RunGraph.Inferred || run.InferOrderEnabled(c.cfg()) {
+		logger.Debug().Msg("Infer order from Terraform remote states.")
+
+		inferred, err = run.InferOrder(c.cfg())
+		if err != nil {
+			fatal(err, "inferring order of execution")
+		}
+	}
+
 	logger.Debug().Msg("Create new graph.")
 
-	dotGraph := dot.NewGraph(dot.Directed)
 	graph := dag.New()
+	wantsGraph := dag.New()
 
 	visited := dag.Visited{}
+	wantsVisited := dag.Visited{}
 	for _, e := range c.filterStacksByWorkingDir(entries) {
-		if _, ok := visited[dag.ID(e.Stack.Dir.String())]; ok {
-			continue
-		}
-
 		if err := run.BuildDAG(
 			graph,
 			c.cfg(),
@@ -1445,21 +1819,59 @@ func (c *cli) generateGraph() {
 			func(s config.Stack) []string { return s.Before },
 			"after",
 			func(s config.Stack) []string { return s.After },
+			inferred,
 			visited,
 		); err != nil {
 			fatal(err, "building order tree")
 		}
+
+		if err := run.BuildDAG(
+			wantsGraph,
+			c.cfg(),
+			e.Stack,
+			"wanted_by",
+			func(s config.Stack) []string { return s.WantedBy },
+			"wants",
+			func(s config.Stack) []string { return s.Wants },
+			nil,
+			wantsVisited,
+		); err != nil {
+			fatal(err, "building wants tree")
+		}
 	}
 
-	for _, id := range graph.IDs() {
-		val, err := graph.Node(id)
+	var output strings.Builder
+	switch c.parsedArgs.Experimental.RunGraph.Format {
+	case "dot":
+		dotGraph := dot.NewGraph(dot.Directed)
+		for _, id := range graph.IDs() {
+			val, err := graph.Node(id)
+			if err != nil {
+				log.Fatal().
+					Err(err).
+					Msg("generating graph")
+			}
+
+			generateDot(dotGraph, graph, id, val.(*config.Stack), getLabel)
+		}
+		output.WriteString(dotGraph.String())
+	default:
+		runGraph, err := newRunGraph(graph, wantsGraph)
 		if err != nil {
-			log.Fatal().
-				Err(err).
-				Msg("generating graph")
+			fatal(err, "generating graph")
 		}
 
-		generateDot(dotGraph, graph, id, val.(*config.Stack), getLabel)
+		switch c.parsedArgs.Experimental.RunGraph.Format {
+		case "mermaid":
+			err = runGraph.writeMermaid(&output, getLabel)
+		case "json":
+			err = runGraph.writeJSON(&output)
+		case "graphml":
+			err = runGraph.writeGraphML(&output, getLabel)
+		}
+		if err != nil {
+			fatal(err, "generating graph")
+		}
 	}
 
 	logger.Debug().
@@ -1492,7 +1904,7 @@ func (c *cli) generateGraph() {
 
 	logger.Debug().
 		Msg("Write graph to output.")
-	_, err = out.Write([]byte(dotGraph.String()))
+	_, err = out.Write([]byte(output.String()))
 	if err != nil {
 		logger := log.With().
 			Str("path", outFile).
@@ -1521,6 +1933,10 @@ func generateDot(
 		edges := dotGraph.FindEdges(parent, n)
 		if len(edges) == 0 {
 			edge := dotGraph.Edge(parent, n)
+			if kinds := graph.EdgeKinds(id, childid); len(kinds) == 1 && kinds[0] == run.InferredKind {
+				edge.Attr("style", "dashed")
+				edge.Attr("label", run.InferredKind)
+			}
 			if graph.HasCycle(childid) {
 				edge.Attr("color", "red")
 				continue
@@ -1546,8 +1962,13 @@ func (c *cli) printRunOrder() {
 		fatal(err, "computing selected stacks")
 	}
 
+	args := c.parsedArgs.Experimental.RunOrder
+	if args.Levels && args.CriticalPath != "" {
+		fatal(errors.E("--levels and --critical-path are mutually exclusive"))
+	}
+
 	logger.Debug().Msg("Get run order.")
-	orderedStacks, reason, err := run.Sort(c.cfg(), stacks)
+	orderedStacks, deps, reason, err := run.SortWithDeps(c.cfg(), stacks)
 	if err != nil {
 		if errors.IsKind(err, dag.ErrCycleDetected) {
 			fatal(err, "cycle detected on run order: %s", reason)
@@ -1556,8 +1977,28 @@ func (c *cli) printRunOrder() {
 		}
 	}
 
-	for _, s := range orderedStacks {
-		c.output.MsgStdOut(s.Dir().String())
+	switch {
+	case args.Levels:
+		for i, level := range run.Levels(orderedStacks, deps) {
+			c.output.MsgStdOut("Level %d:", i+1)
+			for _, s := range level {
+				c.output.MsgStdOut("  %s", s.Dir())
+			}
+		}
+	case args.CriticalPath != "":
+		durations, err := c.loadRunReportDurations(args.CriticalPath)
+		if err != nil {
+			fatal(err, "loading durations for the critical path")
+		}
+		path, total := run.CriticalPath(orderedStacks, deps, durations)
+		for _, dir := range path {
+			c.output.MsgStdOut("%s\t%s", dir, durations[dir].Round(time.Millisecond))
+		}
+		c.output.MsgStdOut("Total: %s", total.Round(time.Millisecond))
+	default:
+		for _, s := range orderedStacks {
+			c.output.MsgStdOut(s.Dir().String())
+		}
 	}
 }
 
@@ -1696,7 +2137,7 @@ func (c *cli) printMetadata() {
 }
 
 func (c *cli) checkGenCode() bool {
-	if c.parsedArgs.Run.DisableCheckGenCode {
+	if c.parsedArgs.Run.DisableCheckGenCode || c.parsedArgs.Script.Run.DisableCheckGenCode {
 		return false
 	}
 
@@ -1934,7 +2375,7 @@ func (c *cli) checkOutdatedGeneratedCode() {
 }
 
 func (c *cli) gitSafeguardRemoteEnabled() bool {
-	if c.parsedArgs.Run.DisableCheckGitRemote {
+	if c.parsedArgs.Run.DisableCheckGitRemote || c.parsedArgs.Script.Run.DisableCheckGitRemote {
 		return false
 	}
 
@@ -1985,7 +2426,11 @@ func (c *cli) computeSelectedStacks(ensureCleanRepo bool) (config.List[*config.S
 
 	logger.Trace().Msg("Filter stacks by working directory.")
 
-	entries := c.filterStacks(report.Stacks)
+	entries, err := c.addOrderedOf(mgr, c.filterStacks(report.Stacks))
+	if err != nil {
+		return nil, errors.E(err, "adding ordered stacks")
+	}
+
 	stacks := make(config.List[*config.SortableStack], len(entries))
 	for i, e := range entries {
 		stacks[i] = e.Stack.Sortable()
@@ -1998,6 +2443,56 @@ func (c *cli) computeSelectedStacks(ensureCleanRepo bool) (config.List[*config.S
 	return stacks, nil
 }
 
+// addOrderedOf adds to the entries the stacks that must run after or before
+// them, as requested by the --include-dependents and --include-dependencies
+// flags. The entries must be already filtered by the working directory and
+// the tags, which only apply to the selection of the stacks: the added stacks
+// are never filtered, like the wanted stacks.
+func (c *cli) addOrderedOf(mgr *stack.Manager, entries []stack.Entry) ([]stack.Entry, error) {
+	if !c.parsedArgs.IncludeDependents && !c.parsedArgs.IncludeDependencies {
+		return entries, nil
+	}
+
+	selected := map[prj.Path]struct{}{}
+	stacks := make(config.List[*config.SortableStack], len(entries))
+	for i, e := range entries {
+		selected[e.Stack.Dir] = struct{}{}
+		stacks[i] = e.Stack.Sortable()
+	}
+
+	addEntries := func(added config.List[*config.SortableStack], reason string) {
+		for _, s := range added {
+			if _, ok := selected[s.Dir()]; ok {
+				continue
+			}
+			selected[s.Dir()] = struct{}{}
+			entries = append(entries, stack.Entry{
+				Stack:  s.Stack,
+				Reason: reason,
+			})
+		}
+	}
+
+	if c.parsedArgs.IncludeDependents {
+		dependents, err := mgr.AddDependentsOf(stacks)
+		if err != nil {
+			return nil, errors.E(err, "adding dependent stacks")
+		}
+		addEntries(dependents, "stack must run after a selected stack")
+	}
+
+	if c.parsedArgs.IncludeDependencies {
+		dependencies, err := mgr.AddDependenciesOf(stacks)
+		if err != nil {
+			return nil, errors.E(err, "adding dependency stacks")
+		}
+		addEntries(dependencies, "stack must run before a selected stack")
+	}
+
+	sort.Sort(stack.EntrySlice(entries))
+	return entries, nil
+}
+
 func (c *cli) filterStacks(stacks []stack.Entry) []stack.Entry {
 	return c.filterStacksByTags(c.filterStacksByWorkingDir(stacks))
 }
@@ -45,6 +45,14 @@ type cloudConfig struct {
 	}
 }
 
+// cloudSyncOptions are the options of the synchronization of the stacks
+// execution with the Terramate Cloud, set by the command executing the stacks.
+type cloudSyncOptions struct {
+	deployment        bool
+	driftStatus       bool
+	terraformPlanFile string
+}
+
 type credential interface {
 	Name() string
 	Load() (bool, error)
@@ -92,7 +100,7 @@ func (c *cli) handleCriticalError(err error) {
 }
 
 func (c *cli) checkCloudSync() {
-	if !c.parsedArgs.Run.CloudSyncDeployment && !c.parsedArgs.Run.CloudSyncDriftStatus {
+	if !c.isCloudSync() {
 		return
 	}
 
@@ -103,13 +111,43 @@ func (c *cli) checkCloudSync() {
 		return
 	}
 
-	if c.parsedArgs.Run.CloudSyncDeployment {
+	if c.cloudSync.deployment {
 		c.cloud.run.meta2id = make(map[string]int)
 		c.cloud.run.runUUID, err = generateRunID()
 		c.handleCriticalError(err)
 	}
 }
 
+// prepareCloudSync validates the cloud sync options and prepares the
+// synchronization of the execution of the given stacks.
+func (c *cli) prepareCloudSync(stacks config.List[*config.SortableStack]) {
+	if c.cloudSync.deployment && c.cloudSync.driftStatus {
+		fatal(errors.E("--cloud-sync-deployment conflicts with --cloud-sync-drift-status"))
+	}
+
+	if c.cloudSync.deployment && c.cloudSync.terraformPlanFile != "" {
+		fatal(errors.E("--cloud-sync-terraform-plan-file can only be used with --cloud-sync-drift-status"))
+	}
+
+	if c.isCloudSync() {
+		if !c.prj.isRepo {
+			fatal(errors.E("cloud features requires a git repository"))
+		}
+		c.ensureAllStackHaveIDs(stacks)
+		c.detectCloudMetadata()
+	}
+}
+
+// isCloudSyncSuccessCode tells if the exit code of a stack execution is
+// successful. When synchronizing the drift status, the exit code 2 means the
+// stack has drifted and is also successful.
+func (c *cli) isCloudSyncSuccessCode(exitCode int) bool {
+	if c.cloudSync.driftStatus {
+		return exitCode == 0 || exitCode == 2
+	}
+	return exitCode == 0
+}
+
 func (c *cli) cloudOrgName() string {
 	orgName := os.Getenv("TM_CLOUD_ORGANIZATION")
 	if orgName != "" {
@@ -200,7 +238,7 @@ func (c *cli) setupCloudConfig() error {
 }
 
 func (c *cli) cloudSyncBefore(run ExecContext, _ string) {
-	if !c.cloudEnabled() || !c.parsedArgs.Run.CloudSyncDeployment {
+	if !c.cloudEnabled() || !c.cloudSync.deployment {
 		return
 	}
 	c.doCloudSyncDeployment(run, deployment.Running)
@@ -211,7 +249,7 @@ func (c *cli) cloudSyncAfter(runContext ExecContext, res RunResult, err error) {
 		return
 	}
 
-	if c.parsedArgs.Run.CloudSyncDeployment {
+	if c.cloudSync.deployment {
 		c.cloudSyncDeployment(runContext, err)
 	} else {
 		c.cloudSyncDriftStatus(runContext, res, err)
@@ -464,7 +502,7 @@ func setGithubPRMetadata(md *cloud.DeploymentMetadata, pull *github.Pull) {
 }
 
 func (c *cli) isCloudSync() bool {
-	return c.parsedArgs.Run.CloudSyncDeployment || c.parsedArgs.Run.CloudSyncDriftStatus
+	return c.cloudSync.deployment || c.cloudSync.driftStatus
 }
 
 func (c *cli) loadCredential() error {
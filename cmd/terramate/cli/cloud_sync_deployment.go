@@ -88,7 +88,7 @@ func (c *cli) createCloudDeployment(runStacks []ExecContext) {
 				Path:            run.Stack.Dir.String(),
 			},
 			CommitSHA:         deploymentCommitSHA,
-			DeploymentCommand: strings.Join(run.Cmd, " "),
+			DeploymentCommand: strings.Join(run.Command(), " "),
 			DeploymentURL:     deploymentURL,
 		})
 	}
@@ -131,7 +131,7 @@ func (c *cli) cloudSyncDeployment(runContext ExecContext, err error) {
 		status = deployment.OK
 	case errors.IsKind(err, ErrRunCanceled):
 		status = deployment.Canceled
-	case errors.IsAnyKind(err, ErrRunFailed, ErrRunCommandNotFound):
+	case errors.IsAnyKind(err, ErrRunFailed, ErrRunCommandNotFound, ErrRunTimeout):
 		status = deployment.Failed
 	default:
 		panic(errors.E(errors.ErrInternal, "unexpected run status"))
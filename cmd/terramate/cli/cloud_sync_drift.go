@@ -28,17 +28,21 @@ func (c *cli) cloudSyncDriftStatus(runContext ExecContext, res RunResult, err er
 		Str("action", "cloudSyncDriftStatus").
 		Stringer("stack", st.Dir).
 		Int("exit_code", res.ExitCode).
-		Strs("command", runContext.Cmd).
+		Strs("command", runContext.Command()).
 		Err(err).
 		Logger()
 
 	var status stack.Status
 	switch {
+	case errors.IsAnyKind(err, ErrRunTimeout, ErrRunCommandNotFound, ErrRunFailed):
+		// the exit code is meaningless if the command timed out or if a
+		// hook failed.
+		status = stack.Failed
 	case res.ExitCode == 0:
 		status = stack.OK
 	case res.ExitCode == 2:
 		status = stack.Drifted
-	case res.ExitCode == 1 || res.ExitCode > 2 || errors.IsAnyKind(err, ErrRunCommandNotFound, ErrRunFailed):
+	case res.ExitCode == 1 || res.ExitCode > 2:
 		status = stack.Failed
 	default:
 		// ignore exit codes < 0
@@ -48,7 +52,7 @@ func (c *cli) cloudSyncDriftStatus(runContext ExecContext, res RunResult, err er
 
 	var driftDetails *cloud.DriftDetails
 
-	if planfile := c.parsedArgs.Run.CloudSyncTerraformPlanFile; planfile != "" {
+	if planfile := c.cloudSync.terraformPlanFile; planfile != "" {
 		var err error
 		driftDetails, err = c.getTerraformDriftDetails(runContext, planfile)
 		if err != nil {
@@ -76,7 +80,7 @@ func (c *cli) cloudSyncDriftStatus(runContext ExecContext, res RunResult, err er
 		Status:     status,
 		Details:    driftDetails,
 		Metadata:   c.cloud.run.metadata,
-		Command:    runContext.Cmd,
+		Command:    runContext.Command(),
 		StartedAt:  res.StartedAt,
 		FinishedAt: res.FinishedAt,
 	})
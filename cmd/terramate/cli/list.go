@@ -0,0 +1,253 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package cli
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/stack"
+	"gopkg.in/yaml.v3"
+)
+
+// listFields are the stack fields available in the structured output of the
+// list command, in the default order.
+var listFields = []string{
+	"id",
+	"name",
+	"description",
+	"tags",
+	"path",
+	"after",
+	"before",
+	"wants",
+	"watch",
+	"reason",
+}
+
+// listStack is a stack in the structured output of the list command. It's also
+// the data of the --template option.
+type listStack struct {
+	ID          string
+	Name        string
+	Description string
+	Tags        []string
+	Path        string
+	After       []string
+	Before      []string
+	Wants       []string
+	Watch       []string
+	Reason      string
+}
+
+// listRecord is a stack in the structured output of the list command with only
+// the selected fields, in the order they were selected.
+type listRecord []listRecordField
+
+type listRecordField struct {
+	name  string
+	value interface{}
+}
+
+func newListStack(entry stack.Entry) listStack {
+	st := entry.Stack
+	watch := make([]string, len(st.Watch))
+	for i, p := range st.Watch {
+		watch[i] = p.String()
+	}
+	return listStack{
+		ID:          st.ID,
+		Name:        st.Name,
+		Description: st.Description,
+		Tags:        nonNilStrings(st.Tags),
+		Path:        st.Dir.String(),
+		After:       nonNilStrings(st.After),
+		Before:      nonNilStrings(st.Before),
+		Wants:       nonNilStrings(st.Wants),
+		Watch:       watch,
+		Reason:      entry.Reason,
+	}
+}
+
+// field returns the value of the field, which must be one of listFields.
+func (s listStack) field(name string) interface{} {
+	switch name {
+	case "id":
+		return s.ID
+	case "name":
+		return s.Name
+	case "description":
+		return s.Description
+	case "tags":
+		return s.Tags
+	case "path":
+		return s.Path
+	case "after":
+		return s.After
+	case "before":
+		return s.Before
+	case "wants":
+		return s.Wants
+	case "watch":
+		return s.Watch
+	case "reason":
+		return s.Reason
+	}
+	panic(errors.E(errors.ErrInternal, "unexpected list field %s", name))
+}
+
+func (s listStack) record(fields []string) listRecord {
+	r := make(listRecord, len(fields))
+	for i, name := range fields {
+		r[i] = listRecordField{name: name, value: s.field(name)}
+	}
+	return r
+}
+
+// MarshalJSON marshals the record as a JSON object keeping the order of the
+// fields.
+func (r listRecord) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, f := range r {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, err := json.Marshal(f.name)
+		if err != nil {
+			return nil, err
+		}
+		val, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(val)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// MarshalYAML marshals the record as a YAML mapping keeping the order of the
+// fields.
+func (r listRecord) MarshalYAML() (interface{}, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for _, f := range r {
+		val := &yaml.Node{}
+		if err := val.Encode(f.value); err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: f.name}, val)
+	}
+	return node, nil
+}
+
+// parseListFields parses the fields given in the --fields flag, returning all
+// the fields if none is given.
+func parseListFields(fields []string) ([]string, error) {
+	if len(fields) == 0 {
+		return listFields, nil
+	}
+	valid := map[string]bool{}
+	for _, name := range listFields {
+		valid[name] = true
+	}
+	res := make([]string, 0, len(fields))
+	for _, name := range fields {
+		name = strings.TrimSpace(name)
+		if !valid[name] {
+			return nil, errors.E("unknown field %q, available fields: %s",
+				name, strings.Join(listFields, ", "))
+		}
+		res = append(res, name)
+	}
+	return res, nil
+}
+
+// parseListTemplate parses the Go template of the --template flag.
+func parseListTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("list").
+		Funcs(template.FuncMap{"join": strings.Join}).
+		Option("missingkey=error").
+		Parse(text)
+	if err != nil {
+		return nil, errors.E(err, "parsing template")
+	}
+	return tmpl, nil
+}
+
+// writeStacksList writes the stacks in the given structured format, which is
+// one of json, yaml or csv, with only the given fields.
+func writeStacksList(w io.Writer, format string, fields []string, stacks []listStack) error {
+	records := make([]listRecord, len(stacks))
+	for i, st := range stacks {
+		records[i] = st.record(fields)
+	}
+
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(records)
+	case "yaml":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(records); err != nil {
+			return err
+		}
+		return enc.Close()
+	case "csv":
+		cw := csv.NewWriter(w)
+		if err := cw.Write(fields); err != nil {
+			return err
+		}
+		for _, r := range records {
+			row := make([]string, len(r))
+			for i, f := range r {
+				switch v := f.value.(type) {
+				case []string:
+					row[i] = strings.Join(v, ",")
+				default:
+					row[i] = v.(string)
+				}
+			}
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+	panic(errors.E(errors.ErrInternal, "unexpected list format %s", format))
+}
+
+// writeStacksTemplate writes a line for each stack with the template executed
+// with the stack data.
+func writeStacksTemplate(w io.Writer, tmpl *template.Template, stacks []listStack) error {
+	for _, st := range stacks {
+		var b bytes.Buffer
+		if err := tmpl.Execute(&b, st); err != nil {
+			return errors.E(err, "executing template for stack %s", st.Path)
+		}
+		b.WriteByte('\n')
+		if _, err := w.Write(b.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func nonNilStrings(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
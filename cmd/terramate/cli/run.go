@@ -4,11 +4,13 @@
 package cli
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"os"
 	"os/exec"
 	"os/signal"
+	"strconv"
 	"strings"
 	"time"
 
@@ -32,12 +34,57 @@ const (
 	// ErrRunCommandNotFound represents the error when the command cannot be found
 	// in the system.
 	ErrRunCommandNotFound errors.Kind = "command not found"
+
+	// ErrRunTimeout represents the error when the command was stopped because
+	// it exceeded the stack timeout.
+	ErrRunTimeout errors.Kind = "execution timed out"
+)
+
+// Reasons for canceling the execution of stacks.
+const (
+	cancelReasonFailure     = "a previous stack failed"
+	cancelReasonDependency  = "a dependency failed or was canceled"
+	cancelReasonInterrupted = "execution interrupted by CTRL-C"
+	cancelReasonKilled      = "execution aborted by CTRL-C (3x)"
+	cancelReasonBeforeAll   = "the before_all hook failed"
 )
 
 // ExecContext declares an stack execution context.
 type ExecContext struct {
 	Stack *config.Stack
-	Cmd   []string
+
+	// Cmds are the commands executed in the stack, one after the other,
+	// stopping at the first failure.
+	Cmds [][]string
+
+	// Deps is the list of stacks that must finish before this stack can be
+	// executed. It's only used when running stacks in parallel.
+	Deps prj.Paths
+}
+
+// Command returns the commands of the execution as a single command line,
+// with the commands separated by "&&".
+func (e ExecContext) Command() []string {
+	var cmd []string
+	for i, c := range e.Cmds {
+		if i > 0 {
+			cmd = append(cmd, "&&")
+		}
+		cmd = append(cmd, c...)
+	}
+	return cmd
+}
+
+// runOptions are the options of the execution of the stacks, given by the
+// command executing them.
+type runOptions struct {
+	// parallel is the maximum number of stacks executed at the same time.
+	parallel        int
+	continueOnError bool
+	retry           runRetry
+	timeout         runTimeout
+	prefixOutput    bool
+	outputDir       string
 }
 
 // RunResult contains exit code and duration of a completed run.
@@ -45,6 +92,10 @@ type RunResult struct {
 	ExitCode   int
 	StartedAt  *time.Time
 	FinishedAt *time.Time
+
+	// Attempt is the number of the execution attempt, starting at 1.
+	// It's zero if the command was not executed.
+	Attempt int
 }
 
 func (c *cli) runOnStacks() {
@@ -60,43 +111,61 @@ func (c *cli) runOnStacks() {
 	}
 
 	c.checkOutdatedGeneratedCode()
+
+	c.cloudSync = cloudSyncOptions{
+		deployment:        c.parsedArgs.Run.CloudSyncDeployment,
+		driftStatus:       c.parsedArgs.Run.CloudSyncDriftStatus,
+		terraformPlanFile: c.parsedArgs.Run.CloudSyncTerraformPlanFile,
+	}
 	c.checkCloudSync()
 
-	var stacks config.List[*config.SortableStack]
-	if c.parsedArgs.Run.NoRecursive {
-		st, found, err := config.TryLoadStack(c.cfg(), prj.PrjAbsPath(c.rootdir(), c.wd()))
-		if err != nil {
-			fatal(err, "loading stack in current directory")
-		}
+	orderedStacks, deps := c.computeRunStacks(c.parsedArgs.Run.NoRecursive, c.parsedArgs.Run.Reverse)
 
-		if !found {
-			logger.Fatal().
-				Msg("--no-recursive provided but no stack found in the current directory")
-		}
-
-		stacks = append(stacks, st.Sortable())
-	} else {
-		var err error
-		stacks, err = c.computeSelectedStacks(true)
-		if err != nil {
-			fatal(err, "computing selected stacks")
-		}
+	if c.parsedArgs.Run.Parallel < 1 {
+		fatal(errors.E("--parallel expects a number greater than zero"))
 	}
 
-	logger.Trace().Msg("Get order of stacks to run command on.")
+	retry, err := c.loadRunRetry(
+		c.parsedArgs.Run.RetryMaxAttempts,
+		c.parsedArgs.Run.RetryBackoff,
+		c.parsedArgs.Run.RetryOn,
+	)
+	if err != nil {
+		fatal(err, "loading the retry policy")
+	}
 
-	orderedStacks, reason, err := run.Sort(c.cfg(), stacks)
+	timeout, err := c.loadRunTimeout(
+		c.parsedArgs.Run.Timeout,
+		c.parsedArgs.Run.TimeoutInterruptGrace,
+		c.parsedArgs.Run.TimeoutTerminateGrace,
+	)
 	if err != nil {
-		if errors.IsKind(err, dag.ErrCycleDetected) {
-			fatal(err, "cycle detected: %s", reason)
-		} else {
-			fatal(err, "failed to plan execution")
-		}
+		fatal(err, "loading the timeout policy")
 	}
 
-	if c.parsedArgs.Run.Reverse {
-		logger.Trace().Msg("Reversing stacks order.")
-		config.ReverseStacks(orderedStacks)
+	opts := runOptions{
+		parallel:        c.parsedArgs.Run.Parallel,
+		continueOnError: c.parsedArgs.Run.ContinueOnError,
+		retry:           retry,
+		timeout:         timeout,
+		prefixOutput:    c.parsedArgs.Run.PrefixOutput,
+		outputDir:       c.parsedArgs.Run.OutputDir,
+	}
+
+	var state *runState
+	if c.parsedArgs.Run.Resume {
+		var pending config.List[*config.SortableStack]
+		state, pending = c.resumeRunState(orderedStacks)
+		if len(pending) == 0 {
+			c.output.MsgStdOut("Nothing to resume, all stacks of the last run succeeded.")
+			return
+		}
+
+		logger.Debug().
+			Int("skipped", len(orderedStacks)-len(pending)).
+			Msg("resuming the last run")
+
+		orderedStacks = pending
 	}
 
 	if c.parsedArgs.Run.DryRun {
@@ -118,54 +187,82 @@ func (c *cli) runOnStacks() {
 
 	var runStacks []ExecContext
 	for _, st := range orderedStacks {
-		run := ExecContext{
-			Stack: st.Stack,
-			Cmd:   c.parsedArgs.Run.Command,
-		}
+		cmd := c.parsedArgs.Run.Command
 		if c.parsedArgs.Run.Eval {
-			run.Cmd = c.evalRunArgs(run.Stack, run.Cmd)
+			cmd = c.evalRunArgs(st.Stack, cmd)
 		}
-		runStacks = append(runStacks, run)
+		runStacks = append(runStacks, ExecContext{
+			Stack: st.Stack,
+			Cmds:  [][]string{cmd},
+			Deps:  deps[st.Dir()],
+		})
 	}
 
-	if c.parsedArgs.Run.CloudSyncDeployment && c.parsedArgs.Run.CloudSyncDriftStatus {
-		fatal(errors.E("--cloud-sync-deployment conflicts with --cloud-sync-drift-status"))
+	c.prepareCloudSync(orderedStacks)
+	c.startRunState(state, runStacks)
+	c.startRunReport(runStacks)
+
+	if c.cloudSync.deployment {
+		c.createCloudDeployment(runStacks)
 	}
 
-	if c.parsedArgs.Run.CloudSyncDeployment && c.parsedArgs.Run.CloudSyncTerraformPlanFile != "" {
-		fatal(errors.E("--cloud-sync-terraform-plan-file can only be used with --cloud-sync-drift-status"))
+	err = c.RunAll(runStacks, opts, c.isCloudSyncSuccessCode)
+	c.finishRunReport()
+	if err != nil {
+		fatal(err, "one or more commands failed")
 	}
+}
+
+// computeRunStacks computes the selected stacks and returns them in the order
+// of execution, together with the dependencies of each stack.
+func (c *cli) computeRunStacks(noRecursive, reverse bool) (config.List[*config.SortableStack], run.Deps) {
+	logger := log.With().
+		Str("action", "cli.computeRunStacks()").
+		Str("workingDir", c.wd()).
+		Logger()
 
-	if c.parsedArgs.Run.CloudSyncDeployment || c.parsedArgs.Run.CloudSyncDriftStatus {
-		if !c.prj.isRepo {
-			fatal(errors.E("cloud features requires a git repository"))
+	var stacks config.List[*config.SortableStack]
+	if noRecursive {
+		st, found, err := config.TryLoadStack(c.cfg(), prj.PrjAbsPath(c.rootdir(), c.wd()))
+		if err != nil {
+			fatal(err, "loading stack in current directory")
 		}
-		c.ensureAllStackHaveIDs(orderedStacks)
-		c.detectCloudMetadata()
-	}
 
-	isSuccessExit := func(exitCode int) bool {
-		return exitCode == 0
-	}
+		if !found {
+			logger.Fatal().
+				Msg("--no-recursive provided but no stack found in the current directory")
+		}
 
-	if c.parsedArgs.Run.CloudSyncDeployment {
-		c.createCloudDeployment(runStacks)
+		stacks = append(stacks, st.Sortable())
+	} else {
+		var err error
+		stacks, err = c.computeSelectedStacks(true)
+		if err != nil {
+			fatal(err, "computing selected stacks")
+		}
 	}
 
-	if c.parsedArgs.Run.CloudSyncDriftStatus {
-		isSuccessExit = func(exitCode int) bool {
-			return exitCode == 0 || exitCode == 2
+	logger.Trace().Msg("Get order of stacks to run command on.")
+
+	orderedStacks, deps, reason, err := run.SortWithDeps(c.cfg(), stacks)
+	if err != nil {
+		if errors.IsKind(err, dag.ErrCycleDetected) {
+			fatal(err, "cycle detected: %s", reason)
+		} else {
+			fatal(err, "failed to plan execution")
 		}
 	}
 
-	err = c.RunAll(runStacks, isSuccessExit)
-	if err != nil {
-		fatal(err, "one or more commands failed")
+	if reverse {
+		logger.Trace().Msg("Reversing stacks order.")
+		config.ReverseStacks(orderedStacks)
+		deps = reverseDeps(deps)
 	}
+	return orderedStacks, deps
 }
 
 // RunAll will execute the list of RunStack definitions. A RunStack defines the
-// stack and its command to be executed. The isSuccessCode is a predicate used
+// stack and its commands to be executed. The isSuccessCode is a predicate used
 // to decide if the command is considered a successful run or not.
 // During the execution of this function the default behavior
 // for signal handling will be changed so we can wait for the child
@@ -175,13 +272,17 @@ func (c *cli) runOnStacks() {
 // stacks.
 // If SIGINT is sent 3x then Terramate will send a SIGKILL to the currently
 // running process and abort the execution of all subsequent stacks.
-func (c *cli) RunAll(runStacks []ExecContext, isSuccessCode func(exitCode int) bool) error {
+// When the parallel option is greater than 1, the stacks are scheduled as soon
+// as all their dependencies (see [ExecContext.Deps]) finished successfully,
+// with at most the given number of stacks running at the same time.
+// The before and after hooks of each stack are executed around its commands
+// and a failing hook fails the stack. The before_all and after_all hooks are
+// executed once, before and after the execution of all the stacks.
+func (c *cli) RunAll(runStacks []ExecContext, opts runOptions, isSuccessCode func(exitCode int) bool) error {
 	logger := log.With().
 		Str("action", "cli.RunAll()").
 		Logger()
 
-	errs := errors.L()
-
 	// we load/check the env of all stacks beforehand then no stack is executed
 	// if the environment is not correct for all of them.
 	stackEnvs, err := c.loadAllStackEnvs(runStacks)
@@ -189,20 +290,72 @@ func (c *cli) RunAll(runStacks []ExecContext, isSuccessCode func(exitCode int) b
 		return err
 	}
 
-	logger.Trace().Msg("loaded stacks run environment variables, running commands")
+	logger.Trace().Msg("loaded stacks run environment variables")
+
+	stackHooks, err := c.loadAllStackHooks(runStacks)
+	if err != nil {
+		return err
+	}
+
+	runHooks, err := run.LoadRunHooks(c.cfg())
+	if err != nil {
+		return err
+	}
+
+	logger.Trace().Msg("loaded run hooks, running commands")
 
 	const signalsBufferSize = 10
 	signals := make(chan os.Signal, signalsBufferSize)
 	signal.Notify(signals, os.Interrupt)
 	defer signal.Reset(os.Interrupt)
 
-	cmds := make(chan *exec.Cmd)
-	defer close(cmds)
+	err = c.runHooks(&logger, "before_all", runHooks.Before, c.rootdir(), os.Environ())
+	if err != nil {
+		c.cancelStacks(runStacks, cancelReasonBeforeAll)
+		return err
+	}
 
-	continueOnError := c.parsedArgs.Run.ContinueOnError
-	results := startCmdConsumer(cmds)
-	for i, runContext := range runStacks {
-		cmdStr := strings.Join(runContext.Cmd, " ")
+	outputs := stackOutputs{}
+	defer outputs.Close()
+
+	if opts.parallel > 1 {
+		err = c.runAllParallel(runStacks, stackEnvs, stackHooks, outputs, opts, signals, isSuccessCode)
+	} else {
+		err = c.runAllSequential(runStacks, stackEnvs, stackHooks, outputs, opts, signals, isSuccessCode)
+	}
+
+	errs := errors.L(err)
+	errs.Append(c.runHooks(&logger, "after_all", runHooks.After, c.rootdir(), os.Environ()))
+	return errs.AsError()
+}
+
+// runAllSequential executes the runStacks one after the other, in the given
+// order. A failed stack is retried, according to the retry policy, before
+// moving to the next stack.
+func (c *cli) runAllSequential(
+	runStacks []ExecContext,
+	stackEnvs map[prj.Path]run.EnvVars,
+	stackHooks map[prj.Path]run.Hooks,
+	outputs stackOutputs,
+	opts runOptions,
+	signals <-chan os.Signal,
+	isSuccessCode func(exitCode int) bool,
+) error {
+	errs := errors.L()
+
+	procs := make(chan *stackProcess)
+	defer close(procs)
+
+	continueOnError := opts.continueOnError
+	results := startCmdConsumer(procs)
+	timeouts := make(chan *cmdTimeout, 3)
+	attempts := make([]int, len(runStacks))
+	for i := 0; i < len(runStacks); i++ {
+		runContext := runStacks[i]
+		attempts[i]++
+		attempt := attempts[i]
+
+		cmdStr := strings.Join(runContext.Command(), " ")
 		logger := log.With().
 			Str("cmd", cmdStr).
 			Stringer("stack", runContext.Stack).
@@ -210,71 +363,57 @@ func (c *cli) RunAll(runStacks []ExecContext, isSuccessCode func(exitCode int) b
 
 		c.cloudSyncBefore(runContext, cmdStr)
 
-		environ := newEnvironFrom(stackEnvs[runContext.Stack.Dir])
-		cmdPath, err := run.LookPath(runContext.Cmd[0], environ)
+		stackEnv := stackEnvs[runContext.Stack.Dir]
+		hooks := stackHooks[runContext.Stack.Dir]
+
+		output, err := outputs.get(c, runContext.Stack, opts)
+		if err == nil {
+			output.StartAttempt(attempt)
+		}
+
+		var proc *stackProcess
+		var logSyncWait func()
+		if err == nil {
+			proc, logSyncWait, err = c.newStackProcess(&logger, runContext, stackEnv, hooks, output, isSuccessCode)
+		}
 		if err != nil {
-			c.cloudSyncAfter(runContext, RunResult{ExitCode: -1}, errors.E(ErrRunCommandNotFound, err))
+			c.afterStackRun(runContext, RunResult{ExitCode: -1}, err)
 			errs.Append(errors.E(err, "running `%s` in stack %s", cmdStr, runContext.Stack.Dir))
 			if continueOnError {
 				continue
 			}
-			c.cloudSyncCancelStacks(runStacks[i+1:])
+			c.cancelStacks(runStacks[i+1:], cancelReasonFailure)
 			return errs.AsError()
 		}
-		cmd := exec.Command(cmdPath, runContext.Cmd[1:]...)
-		cmd.Dir = runContext.Stack.HostDir(c.cfg())
-		cmd.Env = environ
-
-		stdout := c.stdout
-		stderr := c.stderr
 
-		logSyncWait := func() {}
-		if c.cloudEnabled() && c.parsedArgs.Run.CloudSyncDeployment {
-			logSyncer := cloud.NewLogSyncer(func(logs cloud.DeploymentLogs) {
-				c.syncLogs(&logger, runContext, logs)
-			})
-			stdout = logSyncer.NewBuffer(cloud.StdoutLogChannel, c.stdout)
-			stderr = logSyncer.NewBuffer(cloud.StderrLogChannel, c.stderr)
-
-			logSyncWait = logSyncer.Wait
+		for _, cmd := range proc.all() {
+			cmd.Stdin = c.stdin
 		}
+		stderr := opts.retry.captureStderr(proc)
 
-		cmd.Stdin = c.stdin
-		cmd.Stdout = stdout
-		cmd.Stderr = stderr
-
-		logger.Info().Msg("running")
+		logger.Info().Int("attempt", attempt).Msg("running")
 
 		startTime := time.Now().UTC()
 
-		if err := cmd.Start(); err != nil {
-			endTime := time.Now().UTC()
-
-			logSyncWait()
-
-			res := RunResult{
-				ExitCode:   -1,
-				StartedAt:  &startTime,
-				FinishedAt: &endTime,
-			}
-			c.cloudSyncAfter(runContext, res, errors.E(err, ErrRunFailed))
-			errs.Append(errors.E(err, "running %s (at stack %s)", cmd, runContext.Stack.Dir))
-			logger.Error().Err(err).Msg("failed to execute")
-			if continueOnError {
-				continue
-			}
-			c.cloudSyncCancelStacks(runStacks[i+1:])
-			return errs.AsError()
-		}
-
-		cmds <- cmd
+		procs <- proc
+		cmdTimeout := opts.timeout.start(runContext.Stack, &logger, timeouts)
 		interruptions := 0
 		cmdIsRunning := true
 
+		// retryErr is the error of the failed attempt which is going to be
+		// retried.
+		var retryErr error
+
 		for cmdIsRunning {
 			select {
+			case expired := <-timeouts:
+				// timeouts of the previous commands are ignored.
+				if expired == cmdTimeout {
+					cmdTimeout.escalate(proc)
+				}
 			case sig := <-signals:
 				interruptions++
+				proc.Stop()
 
 				logger.Info().
 					Str("signal", sig.String()).
@@ -284,9 +423,10 @@ func (c *cli) RunAll(runStacks []ExecContext, isSuccessCode func(exitCode int) b
 				if interruptions >= 3 {
 					logger.Info().Msg("interrupted 3x times or more, killing child process")
 
-					if err := cmd.Process.Kill(); err != nil {
+					if err := proc.Signal(os.Kill); err != nil {
 						logger.Debug().Err(err).Msg("unable to send kill signal to child process")
 					}
+					cmdTimeout.stop()
 
 					endTime := time.Now().UTC()
 
@@ -296,46 +436,83 @@ func (c *cli) RunAll(runStacks []ExecContext, isSuccessCode func(exitCode int) b
 						ExitCode:   -1,
 						StartedAt:  &startTime,
 						FinishedAt: &endTime,
+						Attempt:    attempt,
 					}
-					c.cloudSyncAfter(runContext, res, errors.E(ErrRunCanceled))
-					c.cloudSyncCancelStacks(runStacks[i+1:])
-					return errors.E(ErrRunCanceled, "execution aborted by CTRL-C (3x)")
+					c.afterStackRun(runContext, res, errors.E(ErrRunCanceled, cancelReasonKilled))
+					c.cancelStacks(runStacks[i+1:], cancelReasonKilled)
+					return errors.E(ErrRunCanceled, cancelReasonKilled)
 				}
 			case result := <-results:
 				logger.Trace().Msg("got command result")
+				cmdTimeout.stop()
 				logSyncWait()
-				var err error
-				if !isSuccessCode(result.cmd.ProcessState.ExitCode()) {
-					err = errors.E(result.err, ErrRunFailed, "running %s (at stack %s)", result.cmd, runContext.Stack.Dir)
-					errs.Append(err)
-					logger.Error().Err(err).Msg("failed to execute")
-				}
 
 				res := RunResult{
-					ExitCode:   result.cmd.ProcessState.ExitCode(),
+					ExitCode:   result.exitCode(),
 					StartedAt:  &startTime,
 					FinishedAt: result.finishedAt,
+					Attempt:    attempt,
 				}
 
-				logMsg := logger.Debug().Int("exit_code", res.ExitCode)
-				if res.StartedAt != nil && res.FinishedAt != nil {
-					logMsg = logMsg.
-						Time("started_at", *res.StartedAt).
-						Time("finished_at", *res.FinishedAt).
-						TimeDiff("duration", *res.FinishedAt, *res.StartedAt)
+				err := stackCmdError(runContext, result, proc, cmdTimeout, isSuccessCode)
+				cmdFailed := err != nil
+				if cmdFailed {
+					logger.Error().Err(err).Msg("failed to execute")
+				}
+
+				logRunResult(&logger, res)
+
+				hookErr := stackHookError(runContext, result)
+
+				retrying := cmdFailed && !cmdTimeout.expired() && interruptions == 0 &&
+					opts.retry.shouldRetry(attempt, stderr.Bytes())
+				if !retrying {
+					errs.Append(err, hookErr)
+				}
+				if err == nil {
+					err = hookErr
 				}
-				logMsg.Msg("command execution finished")
 
-				c.cloudSyncAfter(runContext, res, err)
+				c.afterStackRun(runContext, res, err)
+				if retrying {
+					retryErr = err
+				}
 				cmdIsRunning = false
 			}
 		}
 
+		if retryErr != nil {
+			delay := opts.retry.delay(attempt)
+
+			logger.Warn().
+				Int("attempt", attempt).
+				Dur("backoff", delay).
+				Msg("command failed, retrying")
+
+			select {
+			case <-time.After(delay):
+				i--
+				continue
+			case sig := <-signals:
+				interruptions++
+
+				logger.Info().
+					Str("signal", sig.String()).
+					Msg("received interruption signal, not retrying")
+
+				errs.Append(retryErr)
+			}
+		}
+
 		err = errs.AsError()
 		if interruptions > 0 || (err != nil && !continueOnError) {
 			logger.Info().Msg("interrupting execution of further stacks")
 
-			c.cloudSyncCancelStacks(runStacks[i+1:])
+			reason := cancelReasonFailure
+			if interruptions > 0 {
+				reason = cancelReasonInterrupted
+			}
+			c.cancelStacks(runStacks[i+1:], reason)
 			return errs.AsError()
 		}
 	}
@@ -343,6 +520,556 @@ func (c *cli) RunAll(runStacks []ExecContext, isSuccessCode func(exitCode int) b
 	return errs.AsError()
 }
 
+type stackRunState int
+
+const (
+	stackPending stackRunState = iota
+	stackRunning
+	stackSucceeded
+	stackFailed
+	stackCanceled
+)
+
+type runningStack struct {
+	proc        *stackProcess
+	startedAt   time.Time
+	stderr      *bytes.Buffer
+	timeout     *cmdTimeout
+	logSyncWait func()
+	logger      zerolog.Logger
+}
+
+// runAllParallel executes the runStacks concurrently respecting the order
+// defined by their dependencies. The signal handling follows the same semantics
+// of the sequential execution, but applied to all running stacks: a single
+// SIGINT stops the scheduling of new stacks and waits for the running ones to
+// finish and 3x SIGINT kills all of them.
+// A failed stack which must be retried goes back to pending and is scheduled
+// again after the retry backoff.
+func (c *cli) runAllParallel(
+	runStacks []ExecContext,
+	stackEnvs map[prj.Path]run.EnvVars,
+	stackHooks map[prj.Path]run.Hooks,
+	outputs stackOutputs,
+	opts runOptions,
+	signals <-chan os.Signal,
+	isSuccessCode func(exitCode int) bool,
+) error {
+	logger := log.With().
+		Str("action", "cli.runAllParallel()").
+		Int("parallel", opts.parallel).
+		Logger()
+
+	errs := errors.L()
+	continueOnError := opts.continueOnError
+	maxParallel := opts.parallel
+
+	states := make([]stackRunState, len(runStacks))
+	stackIndex := map[prj.Path]int{}
+	for i, runContext := range runStacks {
+		stackIndex[runContext.Stack.Dir] = i
+	}
+
+	// depsState returns stackSucceeded if all dependencies of the stack
+	// finished successfully, stackCanceled if any of them failed or was
+	// canceled and stackPending otherwise.
+	depsState := func(runContext ExecContext) stackRunState {
+		state := stackSucceeded
+		for _, dep := range runContext.Deps {
+			i, ok := stackIndex[dep]
+			if !ok {
+				continue
+			}
+			switch states[i] {
+			case stackFailed, stackCanceled:
+				return stackCanceled
+			case stackPending, stackRunning:
+				state = stackPending
+			}
+		}
+		return state
+	}
+
+	results := make(chan cmdResult, len(runStacks))
+	timeouts := make(chan *cmdTimeout, 3*maxParallel)
+	running := map[int]*runningStack{}
+	attempts := make([]int, len(runStacks))
+	retryAt := map[int]time.Time{}
+	retryErrs := map[int]error{}
+	aborted := false
+	interruptions := 0
+
+	fail := func(i int, err error) {
+		states[i] = stackFailed
+		errs.Append(err)
+		if !continueOnError {
+			aborted = true
+		}
+	}
+
+	schedule := func() {
+		for i, runContext := range runStacks {
+			if aborted || len(running) >= maxParallel {
+				return
+			}
+
+			if states[i] != stackPending {
+				continue
+			}
+
+			if at, ok := retryAt[i]; ok {
+				if time.Now().Before(at) {
+					continue
+				}
+				delete(retryAt, i)
+			}
+
+			switch depsState(runContext) {
+			case stackPending:
+				continue
+			case stackCanceled:
+				logger.Info().
+					Stringer("stack", runContext.Stack).
+					Msg("canceling stack because one of its dependencies failed")
+
+				states[i] = stackCanceled
+				c.cancelStacks([]ExecContext{runContext}, cancelReasonDependency)
+				continue
+			}
+
+			cmdStr := strings.Join(runContext.Command(), " ")
+			logger := log.With().
+				Str("cmd", cmdStr).
+				Stringer("stack", runContext.Stack).
+				Logger()
+
+			c.cloudSyncBefore(runContext, cmdStr)
+
+			stackEnv := stackEnvs[runContext.Stack.Dir]
+			hooks := stackHooks[runContext.Stack.Dir]
+
+			output, err := outputs.get(c, runContext.Stack, opts)
+			if err == nil {
+				output.StartAttempt(attempts[i] + 1)
+			}
+
+			var proc *stackProcess
+			var logSyncWait func()
+			if err == nil {
+				proc, logSyncWait, err = c.newStackProcess(&logger, runContext, stackEnv, hooks, output, isSuccessCode)
+			}
+			if err != nil {
+				c.afterStackRun(runContext, RunResult{ExitCode: -1}, err)
+				fail(i, errors.E(err, "running `%s` in stack %s", cmdStr, runContext.Stack.Dir))
+				continue
+			}
+
+			stderr := opts.retry.captureStderr(proc)
+			attempts[i]++
+
+			logger.Info().Int("attempt", attempts[i]).Msg("running")
+
+			startTime := time.Now().UTC()
+
+			states[i] = stackRunning
+			r := &runningStack{
+				proc:        proc,
+				startedAt:   startTime,
+				stderr:      stderr,
+				logSyncWait: logSyncWait,
+				logger:      logger,
+			}
+			r.timeout = opts.timeout.start(runContext.Stack, &r.logger, timeouts)
+			running[i] = r
+
+			go func(index int, proc *stackProcess) {
+				result := waitStackProcess(proc)
+				result.index = index
+				results <- result
+			}(i, proc)
+		}
+	}
+
+	for {
+		schedule()
+
+		if len(running) == 0 && (aborted || len(retryAt) == 0) {
+			break
+		}
+
+		// retryTimer fires when the next failed stack must be retried.
+		var retryTimer <-chan time.Time
+		if !aborted && len(retryAt) > 0 {
+			var next time.Time
+			for _, at := range retryAt {
+				if next.IsZero() || at.Before(next) {
+					next = at
+				}
+			}
+			retryTimer = time.After(time.Until(next))
+		}
+
+		select {
+		case <-retryTimer:
+		case expired := <-timeouts:
+			// the timeouts of the commands which already finished are ignored.
+			for _, r := range running {
+				if r.timeout == expired {
+					expired.escalate(r.proc)
+				}
+			}
+		case sig := <-signals:
+			interruptions++
+
+			logger.Info().
+				Str("signal", sig.String()).
+				Int("interruptions", interruptions).
+				Msg("received interruption signal")
+
+			if !aborted {
+				logger.Info().Msg("interrupting execution of further stacks")
+				aborted = true
+			}
+
+			for _, r := range running {
+				r.proc.Stop()
+			}
+
+			if interruptions >= 3 {
+				logger.Info().Msg("interrupted 3x times or more, killing child processes")
+
+				for _, r := range running {
+					if err := r.proc.Signal(os.Kill); err != nil {
+						r.logger.Debug().Err(err).Msg("unable to send kill signal to child process")
+					}
+				}
+			}
+		case result := <-results:
+			r := running[result.index]
+			delete(running, result.index)
+			r.timeout.stop()
+
+			runContext := runStacks[result.index]
+			logger := r.logger
+
+			logger.Trace().Msg("got command result")
+			r.logSyncWait()
+
+			res := RunResult{
+				ExitCode:   result.exitCode(),
+				StartedAt:  &r.startedAt,
+				FinishedAt: result.finishedAt,
+				Attempt:    attempts[result.index],
+			}
+
+			if interruptions >= 3 {
+				res.ExitCode = -1
+				states[result.index] = stackCanceled
+				c.afterStackRun(runContext, res, errors.E(ErrRunCanceled, cancelReasonKilled))
+				continue
+			}
+
+			logRunResult(&logger, res)
+
+			err := stackCmdError(runContext, result, r.proc, r.timeout, isSuccessCode)
+			cmdFailed := err != nil
+			if cmdFailed {
+				logger.Error().Err(err).Msg("failed to execute")
+			}
+
+			hookErr := stackHookError(runContext, result)
+
+			retrying := cmdFailed && !r.timeout.expired() && !aborted &&
+				opts.retry.shouldRetry(res.Attempt, r.stderr.Bytes())
+			if hookErr != nil {
+				if err == nil {
+					err = hookErr
+				} else if !retrying {
+					errs.Append(hookErr)
+				}
+			}
+
+			switch {
+			case retrying:
+				delay := opts.retry.delay(res.Attempt)
+
+				logger.Warn().
+					Int("attempt", res.Attempt).
+					Dur("backoff", delay).
+					Msg("command failed, retrying")
+
+				states[result.index] = stackPending
+				retryAt[result.index] = time.Now().Add(delay)
+				retryErrs[result.index] = err
+			case err != nil:
+				fail(result.index, err)
+			default:
+				states[result.index] = stackSucceeded
+			}
+
+			c.afterStackRun(runContext, res, err)
+		}
+	}
+
+	var canceled []ExecContext
+	for i, state := range states {
+		if state != stackPending {
+			continue
+		}
+		// the stacks waiting for a retry when the execution was aborted
+		// keep the error of their last attempt.
+		if _, ok := retryAt[i]; ok {
+			errs.Append(retryErrs[i])
+			continue
+		}
+		canceled = append(canceled, runStacks[i])
+	}
+	reason := cancelReasonFailure
+	switch {
+	case interruptions >= 3:
+		reason = cancelReasonKilled
+	case interruptions > 0:
+		reason = cancelReasonInterrupted
+	}
+	c.cancelStacks(canceled, reason)
+
+	if interruptions >= 3 {
+		return errors.E(ErrRunCanceled, cancelReasonKilled)
+	}
+	return errs.AsError()
+}
+
+// stackCmdError returns the error of a finished stack execution, if any, given
+// the result of its last executed command.
+// A command stopped because of its timeout always fails with the
+// ErrRunTimeout kind, whatever its exit code. If the execution was interrupted
+// before all the commands were executed, it fails with the ErrRunCanceled kind.
+func stackCmdError(
+	runContext ExecContext,
+	result cmdResult,
+	proc *stackProcess,
+	timeout *cmdTimeout,
+	isSuccessCode func(exitCode int) bool,
+) error {
+	if timeout.expired() {
+		cmd := "the before hooks"
+		if result.cmd != nil {
+			cmd = result.cmd.String()
+		}
+		return errors.E(result.err, ErrRunTimeout, "running %s (at stack %s) timed out after %s",
+			cmd, runContext.Stack.Dir, timeout.duration)
+	}
+	if result.cmd == nil {
+		// the commands were not executed because a before hook failed or the
+		// process was stopped before they started.
+		if proc.Skipped() && result.hookErr == nil {
+			return errors.E(ErrRunCanceled, cancelReasonInterrupted)
+		}
+		return nil
+	}
+	if !isSuccessCode(result.exitCode()) {
+		return errors.E(result.err, ErrRunFailed, "running %s (at stack %s)", result.cmd, runContext.Stack.Dir)
+	}
+	if proc.Skipped() {
+		return errors.E(ErrRunCanceled, cancelReasonInterrupted)
+	}
+	return nil
+}
+
+// stackHookError returns the error of the hooks of a finished stack execution,
+// if any.
+func stackHookError(runContext ExecContext, result cmdResult) error {
+	if result.hookErr == nil {
+		return nil
+	}
+	return errors.E(result.hookErr, "at stack %s", runContext.Stack.Dir)
+}
+
+// newStackProcess creates the process executing the hooks and the commands of
+// the given stack execution context, writing their output to the given stack
+// output, if any. All the commands are looked up before any of them is
+// executed.
+// The returned function must be called after the process finishes, so all
+// its output is guaranteed to be processed.
+// The returned error has either the ErrRunCommandNotFound or the ErrRunFailed
+// kind.
+func (c *cli) newStackProcess(
+	logger *zerolog.Logger,
+	runContext ExecContext,
+	stackEnv run.EnvVars,
+	hooks run.Hooks,
+	output *stackOutput,
+	isSuccessCode func(exitCode int) bool,
+) (*stackProcess, func(), error) {
+	environ := c.newStackEnviron(runContext.Stack, stackEnv)
+	stackdir := runContext.Stack.HostDir(c.cfg())
+
+	var cmds []*exec.Cmd
+	for _, args := range runContext.Cmds {
+		cmd, err := newCmd(args, stackdir, environ)
+		if err != nil {
+			return nil, nil, errors.E(ErrRunCommandNotFound, err)
+		}
+		cmds = append(cmds, cmd)
+	}
+
+	newHooks := func(kind string, hooks [][]string) ([]*exec.Cmd, error) {
+		var cmds []*exec.Cmd
+		for _, hook := range hooks {
+			// the environment is copied because the exit code is added to
+			// the environment of the after hooks.
+			cmd, err := newCmd(hook, stackdir, append([]string{}, environ...))
+			if err != nil {
+				return nil, errors.E(ErrRunCommandNotFound, err,
+					"running %s hook `%s` (at stack %s)", kind, strings.Join(hook, " "), runContext.Stack.Dir)
+			}
+			cmds = append(cmds, cmd)
+		}
+		return cmds, nil
+	}
+	before, err := newHooks("before", hooks.Before)
+	if err != nil {
+		return nil, nil, err
+	}
+	after, err := newHooks("after", hooks.After)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stdout := c.stdout
+	stderr := c.stderr
+	outputWait := func() {}
+
+	if output != nil {
+		stdout = output.NewWriter(c.stdout)
+		stderr = output.NewWriter(c.stderr)
+		outputWait = output.Wait
+	}
+
+	logSyncWait := func() {}
+	if c.cloudEnabled() && c.cloudSync.deployment {
+		logSyncer := cloud.NewLogSyncer(func(logs cloud.DeploymentLogs) {
+			c.syncLogs(logger, runContext, logs)
+		})
+		stdout = logSyncer.NewBuffer(cloud.StdoutLogChannel, stdout)
+		stderr = logSyncer.NewBuffer(cloud.StderrLogChannel, stderr)
+
+		logSyncWait = logSyncer.Wait
+	}
+
+	proc := newStackProcess(before, cmds, after, isSuccessCode)
+	for _, cmd := range proc.all() {
+		cmd.Stdout = stdout
+		cmd.Stderr = stderr
+	}
+	return proc, func() {
+		// the log syncer writes to the stack output, then it must be
+		// waited first.
+		logSyncWait()
+		outputWait()
+	}, nil
+}
+
+// afterStackRun must be called after the execution of every stack, whatever
+// the result of the execution.
+func (c *cli) afterStackRun(runContext ExecContext, res RunResult, err error) {
+	c.runStateAfter(runContext, err)
+	c.runReportAfter(runContext, res, err)
+	c.cloudSyncAfter(runContext, res, err)
+}
+
+// cancelStacks must be called for the stacks that will not be executed.
+// The reason tells why the stacks are not going to be executed.
+func (c *cli) cancelStacks(stacks []ExecContext, reason string) {
+	err := errors.E(ErrRunCanceled, reason)
+	for _, run := range stacks {
+		c.runStateAfter(run, err)
+		c.runReportAfter(run, RunResult{ExitCode: -1}, err)
+	}
+	c.cloudSyncCancelStacks(stacks)
+}
+
+// runHooks executes the hook commands in order, stopping at the first failure.
+// The returned error has either the ErrRunCommandNotFound or the ErrRunFailed
+// kind.
+func (c *cli) runHooks(logger *zerolog.Logger, kind string, hooks [][]string, dir string, environ []string) error {
+	for _, hook := range hooks {
+		hookStr := strings.Join(hook, " ")
+
+		logger.Debug().
+			Str("hook", kind).
+			Str("hook_cmd", hookStr).
+			Msg("running hook")
+
+		cmd, err := newCmd(hook, dir, environ)
+		if err != nil {
+			return errors.E(ErrRunCommandNotFound, err, "running %s hook `%s`", kind, hookStr)
+		}
+		cmd.Stdin = c.stdin
+		cmd.Stdout = c.stdout
+		cmd.Stderr = c.stderr
+
+		if err := cmd.Run(); err != nil && !errors.Is(err, exec.ErrWaitDelay) {
+			return errors.E(ErrRunFailed, err, "running %s hook `%s`", kind, hookStr)
+		}
+	}
+	return nil
+}
+
+// cmdWaitDelay is how long to wait for the output of a command to be closed
+// after it exits. The output is kept open by the processes it left running in
+// the background, which are not stopped by its signals.
+const cmdWaitDelay = 5 * time.Second
+
+// newCmd creates the command with the given args, looking up the program in
+// the PATH of the given environment.
+func newCmd(args []string, dir string, environ []string) (*exec.Cmd, error) {
+	cmdPath, err := run.LookPath(args[0], environ)
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command(cmdPath, args[1:]...)
+	cmd.Dir = dir
+	cmd.Env = environ
+	cmd.WaitDelay = cmdWaitDelay
+	return cmd, nil
+}
+
+// exitCodeEnv returns the environment variable which exposes the exit code of
+// the stack command to the after hooks.
+func exitCodeEnv(exitCode int) string {
+	return "TM_RUN_EXIT_CODE=" + strconv.Itoa(exitCode)
+}
+
+func logRunResult(logger *zerolog.Logger, res RunResult) {
+	logMsg := logger.Debug().Int("exit_code", res.ExitCode)
+	if res.StartedAt != nil && res.FinishedAt != nil {
+		logMsg = logMsg.
+			Time("started_at", *res.StartedAt).
+			Time("finished_at", *res.FinishedAt).
+			TimeDiff("duration", *res.FinishedAt, *res.StartedAt)
+	}
+	logMsg.Msg("command execution finished")
+}
+
+// reverseDeps inverts the dependencies, making every stack depend on the
+// stacks that previously depended on it.
+func reverseDeps(deps run.Deps) run.Deps {
+	reversed := run.Deps{}
+	for stack, stackDeps := range deps {
+		if _, ok := reversed[stack]; !ok {
+			reversed[stack] = nil
+		}
+		for _, dep := range stackDeps {
+			reversed[dep] = append(reversed[dep], stack)
+		}
+	}
+	for _, stackDeps := range reversed {
+		stackDeps.Sort()
+	}
+	return reversed
+}
+
 func (c *cli) syncLogs(logger *zerolog.Logger, runContext ExecContext, logs cloud.DeploymentLogs) {
 	data, _ := json.Marshal(logs)
 	logger.Debug().RawJSON("logs", data).Msg("synchronizing logs")
@@ -357,33 +1084,63 @@ func (c *cli) syncLogs(logger *zerolog.Logger, runContext ExecContext, logs clou
 	}
 }
 
+// cmdResult is the result of a stack process. The cmd is the last executed
+// command, which is nil if no command was executed, and err is its error.
 type cmdResult struct {
+	index      int
 	cmd        *exec.Cmd
 	err        error
+	hookErr    error
 	finishedAt *time.Time
 }
 
-func startCmdConsumer(cmds <-chan *exec.Cmd) <-chan cmdResult {
+// exitCode returns the exit code of the last executed command, or -1 if no
+// command was executed.
+func (r cmdResult) exitCode() int {
+	if r.cmd == nil {
+		return -1
+	}
+	return r.cmd.ProcessState.ExitCode()
+}
+
+func waitStackProcess(proc *stackProcess) cmdResult {
+	cmd, err, hookErr := proc.Wait()
+	endTime := time.Now().UTC()
+	return cmdResult{
+		cmd:        cmd,
+		err:        err,
+		hookErr:    hookErr,
+		finishedAt: &endTime,
+	}
+}
+
+func startCmdConsumer(procs <-chan *stackProcess) <-chan cmdResult {
 	results := make(chan cmdResult)
 	go func() {
-		for cmd := range cmds {
-			err := cmd.Wait()
-			endTime := time.Now().UTC()
-
-			results <- cmdResult{
-				cmd:        cmd,
-				err:        err,
-				finishedAt: &endTime,
-			}
+		for proc := range procs {
+			results <- waitStackProcess(proc)
 		}
 		close(results)
 	}()
 	return results
 }
 
-func newEnvironFrom(stackEnviron []string) []string {
-	environ := make([]string, len(os.Environ()))
-	copy(environ, os.Environ())
+// newStackEnviron returns the environment of the commands executed in the
+// stack: the environment of Terramate, without the variables unset in the
+// stack run env, followed by the stack run env.
+func (c *cli) newStackEnviron(st *config.Stack, stackEnviron []string) []string {
+	unset := map[string]bool{}
+	for _, name := range run.UnsetEnv(c.cfg(), st) {
+		unset[name] = true
+	}
+
+	var environ []string
+	for _, env := range os.Environ() {
+		name, _, _ := strings.Cut(env, "=")
+		if !unset[name] {
+			environ = append(environ, env)
+		}
+	}
 	environ = append(environ, stackEnviron...)
 	return environ
 }
@@ -408,3 +1165,27 @@ func (c *cli) loadAllStackEnvs(runStacks []ExecContext) (map[prj.Path]run.EnvVar
 	}
 	return stackEnvs, nil
 }
+
+func (c *cli) loadAllStackHooks(runStacks []ExecContext) (map[prj.Path]run.Hooks, error) {
+	logger := log.With().
+		Str("action", "cli.loadAllStackHooks").
+		Logger()
+
+	logger.Trace().Msg("loading stacks run hooks")
+
+	errs := errors.L()
+	stackHooks := map[prj.Path]run.Hooks{}
+	for _, elem := range runStacks {
+		if _, ok := stackHooks[elem.Stack.Dir]; ok {
+			continue
+		}
+		hooks, err := run.LoadStackHooks(c.cfg(), elem.Stack)
+		errs.Append(err)
+		stackHooks[elem.Stack.Dir] = hooks
+	}
+
+	if errs.AsError() != nil {
+		return nil, errs.AsError()
+	}
+	return stackHooks, nil
+}
@@ -0,0 +1,245 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package cli
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/terramate-io/terramate/config"
+	"github.com/terramate-io/terramate/run"
+	"github.com/terramate-io/terramate/run/dag"
+)
+
+// runGraph is the format independent representation of the graph printed by
+// the run-graph command.
+type runGraph struct {
+	Nodes []runGraphNode `json:"nodes"`
+	Edges []runGraphEdge `json:"edges"`
+}
+
+// runGraphNode is a stack in the graph. The ID of the node is the stack path,
+// which is always defined and unique, while the StackID is the optional ID of
+// the stack.
+type runGraphNode struct {
+	ID      string   `json:"id"`
+	StackID string   `json:"stack_id"`
+	Name    string   `json:"name"`
+	Path    string   `json:"path"`
+	Tags    []string `json:"tags"`
+
+	stack *config.Stack
+}
+
+// runGraphEdge is an edge between two stacks. The From stack runs after or
+// wants the To stack and the Kind is the stack attribute which defines the
+// relationship: after, before, wants or wanted_by, or inferred if the From
+// stack reads the Terraform state of the To stack.
+type runGraphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Kind string `json:"kind"`
+
+	cycle bool
+}
+
+// newRunGraph creates a graph with the nodes and edges of all the given DAGs.
+// The DAGs must be built with [run.BuildDAG].
+func newRunGraph(graphs ...*dag.DAG) (runGraph, error) {
+	var g runGraph
+	visited := dag.Visited{}
+	for _, d := range graphs {
+		for _, id := range d.IDs() {
+			val, err := d.Node(id)
+			if err != nil {
+				return runGraph{}, err
+			}
+
+			if _, ok := visited[id]; !ok {
+				visited[id] = struct{}{}
+
+				st := val.(*config.Stack)
+				tags := st.Tags
+				if tags == nil {
+					tags = []string{}
+				}
+				g.Nodes = append(g.Nodes, runGraphNode{
+					ID:      st.Dir.String(),
+					StackID: st.ID,
+					Name:    st.Name,
+					Path:    st.Dir.String(),
+					Tags:    tags,
+					stack:   st,
+				})
+			}
+
+			for _, ancestor := range d.AncestorsOf(id) {
+				for _, kind := range d.EdgeKinds(id, ancestor) {
+					g.Edges = append(g.Edges, runGraphEdge{
+						From:  string(id),
+						To:    string(ancestor),
+						Kind:  kind,
+						cycle: d.HasCycle(ancestor),
+					})
+				}
+			}
+		}
+	}
+
+	sort.Slice(g.Nodes, func(i, j int) bool {
+		return g.Nodes[i].Path < g.Nodes[j].Path
+	})
+	sort.Slice(g.Edges, func(i, j int) bool {
+		a, b := g.Edges[i], g.Edges[j]
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		if a.To != b.To {
+			return a.To < b.To
+		}
+		return a.Kind < b.Kind
+	})
+	return g, nil
+}
+
+func (g runGraph) writeJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(g)
+}
+
+// writeMermaid writes the graph as a Mermaid flowchart. The edges defined
+// by the wants and wanted_by attributes and the inferred edges are dotted and
+// the edges which are part of a cycle are red.
+func (g runGraph) writeMermaid(w io.Writer, getLabel func(s *config.Stack) string) error {
+	var b strings.Builder
+	b.WriteString("flowchart TD\n")
+
+	nodeIDs := map[string]string{}
+	for i, n := range g.Nodes {
+		id := fmt.Sprintf("n%d", i+1)
+		nodeIDs[n.Path] = id
+		fmt.Fprintf(&b, "    %s[\"%s\"]\n", id, mermaidEscape(getLabel(n.stack)))
+	}
+
+	var cycleEdges []string
+	for i, e := range g.Edges {
+		arrow := "-->"
+		if e.Kind == "wants" || e.Kind == "wanted_by" || e.Kind == run.InferredKind {
+			arrow = "-.->"
+		}
+		fmt.Fprintf(&b, "    %s %s|%s| %s\n", nodeIDs[e.From], arrow, e.Kind, nodeIDs[e.To])
+		if e.cycle {
+			cycleEdges = append(cycleEdges, fmt.Sprint(i))
+		}
+	}
+
+	if len(cycleEdges) > 0 {
+		fmt.Fprintf(&b, "    linkStyle %s stroke:red\n", strings.Join(cycleEdges, ","))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// mermaidEscape escapes the characters which cannot be used inside a quoted
+// Mermaid label.
+func mermaidEscape(s string) string {
+	return strings.ReplaceAll(s, `"`, "#quot;")
+}
+
+type (
+	graphML struct {
+		XMLName xml.Name     `xml:"graphml"`
+		XMLNS   string       `xml:"xmlns,attr"`
+		Keys    []graphMLKey `xml:"key"`
+		Graph   graphMLGraph `xml:"graph"`
+	}
+
+	graphMLKey struct {
+		ID       string `xml:"id,attr"`
+		For      string `xml:"for,attr"`
+		AttrName string `xml:"attr.name,attr"`
+		AttrType string `xml:"attr.type,attr"`
+	}
+
+	graphMLGraph struct {
+		ID          string        `xml:"id,attr"`
+		EdgeDefault string        `xml:"edgedefault,attr"`
+		Nodes       []graphMLNode `xml:"node"`
+		Edges       []graphMLEdge `xml:"edge"`
+	}
+
+	graphMLNode struct {
+		ID   string        `xml:"id,attr"`
+		Data []graphMLData `xml:"data"`
+	}
+
+	graphMLEdge struct {
+		Source string        `xml:"source,attr"`
+		Target string        `xml:"target,attr"`
+		Data   []graphMLData `xml:"data"`
+	}
+
+	graphMLData struct {
+		Key   string `xml:"key,attr"`
+		Value string `xml:",chardata"`
+	}
+)
+
+// writeGraphML writes the graph in the GraphML format. The node ids are the
+// stack paths.
+func (g runGraph) writeGraphML(w io.Writer, getLabel func(s *config.Stack) string) error {
+	doc := graphML{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: "label", For: "node", AttrName: "label", AttrType: "string"},
+			{ID: "stack_id", For: "node", AttrName: "stack_id", AttrType: "string"},
+			{ID: "name", For: "node", AttrName: "name", AttrType: "string"},
+			{ID: "tags", For: "node", AttrName: "tags", AttrType: "string"},
+			{ID: "kind", For: "edge", AttrName: "kind", AttrType: "string"},
+		},
+		Graph: graphMLGraph{
+			ID:          "run-graph",
+			EdgeDefault: "directed",
+		},
+	}
+
+	for _, n := range g.Nodes {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{
+			ID: n.ID,
+			Data: []graphMLData{
+				{Key: "label", Value: getLabel(n.stack)},
+				{Key: "stack_id", Value: n.StackID},
+				{Key: "name", Value: n.Name},
+				{Key: "tags", Value: strings.Join(n.Tags, ",")},
+			},
+		})
+	}
+
+	for _, e := range g.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{
+			Source: e.From,
+			Target: e.To,
+			Data: []graphMLData{
+				{Key: "kind", Value: e.Kind},
+			},
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
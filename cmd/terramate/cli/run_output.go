@@ -0,0 +1,193 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package cli
+
+import (
+	stdfmt "fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+	"github.com/terramate-io/terramate/cloud"
+	"github.com/terramate-io/terramate/config"
+	"github.com/terramate-io/terramate/errors"
+	prj "github.com/terramate-io/terramate/project"
+)
+
+// stackLogFilename is the name of the file, inside the stack directory created
+// in the --output-dir directory, that stores the combined output of the stack.
+const stackLogFilename = "output.log"
+
+// outputMu serializes the writing of output lines, so lines from stacks
+// running in parallel are never interleaved.
+var outputMu sync.Mutex
+
+// stackOutput handles the output of the commands of a stack. It can prefix
+// every output line with the stack path and also capture the combined stdout
+// and stderr of the commands into a log file.
+// The same stackOutput is used by all the commands and the retry attempts of
+// the stack in a run, so the log file has the output of all of them.
+type stackOutput struct {
+	prefix  string
+	logfile *os.File
+
+	fds []io.Closer
+	wg  sync.WaitGroup
+}
+
+// newStackOutput creates a stack output handler for the given stack, which
+// must be closed after the last command of the stack finishes.
+// The log file is truncated when the handler is created and only appended
+// afterwards. It returns nil if no output handling is needed.
+func (c *cli) newStackOutput(st *config.Stack, opts runOptions) (*stackOutput, error) {
+	prefixOutput := opts.prefixOutput
+	outputDir := opts.outputDir
+
+	if !prefixOutput && outputDir == "" {
+		return nil, nil
+	}
+
+	o := &stackOutput{}
+	if prefixOutput {
+		o.prefix = stdfmt.Sprintf("[%s] ", st.Dir)
+	}
+
+	if outputDir != "" {
+		if !filepath.IsAbs(outputDir) {
+			outputDir = filepath.Join(c.wd(), outputDir)
+		}
+		logdir := filepath.Join(outputDir, filepath.FromSlash(st.RelPath()))
+		if err := os.MkdirAll(logdir, 0755); err != nil {
+			return nil, errors.E(err, "creating output directory for stack %s", st.Dir)
+		}
+		logfile, err := os.OpenFile(filepath.Join(logdir, stackLogFilename),
+			os.O_CREATE|os.O_WRONLY|os.O_TRUNC|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, errors.E(err, "creating output file for stack %s", st.Dir)
+		}
+		o.logfile = logfile
+	}
+	return o, nil
+}
+
+// stackOutputs are the output handlers of the stacks of a run, indexed by
+// the stack path.
+type stackOutputs map[prj.Path]*stackOutput
+
+// get returns the output handler of the stack, creating it at the first
+// execution of the stack in the run.
+func (outputs stackOutputs) get(c *cli, st *config.Stack, opts runOptions) (*stackOutput, error) {
+	if o, ok := outputs[st.Dir]; ok {
+		return o, nil
+	}
+	o, err := c.newStackOutput(st, opts)
+	if err != nil {
+		return nil, errors.E(ErrRunFailed, err)
+	}
+	outputs[st.Dir] = o
+	return o, nil
+}
+
+// Close closes all the output handlers.
+func (outputs stackOutputs) Close() {
+	for _, o := range outputs {
+		o.Close()
+	}
+}
+
+// NewWriter creates a new writer which writes the output lines to out.
+func (o *stackOutput) NewWriter(out io.Writer) io.Writer {
+	r, w := io.Pipe()
+	o.fds = append(o.fds, w)
+	o.wg.Add(1)
+	go func() {
+		defer o.wg.Done()
+
+		var pending []byte
+		errs := errors.L()
+		for {
+			lines, rest, readErr := cloud.ReadLines(r, pending)
+			if readErr != nil && readErr != io.EOF {
+				errs.Append(readErr)
+				break
+			}
+			if readErr == io.EOF && len(rest) > 0 {
+				lines = [][]byte{rest}
+			}
+			for _, line := range lines {
+				errs.Append(o.writeLine(out, line))
+			}
+			if readErr == io.EOF {
+				break
+			}
+			pending = rest
+		}
+
+		errs.Append(r.Close())
+		if err := errs.AsError(); err != nil {
+			log.Error().Err(err).Msg("writing command output")
+		}
+	}()
+	return w
+}
+
+func (o *stackOutput) writeLine(out io.Writer, line []byte) error {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+
+	errs := errors.L()
+	if o.prefix != "" {
+		prefixed := make([]byte, 0, len(o.prefix)+len(line))
+		prefixed = append(prefixed, o.prefix...)
+		prefixed = append(prefixed, line...)
+		line = prefixed
+	}
+	if _, err := out.Write(line); err != nil {
+		errs.Append(errors.E(err, "writing to terminal"))
+	}
+	if o.logfile != nil {
+		if _, err := o.logfile.Write(line[len(o.prefix):]); err != nil {
+			errs.Append(errors.E(err, "writing to output file"))
+		}
+	}
+	return errs.AsError()
+}
+
+// StartAttempt must be called before the commands of each retry attempt of
+// the stack, then the output of the attempts are separated in the log file.
+func (o *stackOutput) StartAttempt(attempt int) {
+	if o == nil || o.logfile == nil || attempt <= 1 {
+		return
+	}
+
+	outputMu.Lock()
+	defer outputMu.Unlock()
+
+	if _, err := stdfmt.Fprintf(o.logfile, "\n--- attempt %d ---\n\n", attempt); err != nil {
+		log.Error().Err(err).Msg("writing to output file")
+	}
+}
+
+// Wait waits for all the output written to the writers created so far to be
+// processed. It must be called after the command using the writers finishes.
+func (o *stackOutput) Wait() {
+	for _, w := range o.fds {
+		_ = w.Close()
+	}
+	o.wg.Wait()
+	o.fds = nil
+}
+
+// Close closes the log file, if any. After calling this method, it's not safe
+// to call any other method.
+func (o *stackOutput) Close() {
+	if o == nil || o.logfile == nil {
+		return
+	}
+	if err := o.logfile.Close(); err != nil {
+		log.Error().Err(err).Msg("closing stack output file")
+	}
+}
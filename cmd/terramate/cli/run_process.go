@@ -0,0 +1,155 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package cli
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/terramate-io/terramate/errors"
+)
+
+// stackProcess executes the before hooks, the commands and the after hooks of
+// a stack execution one after the other. The commands are not executed if a
+// before hook fails and the execution stops at the first command which fails.
+// The after hooks are executed whatever the result of the commands, unless the
+// process was killed.
+type stackProcess struct {
+	before        []*exec.Cmd
+	cmds          []*exec.Cmd
+	after         []*exec.Cmd
+	isSuccessCode func(exitCode int) bool
+
+	mu       sync.Mutex
+	current  *exec.Cmd
+	executed int
+	stopped  bool
+	killed   bool
+}
+
+func newStackProcess(before, cmds, after []*exec.Cmd, isSuccessCode func(exitCode int) bool) *stackProcess {
+	return &stackProcess{
+		before:        before,
+		cmds:          cmds,
+		after:         after,
+		isSuccessCode: isSuccessCode,
+	}
+}
+
+// all returns all the commands of the process, including the hooks.
+func (p *stackProcess) all() []*exec.Cmd {
+	all := make([]*exec.Cmd, 0, len(p.before)+len(p.cmds)+len(p.after))
+	all = append(all, p.before...)
+	all = append(all, p.cmds...)
+	return append(all, p.after...)
+}
+
+// Wait executes the process and waits for it to finish. It returns the last
+// executed command and its error, which is nil if no command was executed,
+// and the error of the hooks, if any. The exit code of the last executed
+// command is available to the after hooks in the TM_RUN_EXIT_CODE environment
+// variable.
+func (p *stackProcess) Wait() (cmd *exec.Cmd, err error, hookErr error) {
+	if hookErr := p.runHooks("before", p.before); hookErr != nil {
+		return nil, nil, hookErr
+	}
+
+	for _, next := range p.cmds {
+		started, startErr := p.start(next, true)
+		if !started {
+			break
+		}
+		cmd, err = next, startErr
+		if err == nil {
+			err = cmd.Wait()
+		}
+		p.mu.Lock()
+		p.executed++
+		p.mu.Unlock()
+		if !p.isSuccessCode(cmd.ProcessState.ExitCode()) {
+			break
+		}
+	}
+
+	p.mu.Lock()
+	killed := p.killed
+	p.mu.Unlock()
+	if killed {
+		return cmd, err, nil
+	}
+
+	exitCode := -1
+	if cmd != nil {
+		exitCode = cmd.ProcessState.ExitCode()
+	}
+	for _, hook := range p.after {
+		hook.Env = append(hook.Env, exitCodeEnv(exitCode))
+	}
+	return cmd, err, p.runHooks("after", p.after)
+}
+
+// runHooks executes the hooks in order, stopping at the first failure. The
+// before hooks are not executed if the process was stopped.
+// The returned error has either the ErrRunCanceled or the ErrRunFailed kind.
+func (p *stackProcess) runHooks(kind string, hooks []*exec.Cmd) error {
+	for _, hook := range hooks {
+		hookStr := strings.Join(hook.Args, " ")
+		started, err := p.start(hook, kind == "before")
+		if !started {
+			return errors.E(ErrRunCanceled, cancelReasonInterrupted)
+		}
+		if err == nil {
+			err = hook.Wait()
+		}
+		if err != nil && !errors.Is(err, exec.ErrWaitDelay) {
+			return errors.E(ErrRunFailed, err, "running %s hook `%s`", kind, hookStr)
+		}
+	}
+	return nil
+}
+
+// start starts the command as the current one of the process. If stoppable is
+// true, the command is not started when the process was stopped and then it
+// returns false.
+func (p *stackProcess) start(cmd *exec.Cmd, stoppable bool) (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if stoppable && p.stopped {
+		return false, nil
+	}
+	p.current = cmd
+	return true, cmd.Start()
+}
+
+// Stop prevents the next commands and before hooks from being started.
+func (p *stackProcess) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stopped = true
+}
+
+// Signal stops the process and sends the signal to the running command. When
+// the signal is os.Kill, the after hooks are not executed.
+func (p *stackProcess) Signal(sig os.Signal) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stopped = true
+	if sig == os.Kill {
+		p.killed = true
+	}
+	if p.current == nil || p.current.Process == nil {
+		return nil
+	}
+	return p.current.Process.Signal(sig)
+}
+
+// Skipped tells if some of the commands were not executed because the process
+// was stopped.
+func (p *stackProcess) Skipped() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.stopped && p.executed < len(p.cmds)
+}
@@ -0,0 +1,212 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/terramate-io/terramate/errors"
+	prj "github.com/terramate-io/terramate/project"
+)
+
+// runStackCanceled is the status reported for the stacks which started but
+// were killed before finishing.
+const runStackCanceled runStackStatus = "canceled"
+
+// runReport is the machine-readable report of a run, written by the
+// --report-json flag.
+type runReport struct {
+	Git        *runReportGit    `json:"git,omitempty"`
+	StartedAt  time.Time        `json:"started_at"`
+	FinishedAt *time.Time       `json:"finished_at,omitempty"`
+	Stacks     []runReportStack `json:"stacks"`
+
+	path string
+}
+
+type runReportGit struct {
+	BaseRef    string `json:"base_ref,omitempty"`
+	HeadCommit string `json:"head_commit,omitempty"`
+}
+
+type runReportStack struct {
+	Path       string         `json:"path"`
+	Command    []string       `json:"command"`
+	Status     runStackStatus `json:"status"`
+	Reason     string         `json:"reason,omitempty"`
+	ExitCode   *int           `json:"exit_code,omitempty"`
+	StartedAt  *time.Time     `json:"started_at,omitempty"`
+	FinishedAt *time.Time     `json:"finished_at,omitempty"`
+
+	// Duration of the execution in seconds.
+	Duration *float64 `json:"duration,omitempty"`
+
+	// Attempts has the result of each execution attempt of the command.
+	Attempts []runReportAttempt `json:"attempts,omitempty"`
+}
+
+type runReportAttempt struct {
+	Attempt    int            `json:"attempt"`
+	Status     runStackStatus `json:"status"`
+	Reason     string         `json:"reason,omitempty"`
+	ExitCode   int            `json:"exit_code"`
+	StartedAt  *time.Time     `json:"started_at,omitempty"`
+	FinishedAt *time.Time     `json:"finished_at,omitempty"`
+	Duration   *float64       `json:"duration,omitempty"`
+}
+
+// startRunReport initializes the run report, if requested, with all the
+// runStacks in the order of execution.
+func (c *cli) startRunReport(runStacks []ExecContext) {
+	reportFile := c.parsedArgs.Run.ReportJSON
+	if reportFile == "" {
+		return
+	}
+
+	if !filepath.IsAbs(reportFile) {
+		reportFile = filepath.Join(c.wd(), reportFile)
+	}
+
+	report := &runReport{
+		StartedAt: time.Now().UTC(),
+		Stacks:    []runReportStack{},
+		path:      reportFile,
+	}
+
+	if c.prj.isRepo {
+		report.Git = &runReportGit{
+			BaseRef: c.prj.baseRef,
+		}
+		head, err := c.prj.git.wrapper.RevParse("HEAD")
+		if err == nil {
+			report.Git.HeadCommit = head
+		}
+	}
+
+	for _, run := range runStacks {
+		report.Stacks = append(report.Stacks, runReportStack{
+			Path:    run.Stack.Dir.String(),
+			Command: run.Command(),
+			Status:  runStackPending,
+		})
+	}
+
+	c.runReport = report
+}
+
+// runReportAfter records the result of the stack execution in the run report.
+func (c *cli) runReportAfter(runContext ExecContext, res RunResult, err error) {
+	if c.runReport == nil {
+		return
+	}
+
+	var stack *runReportStack
+	for i := range c.runReport.Stacks {
+		if c.runReport.Stacks[i].Path == runContext.Stack.Dir.String() {
+			stack = &c.runReport.Stacks[i]
+			break
+		}
+	}
+	if stack == nil {
+		return
+	}
+
+	stack.Command = runContext.Command()
+	stack.StartedAt = res.StartedAt
+	stack.FinishedAt = res.FinishedAt
+	stack.ExitCode = nil
+	stack.Duration = nil
+
+	if res.StartedAt != nil {
+		exitCode := res.ExitCode
+		stack.ExitCode = &exitCode
+	}
+
+	if res.StartedAt != nil && res.FinishedAt != nil {
+		duration := res.FinishedAt.Sub(*res.StartedAt).Seconds()
+		stack.Duration = &duration
+	}
+
+	stack.Reason = ""
+	switch {
+	case err == nil:
+		stack.Status = runStackOK
+	case errors.IsKind(err, ErrRunCanceled):
+		stack.Status = runStackSkipped
+		if res.StartedAt != nil {
+			stack.Status = runStackCanceled
+		}
+		stack.Reason = err.Error()
+	default:
+		stack.Status = runStackFailed
+		stack.Reason = err.Error()
+	}
+
+	if res.Attempt > 0 {
+		stack.Attempts = append(stack.Attempts, runReportAttempt{
+			Attempt:    res.Attempt,
+			Status:     stack.Status,
+			Reason:     stack.Reason,
+			ExitCode:   res.ExitCode,
+			StartedAt:  stack.StartedAt,
+			FinishedAt: stack.FinishedAt,
+			Duration:   stack.Duration,
+		})
+	}
+}
+
+// finishRunReport writes the run report file, if requested.
+func (c *cli) finishRunReport() {
+	if c.runReport == nil {
+		return
+	}
+
+	finishedAt := time.Now().UTC()
+	c.runReport.FinishedAt = &finishedAt
+
+	data, err := json.MarshalIndent(c.runReport, "", "  ")
+	if err != nil {
+		fatal(err, "encoding the run report")
+	}
+
+	if err := os.WriteFile(c.runReport.path, data, 0644); err != nil {
+		fatal(errors.E(err, "writing the run report to %s", c.runReport.path))
+	}
+
+	log.Debug().
+		Str("report_file", c.runReport.path).
+		Msg("run report written")
+}
+
+// loadRunReportDurations loads the duration of each stack execution from the
+// given run report file, written by the --report-json flag of a previous run.
+// The stacks which didn't execute have no duration.
+func (c *cli) loadRunReportDurations(reportFile string) (map[prj.Path]time.Duration, error) {
+	if !filepath.IsAbs(reportFile) {
+		reportFile = filepath.Join(c.wd(), reportFile)
+	}
+
+	data, err := os.ReadFile(reportFile)
+	if err != nil {
+		return nil, errors.E(err, "reading run report file")
+	}
+
+	var report runReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, errors.E(err, "parsing run report file %s", reportFile)
+	}
+
+	durations := map[prj.Path]time.Duration{}
+	for _, stack := range report.Stacks {
+		if stack.Duration == nil {
+			continue
+		}
+		durations[prj.NewPath(stack.Path)] = time.Duration(*stack.Duration * float64(time.Second))
+	}
+	return durations, nil
+}
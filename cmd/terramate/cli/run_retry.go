@@ -0,0 +1,120 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package cli
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"time"
+
+	"github.com/terramate-io/terramate/errors"
+)
+
+const (
+	// maxRetryDelay is the maximum time to wait before retrying a failed
+	// stack, whatever the number of the failed attempt.
+	maxRetryDelay = time.Hour
+
+	// maxRetryShift is the maximum number of times the backoff is doubled.
+	maxRetryShift = 30
+)
+
+// runRetry is the policy for retrying the failed stack commands.
+type runRetry struct {
+	maxAttempts int
+	backoff     time.Duration
+	patterns    []*regexp.Regexp
+}
+
+// loadRunRetry loads the retry policy from the terramate.config.run.retry
+// block of the root configuration. The values of the --retry-* flags, given
+// as arguments, take precedence over the configuration when they are set.
+func (c *cli) loadRunRetry(maxAttempts int, backoff time.Duration, retryOn []string) (runRetry, error) {
+	retry := runRetry{
+		maxAttempts: 1,
+	}
+
+	var patterns []string
+
+	rootcfg := c.cfg().Tree().Node
+	if rootcfg.Terramate != nil &&
+		rootcfg.Terramate.Config != nil &&
+		rootcfg.Terramate.Config.Run != nil &&
+		rootcfg.Terramate.Config.Run.Retry != nil {
+		cfg := rootcfg.Terramate.Config.Run.Retry
+		retry.maxAttempts = cfg.MaxAttempts
+		retry.backoff = cfg.Backoff
+		patterns = cfg.Patterns
+	}
+
+	if maxAttempts < 0 {
+		return runRetry{}, errors.E("--retry-max-attempts must not be negative")
+	}
+	if maxAttempts > 0 {
+		retry.maxAttempts = maxAttempts
+	}
+	if backoff < 0 {
+		return runRetry{}, errors.E("--retry-backoff must not be negative")
+	}
+	if backoff > 0 {
+		retry.backoff = backoff
+	}
+	if len(retryOn) > 0 {
+		patterns = retryOn
+	}
+
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return runRetry{}, errors.E(err, "invalid retry pattern %q", pattern)
+		}
+		retry.patterns = append(retry.patterns, re)
+	}
+	return retry, nil
+}
+
+// captureStderr makes the stderr of the commands of the process also available
+// in the returned buffer, when it's needed to decide if the stack must be
+// retried.
+func (r runRetry) captureStderr(proc *stackProcess) *bytes.Buffer {
+	buf := &bytes.Buffer{}
+	if r.maxAttempts > 1 && len(r.patterns) > 0 {
+		for _, cmd := range proc.cmds {
+			cmd.Stderr = io.MultiWriter(cmd.Stderr, buf)
+		}
+	}
+	return buf
+}
+
+// shouldRetry tells if a failed command must be retried given the number of
+// the failed attempt, starting at 1, and the stderr of the command.
+// If there are no patterns, all failures are retried.
+func (r runRetry) shouldRetry(attempt int, stderr []byte) bool {
+	if attempt >= r.maxAttempts {
+		return false
+	}
+	if len(r.patterns) == 0 {
+		return true
+	}
+	for _, re := range r.patterns {
+		if re.Match(stderr) {
+			return true
+		}
+	}
+	return false
+}
+
+// delay returns the time to wait before retrying the given failed attempt.
+// The backoff is doubled on each attempt, up to maxRetryDelay.
+func (r runRetry) delay(attempt int) time.Duration {
+	shift := attempt - 1
+	if shift > maxRetryShift {
+		shift = maxRetryShift
+	}
+	if r.backoff > maxRetryDelay>>shift {
+		return maxRetryDelay
+	}
+	return r.backoff << shift
+}
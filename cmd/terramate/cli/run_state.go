@@ -0,0 +1,250 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/rs/zerolog/log"
+	"github.com/terramate-io/terramate/config"
+	"github.com/terramate-io/terramate/errors"
+	prj "github.com/terramate-io/terramate/project"
+)
+
+const (
+	// ErrRunStateResume represents the error when the last run cannot be resumed.
+	ErrRunStateResume errors.Kind = "cannot resume run"
+)
+
+// runStateFilename is the path of the run state file, relative to the
+// git directory of the project. The git directory is used because it's
+// never tracked, so the state file doesn't trigger the git safeguards.
+const runStateFilename = "terramate/run-state.json"
+
+type runStackStatus string
+
+const (
+	runStackPending runStackStatus = "pending"
+	runStackOK      runStackStatus = "ok"
+	runStackFailed  runStackStatus = "failed"
+	runStackSkipped runStackStatus = "skipped"
+)
+
+// runState is the persisted state of a run. It's updated after every stack
+// execution so a failed or interrupted run can be resumed later with --resume.
+type runState struct {
+	HeadCommit string          `json:"head_commit"`
+	Command    []string        `json:"command"`
+	Stacks     []runStackState `json:"stacks"`
+
+	path string
+}
+
+type runStackState struct {
+	Path   string         `json:"path"`
+	Status runStackStatus `json:"status"`
+}
+
+// resumeRunState loads the state of the last run and checks that it can be
+// resumed with the given ordered stacks. It returns the loaded state and the
+// ordered stacks that didn't succeed in the last run. The stacks that
+// succeeded are skipped, even if they come after a failed stack, like when
+// running in parallel or with --continue-on-error.
+func (c *cli) resumeRunState(
+	orderedStacks config.List[*config.SortableStack],
+) (*runState, config.List[*config.SortableStack]) {
+	logger := log.With().
+		Str("action", "cli.resumeRunState()").
+		Logger()
+
+	if !c.prj.isRepo {
+		fatal(errors.E(ErrRunStateResume, "--resume requires a git repository"))
+	}
+
+	statefile, err := c.runStateFile()
+	if err != nil {
+		fatal(err, "locating the run state file")
+	}
+
+	state, err := loadRunState(statefile)
+	if err != nil {
+		fatal(err, "loading the state of the last run")
+	}
+
+	logger.Debug().
+		Str("state_file", statefile).
+		Msg("loaded state of the last run")
+
+	head := c.prj.headCommit()
+	if state.HeadCommit != head {
+		fatal(errors.E(ErrRunStateResume,
+			"git HEAD changed since the last run (was %s, now is %s)",
+			state.HeadCommit, head))
+	}
+
+	if !equalStrings(state.Command, c.parsedArgs.Run.Command) {
+		fatal(errors.E(ErrRunStateResume,
+			"command changed since the last run (was %q)", state.Command))
+	}
+
+	changed := len(state.Stacks) != len(orderedStacks)
+	for i := 0; !changed && i < len(orderedStacks); i++ {
+		changed = state.Stacks[i].Path != orderedStacks[i].Dir().String()
+	}
+	if changed {
+		fatal(errors.E(ErrRunStateResume,
+			"the set of stacks to run changed since the last run"))
+	}
+
+	var pending config.List[*config.SortableStack]
+	for i, st := range state.Stacks {
+		if st.Status != runStackOK {
+			pending = append(pending, orderedStacks[i])
+		}
+	}
+	return state, pending
+}
+
+// startRunState persists the initial state of the run. If state is nil, a new
+// state is created, otherwise the status of all runStacks is reset in the
+// given state.
+// The state is only persisted in git repositories.
+func (c *cli) startRunState(state *runState, runStacks []ExecContext) {
+	logger := log.With().
+		Str("action", "cli.startRunState()").
+		Logger()
+
+	if !c.prj.isRepo {
+		logger.Debug().Msg("not a git repository, run state will not be persisted")
+		return
+	}
+
+	if state == nil {
+		head, err := c.prj.git.wrapper.RevParse("HEAD")
+		if err != nil {
+			logger.Debug().Err(err).Msg("unable to get HEAD commit, run state will not be persisted")
+			return
+		}
+
+		statefile, err := c.runStateFile()
+		if err != nil {
+			fatal(err, "locating the run state file")
+		}
+
+		state = &runState{
+			HeadCommit: head,
+			Command:    c.parsedArgs.Run.Command,
+			path:       statefile,
+		}
+		for _, run := range runStacks {
+			state.Stacks = append(state.Stacks, runStackState{
+				Path:   run.Stack.Dir.String(),
+				Status: runStackPending,
+			})
+		}
+	} else {
+		for _, run := range runStacks {
+			state.set(run.Stack.Dir, runStackPending)
+		}
+	}
+
+	if err := state.save(); err != nil {
+		fatal(err, "saving the run state")
+	}
+
+	c.runState = state
+}
+
+// runStateAfter records the result of the stack execution in the run state.
+func (c *cli) runStateAfter(runContext ExecContext, err error) {
+	if c.runState == nil {
+		return
+	}
+
+	status := runStackOK
+	if err != nil {
+		status = runStackFailed
+		if errors.IsKind(err, ErrRunCanceled) {
+			status = runStackSkipped
+		}
+	}
+
+	c.runState.set(runContext.Stack.Dir, status)
+	if err := c.runState.save(); err != nil {
+		log.Warn().
+			Err(err).
+			Stringer("stack", runContext.Stack).
+			Msg("failed to save the run state")
+	}
+}
+
+func (c *cli) runStateFile() (string, error) {
+	gitdir, err := c.prj.git.wrapper.GitDir()
+	if err != nil {
+		return "", errors.E(err, "getting the git directory")
+	}
+	return filepath.Join(gitdir, filepath.FromSlash(runStateFilename)), nil
+}
+
+func loadRunState(path string) (*runState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.E(ErrRunStateResume, "no previous run found")
+		}
+		return nil, errors.E(err, "reading run state file")
+	}
+
+	state := &runState{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, errors.E(err, "parsing run state file %s", path)
+	}
+	state.path = path
+	return state, nil
+}
+
+func (s *runState) set(stackdir prj.Path, status runStackStatus) {
+	for i := range s.Stacks {
+		if s.Stacks[i].Path == stackdir.String() {
+			s.Stacks[i].Status = status
+			return
+		}
+	}
+}
+
+// save writes the state file atomically, so an interruption never leaves a
+// corrupted state behind.
+func (s *runState) save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return errors.E(err, "encoding run state")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return errors.E(err, "creating run state directory")
+	}
+
+	tmpfile := s.path + ".tmp"
+	if err := os.WriteFile(tmpfile, data, 0644); err != nil {
+		return errors.E(err, "writing run state file")
+	}
+	if err := os.Rename(tmpfile, s.path); err != nil {
+		return errors.E(err, "writing run state file")
+	}
+	return nil
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
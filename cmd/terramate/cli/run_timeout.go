@@ -0,0 +1,150 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package cli
+
+import (
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/terramate-io/terramate/config"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/hcl"
+)
+
+// runTimeout is the policy for stopping the stack commands which run for
+// too long.
+type runTimeout struct {
+	duration       time.Duration
+	interruptGrace time.Duration
+	terminateGrace time.Duration
+
+	// flagDuration tells if the duration was set by the --timeout flag, then
+	// taking precedence over the stack timeout.
+	flagDuration bool
+}
+
+// loadRunTimeout loads the timeout policy from the terramate.config.run.timeout
+// block of the root configuration. The values of the --timeout* flags, given
+// as arguments, take precedence over the configuration when they are set.
+func (c *cli) loadRunTimeout(duration, interruptGrace, terminateGrace time.Duration) (runTimeout, error) {
+	timeout := runTimeout{
+		interruptGrace: hcl.DefaultTimeoutInterruptGrace,
+		terminateGrace: hcl.DefaultTimeoutTerminateGrace,
+	}
+
+	rootcfg := c.cfg().Tree().Node
+	if rootcfg.Terramate != nil &&
+		rootcfg.Terramate.Config != nil &&
+		rootcfg.Terramate.Config.Run != nil &&
+		rootcfg.Terramate.Config.Run.Timeout != nil {
+		cfg := rootcfg.Terramate.Config.Run.Timeout
+		timeout.duration = cfg.Duration
+		timeout.interruptGrace = cfg.InterruptGrace
+		timeout.terminateGrace = cfg.TerminateGrace
+	}
+
+	if duration < 0 {
+		return runTimeout{}, errors.E("--timeout must not be negative")
+	}
+	if duration > 0 {
+		timeout.duration = duration
+		timeout.flagDuration = true
+	}
+	if interruptGrace < 0 {
+		return runTimeout{}, errors.E("--timeout-interrupt-grace must not be negative")
+	}
+	if interruptGrace > 0 {
+		timeout.interruptGrace = interruptGrace
+	}
+	if terminateGrace < 0 {
+		return runTimeout{}, errors.E("--timeout-terminate-grace must not be negative")
+	}
+	if terminateGrace > 0 {
+		timeout.terminateGrace = terminateGrace
+	}
+	return timeout, nil
+}
+
+// forStack returns the timeout of the execution of the given stack.
+// Zero means no timeout.
+func (t runTimeout) forStack(st *config.Stack) time.Duration {
+	if st.Timeout > 0 && !t.flagDuration {
+		return st.Timeout
+	}
+	return t.duration
+}
+
+// start starts the timer of the execution just started in the given stack.
+// Every time the running command must receive a signal, the returned cmdTimeout is
+// sent to the expired channel and the receiver must call its escalate method.
+func (t runTimeout) start(st *config.Stack, logger *zerolog.Logger, expired chan<- *cmdTimeout) *cmdTimeout {
+	ct := &cmdTimeout{
+		policy:   t,
+		duration: t.forStack(st),
+		logger:   logger,
+	}
+	if ct.duration > 0 {
+		ct.timer = time.AfterFunc(ct.duration, func() {
+			expired <- ct
+		})
+	}
+	return ct
+}
+
+// cmdTimeout controls the timeout of a running command. When the timeout
+// expires, the command receives a SIGINT, then a SIGTERM and finally a SIGKILL,
+// waiting the grace periods of the policy between the signals.
+type cmdTimeout struct {
+	policy   runTimeout
+	duration time.Duration
+	logger   *zerolog.Logger
+
+	timer *time.Timer
+	step  int
+}
+
+// escalate sends the next signal to the process and schedules the following
+// one after the corresponding grace period.
+func (t *cmdTimeout) escalate(process interface{ Signal(os.Signal) error }) {
+	sig, grace := t.next()
+
+	t.logger.Warn().
+		Dur("timeout", t.duration).
+		Str("signal", sig.String()).
+		Msg("command timed out, stopping it")
+
+	if err := process.Signal(sig); err != nil {
+		t.logger.Debug().Err(err).Msg("unable to send signal to child process")
+	}
+
+	t.step++
+	if t.step <= 2 {
+		t.timer.Reset(grace)
+	}
+}
+
+func (t *cmdTimeout) next() (os.Signal, time.Duration) {
+	switch t.step {
+	case 0:
+		return os.Interrupt, t.policy.interruptGrace
+	case 1:
+		return syscall.SIGTERM, t.policy.terminateGrace
+	default:
+		return os.Kill, 0
+	}
+}
+
+// expired tells if the timeout of the command expired.
+func (t *cmdTimeout) expired() bool {
+	return t.step > 0
+}
+
+// stop stops the timer. It must be called after the command finishes.
+func (t *cmdTimeout) stop() {
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package cli
+
+import (
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/terramate-io/terramate/config"
+	"github.com/terramate-io/terramate/errors"
+)
+
+// runScript runs the jobs of the script, given by name, in all the selected
+// stacks which have the script defined. The stacks are executed in the order
+// of execution and the commands of each stack are executed in the order they
+// are defined in the jobs, as a single stack execution: the stack hooks run
+// once around all the commands and the first failing command fails the stack.
+func (c *cli) runScript() {
+	name := c.parsedArgs.Script.Run.Name
+
+	logger := log.With().
+		Str("action", "cli.runScript()").
+		Str("workingDir", c.wd()).
+		Str("script", name).
+		Logger()
+
+	c.gitSafeguardDefaultBranchIsReachable()
+	c.checkOutdatedGeneratedCode()
+
+	c.cloudSync = cloudSyncOptions{
+		deployment:        c.parsedArgs.Script.Run.CloudSyncDeployment,
+		driftStatus:       c.parsedArgs.Script.Run.CloudSyncDriftStatus,
+		terraformPlanFile: c.parsedArgs.Script.Run.CloudSyncTerraformPlanFile,
+	}
+	c.checkCloudSync()
+
+	orderedStacks, deps := c.computeRunStacks(
+		c.parsedArgs.Script.Run.NoRecursive,
+		c.parsedArgs.Script.Run.Reverse,
+	)
+
+	if c.parsedArgs.Script.Run.Parallel < 1 {
+		fatal(errors.E("--parallel expects a number greater than zero"))
+	}
+
+	type stackScript struct {
+		stack  *config.SortableStack
+		script config.Script
+	}
+
+	var stackScripts []stackScript
+	for _, st := range orderedStacks {
+		scriptCfg, found := c.cfg().LookupScript(st.Dir(), name)
+		if !found {
+			logger.Debug().
+				Stringer("stack", st.Dir()).
+				Msg("script not defined for stack, skipping")
+			continue
+		}
+
+		evalctx := c.setupEvalContext(st.Stack, map[string]string{})
+		script, err := config.EvalScript(evalctx, scriptCfg)
+		if err != nil {
+			fatal(err, "evaluating script %q in stack %s", name, st.Dir())
+		}
+
+		stackScripts = append(stackScripts, stackScript{
+			stack:  st,
+			script: script,
+		})
+	}
+
+	if len(stackScripts) == 0 {
+		fatal(errors.E("script %q not found in any of the selected stacks", name))
+	}
+
+	if c.parsedArgs.Script.Run.DryRun {
+		c.output.MsgStdOut("The script %q will be executed using order below:", name)
+
+		for i, s := range stackScripts {
+			stackdir, _ := c.friendlyFmtDir(s.stack.Dir().String())
+			c.output.MsgStdOut("\t%d. %s (%s)", i, s.stack.Name, stackdir)
+			for _, job := range s.script.Jobs {
+				for _, cmd := range job.Commands {
+					c.output.MsgStdOut("\t\t%s", strings.Join(cmd, " "))
+				}
+			}
+		}
+		return
+	}
+
+	retry, err := c.loadRunRetry(
+		c.parsedArgs.Script.Run.RetryMaxAttempts,
+		c.parsedArgs.Script.Run.RetryBackoff,
+		c.parsedArgs.Script.Run.RetryOn,
+	)
+	if err != nil {
+		fatal(err, "loading the retry policy")
+	}
+
+	timeout, err := c.loadRunTimeout(
+		c.parsedArgs.Script.Run.Timeout,
+		c.parsedArgs.Script.Run.TimeoutInterruptGrace,
+		c.parsedArgs.Script.Run.TimeoutTerminateGrace,
+	)
+	if err != nil {
+		fatal(err, "loading the timeout policy")
+	}
+
+	opts := runOptions{
+		parallel:        c.parsedArgs.Script.Run.Parallel,
+		continueOnError: c.parsedArgs.Script.Run.ContinueOnError,
+		retry:           retry,
+		timeout:         timeout,
+	}
+
+	var runStacks []ExecContext
+	var scriptStacks config.List[*config.SortableStack]
+	for _, s := range stackScripts {
+		run := ExecContext{
+			Stack: s.stack.Stack,
+			Deps:  deps[s.stack.Dir()],
+		}
+		for _, job := range s.script.Jobs {
+			run.Cmds = append(run.Cmds, job.Commands...)
+		}
+		runStacks = append(runStacks, run)
+		scriptStacks = append(scriptStacks, s.stack)
+	}
+
+	c.prepareCloudSync(scriptStacks)
+
+	if c.cloudSync.deployment {
+		c.createCloudDeployment(runStacks)
+	}
+
+	err = c.RunAll(runStacks, opts, c.isCloudSyncSuccessCode)
+	if err != nil {
+		fatal(err, "one or more commands failed")
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"os/exec"
 	"os/signal"
 	"path/filepath"
 	"strconv"
@@ -49,10 +50,18 @@ func main() {
 		hang()
 	case "sleep":
 		sleep(os.Args[2])
+	case "spawn-sleep":
+		spawnSleep(os.Args[2])
 	case "env":
 		env()
 	case "cat":
 		cat(os.Args[2])
+	case "fail-once":
+		failOnce(os.Args[2], os.Args[3])
+	case "touch":
+		touch(os.Args[2])
+	case "wait-file":
+		waitFile(os.Args[2], os.Args[3])
 	case "stack-abs-path":
 		stackAbsPath(os.Args[2])
 	case "tf-plan-sanitize":
@@ -85,6 +94,17 @@ func sleep(durationStr string) {
 	time.Sleep(d)
 }
 
+// spawnSleep starts a child process which sleeps for the given duration,
+// sharing the output of the test process, and then hangs until it is stopped.
+// The child process doesn't receive the signals sent to the test process.
+func spawnSleep(durationStr string) {
+	cmd := exec.Command(os.Args[0], "sleep", durationStr)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	checkerr(cmd.Start())
+	select {}
+}
+
 // exit with the provided exitCode.
 func exit(exitCodeStr string) {
 	code, err := strconv.Atoi(exitCodeStr)
@@ -106,6 +126,39 @@ func cat(fname string) {
 	fmt.Printf("%s", string(bytes))
 }
 
+// failOnce fails with the given message on stderr if the marker file does not
+// exist, creating it, and succeeds otherwise.
+// It is useful to validate the retry of failed commands.
+func failOnce(markerFile, msg string) {
+	if _, err := os.Stat(markerFile); err == nil {
+		fmt.Println("succeeded")
+		return
+	}
+	checkerr(os.WriteFile(markerFile, []byte{}, 0644))
+	fmt.Fprintln(os.Stderr, msg)
+	os.Exit(1)
+}
+
+// touch creates the file.
+func touch(fname string) {
+	checkerr(os.WriteFile(fname, []byte{}, 0644))
+}
+
+// waitFile waits for the file to be created, failing if it doesn't exist
+// after the given duration.
+func waitFile(fname string, durationStr string) {
+	d, err := time.ParseDuration(durationStr)
+	checkerr(err)
+	deadline := time.Now().Add(d)
+	for time.Now().Before(deadline) {
+		if _, err := os.Stat(fname); err == nil {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	log.Fatalf("file %s not created after %s", fname, d)
+}
+
 func stackAbsPath(base string) {
 	cwd, err := os.Getwd()
 	checkerr(err)
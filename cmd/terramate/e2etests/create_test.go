@@ -252,6 +252,77 @@ func TestCreateFailsWithIncompatibleFlags(t *testing.T) {
 	t.Run("--all-terraform and --ignore-existing", func(t *testing.T) {
 		test(t, "--all-terraform", "--ignore-existing")
 	})
+
+	t.Run("--all-terraform and --template", func(t *testing.T) {
+		test(t, "--all-terraform", "--template=/template")
+	})
+}
+
+func TestCreateStackFromTemplate(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.RootEntry().CreateFile("globals.tm.hcl", `
+		globals {
+		  region = "eu-west-1"
+		}
+	`)
+
+	templatedir := t.TempDir()
+	test.WriteFile(t, templatedir, "stack.tm.hcl", `stack {
+  id    = "${terramate.stack.id}"
+  name  = "${var.team}-${terramate.stack.path.basename}"
+  tags  = ["team-${var.team}"]
+  after = ["/network"]
+}
+`)
+	test.WriteFile(t, templatedir, "main.tf", `# region: ${global.region}
+resource "null_resource" "owner" {
+  triggers = {
+    owner = "$${var.owner}"
+  }
+}
+`)
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.run("create", "stacks/app", "--template", templatedir, "--var", "team=core"),
+		runExpected{
+			Stdout: "Created stack /stacks/app\n",
+		})
+
+	got := s.LoadStack(project.NewPath("/stacks/app"))
+	_, err := uuid.Parse(got.ID)
+	assert.NoError(t, err, "validating generated UUID")
+	assert.EqualStrings(t, "core-app", got.Name)
+	assert.EqualStrings(t, "team-core", strings.Join(got.Tags, ","))
+	assert.EqualStrings(t, "/network", strings.Join(got.After, ","))
+
+	test.AssertFileContentEquals(t, filepath.Join(s.RootDir(), "stacks/app/main.tf"), `# region: eu-west-1
+resource "null_resource" "owner" {
+  triggers = {
+    owner = "${var.owner}"
+  }
+}
+`)
+
+	assertRunResult(t, cli.run("create", "stacks/other", "--template", templatedir), runExpected{
+		Status:      1,
+		StderrRegex: string(stack.ErrTemplate),
+	})
+	for _, flag := range []string{"--name=other", "--description=other", "--tags=other", "--after=/network", "--before=/network"} {
+		assertRunResult(t, cli.run("create", "stacks/other", "--template", templatedir, "--var", "team=core", flag),
+			runExpected{
+				Status:      1,
+				StderrRegex: "can't be used with a template providing the stack.tm.hcl file",
+			})
+	}
+	_, err = os.Stat(filepath.Join(s.RootDir(), "stacks/other"))
+	assert.IsTrue(t, os.IsNotExist(err), "stack must not be created: %v", err)
+
+	assertRunResult(t, cli.run("create", "stacks/other", "--var", "team=core"), runExpected{
+		Status:      1,
+		StderrRegex: "--var must be used together with --template",
+	})
 }
 
 func TestCreateWithAllTerraformModuleAtRoot(t *testing.T) {
@@ -6,9 +6,11 @@ package e2etest
 import (
 	"fmt"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/project"
 	"github.com/terramate-io/terramate/test"
 	"github.com/terramate-io/terramate/test/sandbox"
 )
@@ -93,3 +95,27 @@ generate_hcl "test2.hcl" {
 	test.AssertGenCodeEquals(t, genHCL, `a = "literal"`)
 	test.AssertGenCodeEquals(t, genHCL2, `b = null`)
 }
+
+func TestCloneNestedStacks(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:stacks/a:id=a`,
+		`s:stacks/a/child:id=a-child;after=["../../../stacks/a"]`,
+	})
+
+	tmcli := newCLI(t, s.RootDir())
+	assertRunResult(t, tmcli.run("experimental", "clone", "stacks/a", "apps/a"), runExpected{
+		StdoutRegex: "Cloned stack stacks/a to apps/a with success\n",
+	})
+	assertRunResult(t, tmcli.listStacks(), runExpected{
+		Stdout: "apps/a\napps/a/child\nstacks/a\nstacks/a/child\n",
+	})
+
+	child := s.LoadStack(project.NewPath("/apps/a/child"))
+	if child.ID == "" || child.ID == "a-child" {
+		t.Fatalf("want cloned child stack to have a new ID, got %q", child.ID)
+	}
+	assert.EqualStrings(t, "..", strings.Join(child.After, " "))
+}
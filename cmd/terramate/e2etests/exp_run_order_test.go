@@ -330,6 +330,154 @@ func TestOrderGraphAfter(t *testing.T) {
 	}
 }
 
+func TestRunGraphFormats(t *testing.T) {
+	t.Parallel()
+
+	type testcase struct {
+		format string
+		want   string
+	}
+
+	for _, tc := range []testcase{
+		{
+			format: "mermaid",
+			want: `flowchart TD
+    n1["stack-a"]
+    n2["stack-b"]
+    n3["stack-c"]
+    n2 -->|after| n1
+    n2 -->|before| n3
+    n2 -.->|wants| n3
+`,
+		},
+		{
+			format: "json",
+			want: `{
+  "nodes": [
+    {
+      "id": "/stack-a",
+      "stack_id": "stack-a-id",
+      "name": "stack-a",
+      "path": "/stack-a",
+      "tags": [
+        "network"
+      ]
+    },
+    {
+      "id": "/stack-b",
+      "stack_id": "",
+      "name": "stack-b",
+      "path": "/stack-b",
+      "tags": []
+    },
+    {
+      "id": "/stack-c",
+      "stack_id": "",
+      "name": "stack-c",
+      "path": "/stack-c",
+      "tags": []
+    }
+  ],
+  "edges": [
+    {
+      "from": "/stack-b",
+      "to": "/stack-a",
+      "kind": "after"
+    },
+    {
+      "from": "/stack-b",
+      "to": "/stack-c",
+      "kind": "before"
+    },
+    {
+      "from": "/stack-b",
+      "to": "/stack-c",
+      "kind": "wants"
+    }
+  ]
+}
+`,
+		},
+		{
+			format: "graphml",
+			want: `<?xml version="1.0" encoding="UTF-8"?>
+<graphml xmlns="http://graphml.graphdrawing.org/xmlns">
+  <key id="label" for="node" attr.name="label" attr.type="string"></key>
+  <key id="stack_id" for="node" attr.name="stack_id" attr.type="string"></key>
+  <key id="name" for="node" attr.name="name" attr.type="string"></key>
+  <key id="tags" for="node" attr.name="tags" attr.type="string"></key>
+  <key id="kind" for="edge" attr.name="kind" attr.type="string"></key>
+  <graph id="run-graph" edgedefault="directed">
+    <node id="/stack-a">
+      <data key="label">stack-a</data>
+      <data key="stack_id">stack-a-id</data>
+      <data key="name">stack-a</data>
+      <data key="tags">network</data>
+    </node>
+    <node id="/stack-b">
+      <data key="label">stack-b</data>
+      <data key="stack_id"></data>
+      <data key="name">stack-b</data>
+      <data key="tags"></data>
+    </node>
+    <node id="/stack-c">
+      <data key="label">stack-c</data>
+      <data key="stack_id"></data>
+      <data key="name">stack-c</data>
+      <data key="tags"></data>
+    </node>
+    <edge source="/stack-b" target="/stack-a">
+      <data key="kind">after</data>
+    </edge>
+    <edge source="/stack-b" target="/stack-c">
+      <data key="kind">before</data>
+    </edge>
+    <edge source="/stack-b" target="/stack-c">
+      <data key="kind">wants</data>
+    </edge>
+  </graph>
+</graphml>
+`,
+		},
+	} {
+		tc := tc
+		t.Run(tc.format, func(t *testing.T) {
+			t.Parallel()
+
+			s := sandbox.New(t)
+			s.BuildTree([]string{
+				`s:stack-a:id=stack-a-id;tags=["network"]`,
+				`s:stack-b:after=["/stack-a"];wants=["/stack-c"]`,
+				`s:stack-c:before=["/stack-b"]`,
+			})
+			cli := newCLI(t, s.RootDir())
+			assertRunResult(t, cli.stacksRunGraph("--format", tc.format), runExpected{
+				Stdout: tc.want,
+			})
+		})
+	}
+}
+
+func TestRunGraphMermaidCycle(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:stack-a:after=["/stack-b"]`,
+		`s:stack-b:after=["/stack-a"]`,
+	})
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.stacksRunGraph("--format", "mermaid", "--label", "stack.dir"), runExpected{
+		Stdout: `flowchart TD
+    n1["/stack-a"]
+    n2["/stack-b"]
+    n1 -->|after| n2
+    n2 -->|after| n1
+    linkStyle 1 stroke:red
+`,
+	})
+}
+
 func TestExperimentalRunOrderNotChangedStackIgnored(t *testing.T) {
 	t.Parallel()
 
@@ -371,3 +519,156 @@ func TestExperimentalRunOrderNotChangedStackIgnored(t *testing.T) {
 func flatten(s string) string {
 	return strings.Replace((strings.Replace(s, "\n", "", -1)), "\t", "", -1)
 }
+
+func TestExperimentalRunOrderLevelsAndCriticalPath(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:network`,
+		`s:app:after=["/network"]`,
+		`s:db:after=["/network"]`,
+		`s:frontend:after=["/app", "/db"]`,
+		`s:other`,
+	})
+
+	s.RootEntry().CreateFile("report.json", `{
+  "started_at": "2023-10-16T10:00:00Z",
+  "stacks": [
+    {"path": "/network", "command": ["terraform", "apply"], "status": "ok", "duration": 1.5},
+    {"path": "/app", "command": ["terraform", "apply"], "status": "ok", "duration": 4},
+    {"path": "/db", "command": ["terraform", "apply"], "status": "ok", "duration": 2},
+    {"path": "/frontend", "command": ["terraform", "apply"], "status": "ok", "duration": 0.25},
+    {"path": "/other", "command": ["terraform", "apply"], "status": "ok", "duration": 3}
+  ]
+}`)
+
+	git := s.Git()
+	git.CommitAll("first commit")
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.stacksRunOrder("--levels"), runExpected{
+		Stdout: nljoin(
+			"Level 1:",
+			"  /network",
+			"  /other",
+			"Level 2:",
+			"  /app",
+			"  /db",
+			"Level 3:",
+			"  /frontend",
+		),
+	})
+
+	assertRunResult(t, cli.stacksRunOrder("--critical-path", "report.json"), runExpected{
+		Stdout: nljoin(
+			"/network\t1.5s",
+			"/app\t4s",
+			"/frontend\t250ms",
+			"Total: 5.75s",
+		),
+	})
+
+	assertRunResult(t, cli.stacksRunOrder("--levels", "--critical-path", "report.json"), runExpected{
+		StderrRegex: "mutually exclusive",
+		Status:      1,
+	})
+}
+
+func TestExperimentalRunOrderReportsAllCycles(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:stack-a:after=["/stack-b"]`,
+		`s:stack-b:after=["/stack-a"]`,
+		`s:stack-c:before=["/stack-d"]`,
+		`s:stack-d:before=["/stack-c"]`,
+	})
+
+	git := s.Git()
+	git.CommitAll("first commit")
+
+	cli := newCLI(t, s.RootDir())
+	res := cli.stacksRunOrder()
+	for _, want := range []string{
+		`stack /stack-a runs after /stack-b in the cycle /stack-a -> /stack-b -> /stack-a file=\S*stack-a[/\\]terramate.tm.hcl:\d+,\d+-\d+`,
+		`stack /stack-b runs after /stack-a in the cycle /stack-a -> /stack-b -> /stack-a file=\S*stack-b[/\\]terramate.tm.hcl:\d+,\d+-\d+`,
+		`stack /stack-c runs after /stack-d in the cycle /stack-c -> /stack-d -> /stack-c file=\S*stack-d[/\\]terramate.tm.hcl:\d+,\d+-\d+`,
+		`stack /stack-d runs after /stack-c in the cycle /stack-c -> /stack-d -> /stack-c file=\S*stack-c[/\\]terramate.tm.hcl:\d+,\d+-\d+`,
+	} {
+		assertRunResult(t, res, runExpected{
+			Status:      defaultErrExitStatus,
+			StderrRegex: want,
+		})
+	}
+}
+
+func TestRunGraphAndOrderInferredFromRemoteStates(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:app`,
+		`s:network`,
+		`s:db:after=["/network"]`,
+	})
+
+	s.DirEntry("network").CreateFile("backend.tf", `
+terraform {
+  backend "local" {}
+}
+`)
+	s.DirEntry("app").CreateFile("deps.tf", `
+data "terraform_remote_state" "network" {
+  backend = "local"
+  config = {
+    path = "../network/terraform.tfstate"
+  }
+}
+`)
+
+	git := s.Git()
+	git.CommitAll("first commit")
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.stacksRunOrder(), runExpected{
+		Stdout: nljoin("/app", "/network", "/db"),
+	})
+	assertRunResult(t, cli.stacksRunGraph("--format", "mermaid"), runExpected{
+		Stdout: `flowchart TD
+    n1["app"]
+    n2["db"]
+    n3["network"]
+    n2 -->|after| n3
+`,
+	})
+	assertRunResult(t, cli.stacksRunGraph("--format", "mermaid", "--inferred"), runExpected{
+		Stdout: `flowchart TD
+    n1["app"]
+    n2["db"]
+    n3["network"]
+    n1 -.->|inferred| n3
+    n2 -->|after| n3
+`,
+	})
+
+	s.RootEntry().CreateFile("infer.tm", `
+terramate {
+  config {
+    run {
+      infer_order = true
+    }
+  }
+}
+`)
+	git.CommitAll("enable order inference")
+
+	assertRunResult(t, cli.stacksRunOrder(), runExpected{
+		Stdout: nljoin("/network", "/app", "/db"),
+	})
+	assertRunResult(t, cli.stacksRunGraph("--label", "stack.dir"), runExpected{
+		Stdout:        `digraph  {n1[label="/app"];n3[label="/db"];n2[label="/network"];n1->n2[label="inferred",style="dashed"];n3->n2;}`,
+		FlattenStdout: true,
+	})
+}
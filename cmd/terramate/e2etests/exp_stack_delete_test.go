@@ -0,0 +1,101 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package e2etest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/project"
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+func TestStackDelete(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:stacks/a:after=["/stacks/b"]`,
+		"s:stacks/b",
+		"s:stacks/b/child",
+		"s:stacks/c",
+	})
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.triggerStack("/stacks/b"), runExpected{IgnoreStdout: true})
+
+	assertRunResult(t, cli.run("experimental", "stack", "delete", "stacks/b"), runExpected{
+		Status:      1,
+		StderrRegex: `use --recursive`,
+	})
+	assertRunResult(t, cli.run("experimental", "stack", "delete", "--recursive", "stacks/b"), runExpected{
+		Status:      1,
+		StderrRegex: `stack /stacks/a refers to the deleted stack /stacks/b in stack\.after`,
+	})
+
+	_, err := os.Stat(filepath.Join(s.RootDir(), "stacks/b"))
+	assert.NoError(t, err, "stack must not be deleted")
+
+	assertRunResult(t, cli.run("experimental", "stack", "delete", "--recursive", "--force-rewrite", "/stacks/b"), runExpected{
+		Stdout: "Removed stack references in /stacks/a/terramate.tm.hcl\n" +
+			"Removed triggers of stack /stacks/b\n" +
+			"Deleted stack /stacks/b\n" +
+			"Deleted stack /stacks/b/child\n",
+	})
+
+	a := s.LoadStack(project.NewPath("/stacks/a"))
+	assert.EqualInts(t, 0, len(a.After))
+
+	assertRunResult(t, cli.listStacks(), runExpected{Stdout: "stacks/a\nstacks/c\n"})
+	assertRunResult(t, cli.run("experimental", "trigger", "list"), runExpected{})
+
+	assertRunResult(t, cli.run("experimental", "stack", "delete", "stacks/c"), runExpected{
+		Stdout: "Deleted stack /stacks/c\n",
+	})
+	assertRunResult(t, cli.listStacks(), runExpected{Stdout: "stacks/a\n"})
+}
+
+func TestStackDeleteNonStackDirs(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		"s:stacks/app",
+		"f:stacks/app/main.tf:# app",
+		"f:stacks/app/modules/vpc/main.tf:# vpc",
+	})
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.run("experimental", "stack", "delete", "stacks/app"), runExpected{
+		Status:      1,
+		StderrRegex: `use --non-stack-dirs`,
+	})
+
+	_, err := os.Stat(filepath.Join(s.RootDir(), "stacks/app/modules/vpc/main.tf"))
+	assert.NoError(t, err, "module must not be deleted")
+
+	assertRunResult(t, cli.run("experimental", "stack", "delete", "--non-stack-dirs", "stacks/app"), runExpected{
+		Stdout: "Deleted stack /stacks/app\n",
+	})
+	assertRunResult(t, cli.listStacks(), runExpected{})
+}
+
+func TestStackDeleteFailsOnNonStack(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{"d:dir"})
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.run("experimental", "stack", "delete", "dir"), runExpected{
+		Status:      1,
+		StderrRegex: "must be a stack",
+	})
+	assertRunResult(t, cli.run("experimental", "stack", "delete", "../outside"), runExpected{
+		Status:      1,
+		StderrRegex: "outside project",
+	})
+}
@@ -0,0 +1,66 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package e2etest
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/project"
+	"github.com/terramate-io/terramate/test"
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+func TestStackMove(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:stacks/a:after=["/stacks/b"]`,
+		"s:stacks/b",
+		`f:generate.tm.hcl:generate_file "path.txt" {
+		  content = terramate.stack.path.absolute
+		}`,
+	})
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.run("generate"), runExpected{IgnoreStdout: true})
+	assertRunResult(t, cli.triggerStack("/stacks/b"), runExpected{IgnoreStdout: true})
+
+	assertRunResult(t, cli.run("experimental", "stack", "move", "stacks/b", "/apps/b"), runExpected{
+		StdoutRegex: `^Moved stack /stacks/b to /apps/b\n` +
+			`Updated stack references in /stacks/a/terramate\.tm\.hcl\n` +
+			`Moved triggers of stack /stacks/b to /apps/b\n` +
+			`Generating code on the moved stack\n`,
+	})
+
+	test.AssertFileContentEquals(t, filepath.Join(s.RootDir(), "apps/b/path.txt"), "/apps/b")
+
+	a := s.LoadStack(project.NewPath("/stacks/a"))
+	assert.EqualInts(t, 1, len(a.After))
+	assert.EqualStrings(t, "/apps/b", a.After[0])
+
+	assertRunResult(t, cli.listStacks(), runExpected{Stdout: "apps/b\nstacks/a\n"})
+	assertRunResult(t, cli.run("experimental", "trigger", "list"), runExpected{
+		StdoutRegex: `^/apps/b\t`,
+	})
+}
+
+func TestStackMoveFailsOutsideProject(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{"s:stack"})
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.run("experimental", "stack", "move", "stack", "../outside"), runExpected{
+		Status:      1,
+		StderrRegex: "outside project",
+	})
+	assertRunResult(t, cli.run("experimental", "stack", "move", "stack", "stack/inner"), runExpected{
+		Status:      1,
+		StderrRegex: "must not be inside",
+	})
+}
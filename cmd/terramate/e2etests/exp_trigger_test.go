@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"testing"
+	"time"
 
 	"github.com/madlambda/spells/assert"
 	"github.com/terramate-io/terramate/stack/trigger"
@@ -36,6 +37,31 @@ func TestTriggerWorksWithRelativeStackPath(t *testing.T) {
 	assertRunResult(t, cli.listChangedStacks(), want)
 }
 
+func TestTriggerStacksNamedAsSubcommands(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.CreateStack("list")
+	s.CreateStack("clean")
+	git := s.Git()
+	git.CommitAll("all")
+	git.Push("main")
+	git.CheckoutNew("trigger-the-stacks")
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.run("experimental", "trigger", "create", "list"), runExpected{
+		IgnoreStdout: true,
+	})
+	assertRunResult(t, cli.triggerStack("./clean"), runExpected{
+		IgnoreStdout: true,
+	})
+
+	git.CommitAll("commit the trigger files")
+	assertRunResult(t, cli.listChangedStacks(), runExpected{
+		Stdout: nljoin("clean", "list"),
+	})
+}
+
 func TestTriggerFailsWithSymlinksInStackPath(t *testing.T) {
 	if runtime.GOOS == "windows" {
 		t.Skip("symlink tests skipped on windows")
@@ -261,3 +287,93 @@ func TestRunWontDetectAsChangeDeletedTrigger(t *testing.T) {
 		testfile,
 	), runExpected{Stdout: ""})
 }
+
+func TestTriggerListAndClean(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.CreateStack("stack-a")
+	s.CreateStack("stack-b")
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.run("experimental", "trigger", "list"), runExpected{})
+
+	assertRunResult(t, cli.run("experimental", "trigger", "/stack-a", "--reason", "reason a"),
+		runExpected{IgnoreStdout: true})
+	assertRunResult(t, cli.run("experimental", "trigger", "/stack-b", "--reason", "reason b"),
+		runExpected{IgnoreStdout: true})
+	s.RootEntry().CreateFile(".tmtriggers/stack-b/changed-old.tm.hcl", `
+		trigger {
+		  ctime  = 1000
+		  reason = "old reason"
+		}
+	`)
+
+	assertRunResult(t, cli.run("experimental", "trigger", "list"), runExpected{
+		StdoutRegex: `^/stack-a\t\d+s\treason a\n` +
+			`/stack-b\t\d+h\d+m\d+s\told reason\n` +
+			`/stack-b\t\d+s\treason b\n$`,
+	})
+
+	// no filters and no expired triggers, nothing to clean.
+	assertRunResult(t, cli.run("experimental", "trigger", "clean"), runExpected{})
+
+	assertRunResult(t, cli.run("experimental", "trigger", "clean", "--older-than", "24h"), runExpected{
+		Stdout: "Removed trigger /.tmtriggers/stack-b/changed-old.tm.hcl of stack /stack-b\n",
+	})
+
+	assertRunResult(t, cli.run("experimental", "trigger", "clean", "--stack", "stack-a"), runExpected{
+		StdoutRegex: `^Removed trigger /\.tmtriggers/stack-a/changed-.*\.tm\.hcl of stack /stack-a\n$`,
+	})
+
+	assertRunResult(t, cli.run("experimental", "trigger", "list"), runExpected{
+		StdoutRegex: `^/stack-b\t\d+s\treason b\n$`,
+	})
+}
+
+func TestListChangedIgnoresExpiredTrigger(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.CreateStack("expired")
+	s.CreateStack("not-expired")
+	s.CreateStack("expires-in")
+
+	git := s.Git()
+	git.CommitAll("all")
+	git.Push("main")
+	git.CheckoutNew("trigger-the-stacks")
+
+	s.RootEntry().CreateFile(".tmtriggers/expired/changed-expired.tm.hcl", `
+		trigger {
+		  ctime      = 1000
+		  reason     = "expired"
+		  expires_at = 2000
+		}
+	`)
+	s.RootEntry().CreateFile(".tmtriggers/not-expired/changed-not-expired.tm.hcl", fmt.Sprintf(`
+		trigger {
+		  ctime      = 1000
+		  reason     = "not expired"
+		  expires_at = %d
+		}
+	`, time.Now().Add(24*time.Hour).Unix()))
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.run("experimental", "trigger", "/expires-in", "--expires-in", "1h"),
+		runExpected{IgnoreStdout: true})
+
+	git.CommitAll("commit the trigger files")
+
+	assertRunResult(t, cli.listChangedStacks(), runExpected{
+		Stdout: "expires-in\nnot-expired\n",
+	})
+
+	assertRunResult(t, cli.run("experimental", "trigger", "list"), runExpected{
+		StdoutRegex: `^/expired\t\d+h\d+m\d+s \(expired\)\texpired\n`,
+	})
+
+	assertRunResult(t, cli.run("experimental", "trigger", "clean"), runExpected{
+		Stdout: "Removed trigger /.tmtriggers/expired/changed-expired.tm.hcl of stack /expired\n",
+	})
+}
@@ -0,0 +1,144 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package e2etest
+
+import (
+	"testing"
+
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+func TestListFormats(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:stacks/a:id=a;tags=["app","prod"];after=["/stacks/b"]`,
+		`s:stacks/b:id=b;description=stack b`,
+	})
+
+	cli := newCLI(t, s.RootDir())
+
+	assertRunResult(t, cli.listStacks("--format", "json"), runExpected{
+		Stdout: `[
+  {
+    "id": "a",
+    "name": "a",
+    "description": "",
+    "tags": [
+      "app",
+      "prod"
+    ],
+    "path": "/stacks/a",
+    "after": [
+      "/stacks/b"
+    ],
+    "before": [],
+    "wants": [],
+    "watch": [],
+    "reason": ""
+  },
+  {
+    "id": "b",
+    "name": "b",
+    "description": "stack b",
+    "tags": [],
+    "path": "/stacks/b",
+    "after": [],
+    "before": [],
+    "wants": [],
+    "watch": [],
+    "reason": ""
+  }
+]
+`,
+	})
+
+	assertRunResult(t, cli.listStacks("--format", "json", "--fields", "path,tags"), runExpected{
+		Stdout: `[
+  {
+    "path": "/stacks/a",
+    "tags": [
+      "app",
+      "prod"
+    ]
+  },
+  {
+    "path": "/stacks/b",
+    "tags": []
+  }
+]
+`,
+	})
+
+	assertRunResult(t, cli.listStacks("--format", "yaml", "--fields", "id,path,after"), runExpected{
+		Stdout: `- id: a
+  path: /stacks/a
+  after:
+    - /stacks/b
+- id: b
+  path: /stacks/b
+  after: []
+`,
+	})
+
+	assertRunResult(t, cli.listStacks("--format", "csv", "--fields", "id,description,tags"), runExpected{
+		Stdout: "id,description,tags\n" +
+			"a,,\"app,prod\"\n" +
+			"b,stack b,\n",
+	})
+
+	assertRunResult(t, cli.listStacks("--template", `{{.ID}} {{.Path}} {{join .Tags ":"}}`), runExpected{
+		Stdout: "a /stacks/a app:prod\n" +
+			"b /stacks/b \n",
+	})
+}
+
+func TestListFormatsWithChanged(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		"s:stack",
+		"s:unchanged",
+	})
+	git := s.Git()
+	git.CommitAll("first commit")
+	git.Push("main")
+	git.CheckoutNew("change")
+	s.RootEntry().CreateFile("stack/main.tf", "# changed")
+	git.CommitAll("stack changed")
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.listChangedStacks("--format", "csv", "--fields", "path,reason"), runExpected{
+		Stdout: "path,reason\n" +
+			"/stack,stack has unmerged changes\n",
+	})
+	assertRunResult(t, cli.listChangedStacks("--template", "{{.Path}}: {{.Reason}}"), runExpected{
+		Stdout: "/stack: stack has unmerged changes\n",
+	})
+}
+
+func TestListFormatsFailures(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{"s:stack"})
+
+	cli := newCLI(t, s.RootDir())
+	for _, args := range [][]string{
+		{"--format", "xml"},
+		{"--fields", "path"},
+		{"--format", "json", "--fields", "unknown"},
+		{"--format", "json", "--template", "{{.Path}}"},
+		{"--template", "{{.Path"},
+		{"--template", "{{.Unknown}}"},
+		{"--changed", "--why", "--format", "json"},
+	} {
+		assertRunResult(t, cli.listStacks(args...), runExpected{
+			Status:       1,
+			IgnoreStderr: true,
+		})
+	}
+}
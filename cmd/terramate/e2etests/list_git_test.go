@@ -6,6 +6,8 @@ package e2etest
 import (
 	"fmt"
 	"path/filepath"
+	"regexp"
+	"runtime"
 	"strings"
 	"testing"
 
@@ -336,3 +338,208 @@ variable "with_optional_attribute2" {
 	wantList := stack.RelPath() + "\n"
 	assertRunResult(t, cli.listChangedStacks(), runExpected{Stdout: wantList})
 }
+
+func TestListChangedDetectsChangesThroughSymlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlink tests skipped on windows")
+	}
+	t.Parallel()
+
+	s := sandbox.New(t)
+
+	shared := s.CreateModule("modules/shared")
+	sharedMainTf := shared.CreateFile("main.tf", "# shared module")
+	test.Symlink(t, "shared", filepath.Join(s.RootDir(), "modules", "link"))
+
+	providers := s.RootEntry().CreateFile("providers/providers.tf", "# providers")
+
+	outside := t.TempDir()
+	test.Symlink(t, outside, filepath.Join(s.RootDir(), "outside"))
+
+	modStack := s.CreateStack("mod-stack")
+	modStack.CreateFile("main.tf", `
+module "shared" {
+  source = "../modules/link"
+}`)
+
+	fileStack := s.CreateStack("file-stack")
+	test.Symlink(t, filepath.Join("..", "providers", "providers.tf"),
+		filepath.Join(fileStack.Path(), "providers.tf"))
+
+	outsideStack := s.CreateStack("outside-stack")
+	outsideStack.CreateFile("main.tf", `
+module "outside" {
+  source = "../outside"
+}`)
+
+	git := s.Git()
+	git.CommitAll("first commit")
+	git.Push("main")
+	git.CheckoutNew("change-symlink-targets")
+
+	sharedMainTf.Write("# shared module changed")
+	providers.Write("# providers changed")
+	git.CommitAll("symlink targets changed")
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.listChangedStacks("--why"), runExpected{
+		Stdout: `file-stack - stack changed because symlink "/file-stack/providers.tf" target "/providers/providers.tf" changed
+mod-stack - stack changed because "../modules/link" changed because module "../modules/link" (symlink to "/modules/shared") has unmerged changes
+`,
+		IgnoreStderr: true,
+	})
+}
+
+func TestListChangedReportsRemoteModuleBumps(t *testing.T) {
+	t.Parallel()
+
+	const (
+		gitModule = `
+module "network" {
+  source = "git::https://example.com/network.git?ref=%s"
+}`
+		registryModule = `
+module "vpc" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "%s"
+}`
+	)
+
+	s := sandbox.New(t)
+
+	refStack := s.CreateStack("ref-stack")
+	refStackMainTf := refStack.CreateFile("main.tf", gitModule, "v1.2.0")
+
+	versionStack := s.CreateStack("version-stack")
+	versionStackMainTf := versionStack.CreateFile("main.tf", registryModule, "5.1.0")
+
+	mod := s.CreateModule("modules/wrapper")
+	modMainTf := mod.CreateFile("main.tf", gitModule, "v1.2.0")
+
+	modStack := s.CreateStack("mod-stack")
+	modStack.CreateFile("main.tf", `
+module "wrapper" {
+  source = "../modules/wrapper"
+}`)
+
+	otherStack := s.CreateStack("other-stack")
+	otherStackMainTf := otherStack.CreateFile("main.tf", gitModule, "v1.2.0")
+
+	git := s.Git()
+	git.CommitAll("first commit")
+	git.Push("main")
+	git.CheckoutNew("bump-modules")
+
+	refStackMainTf.Write(gitModule, "v1.3.0")
+	versionStackMainTf.Write(registryModule, "5.2.0")
+	modMainTf.Write(gitModule, "v2.0.0")
+	otherStackMainTf.Write(gitModule+"\n# comment", "v1.2.0")
+	git.CommitAll("bump modules")
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.listChangedStacks("--why"), runExpected{
+		Stdout: `mod-stack - stack changed because "../modules/wrapper" changed because module "../modules/wrapper" changed because module "network" ref v1.2.0 -> v2.0.0
+other-stack - stack has unmerged changes
+ref-stack - stack changed because module "network" ref v1.2.0 -> v1.3.0
+version-stack - stack changed because module "vpc" version 5.1.0 -> 5.2.0
+`,
+	})
+	assertRunResult(t, cli.listChangedStacks("--format", "csv", "--fields", "path,reason"), runExpected{
+		Stdout: `path,reason
+/mod-stack,"stack changed because ""../modules/wrapper"" changed because module ""../modules/wrapper"" changed because module ""network"" ref v1.2.0 -> v2.0.0"
+/other-stack,stack has unmerged changes
+/ref-stack,"stack changed because module ""network"" ref v1.2.0 -> v1.3.0"
+/version-stack,"stack changed because module ""vpc"" version 5.1.0 -> 5.2.0"
+`,
+	})
+}
+
+func TestListChangedIncludeWorktree(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+
+	committedStack := s.CreateStack("committed-stack")
+	committedFile := committedStack.CreateFile("main.tf", "# committed")
+
+	uncommittedStack := s.CreateStack("uncommitted-stack")
+	uncommittedFile := uncommittedStack.CreateFile("main.tf", "# uncommitted")
+
+	stagedStack := s.CreateStack("staged-stack")
+	stagedFile := stagedStack.CreateFile("main.tf", "# staged")
+
+	untrackedStack := s.CreateStack("untracked-stack")
+
+	mod := s.CreateModule("modules/mod")
+	modMainTf := mod.CreateFile("main.tf", "# module")
+	modStack := s.CreateStack("mod-stack")
+	modStack.CreateFile("main.tf", `
+module "mod" {
+  source = "../modules/mod"
+}`)
+
+	s.BuildTree([]string{
+		`s:watch-stack:watch=["/external/file.txt"]`,
+		"s:triggered-stack",
+		"s:unchanged-stack",
+	})
+
+	git := s.Git()
+	git.CommitAll("first commit")
+	git.Push("main")
+	git.CheckoutNew("change-stacks")
+
+	committedFile.Write("# committed changed")
+	git.CommitAll("committed change")
+
+	committedFile.Write("# committed changed again")
+	uncommittedFile.Write("# uncommitted changed")
+	stagedFile.Write("# staged changed")
+	git.Add(stagedFile.HostPath())
+	untrackedStack.CreateFile("main.tf", "# untracked")
+	modMainTf.Write("# module changed")
+	s.RootEntry().CreateFile("external/file.txt", "watched")
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.triggerStack("/triggered-stack"), runExpected{
+		IgnoreStdout: true,
+	})
+
+	assertRunResult(t, cli.listChangedStacks("--why"), runExpected{
+		Stdout: "committed-stack - stack has unmerged changes\n",
+	})
+
+	assertRunResult(t, cli.listChangedStacks("--why", "--changed-include-worktree"), runExpected{
+		StdoutRegex: "^" + regexp.QuoteMeta(`committed-stack - stack has unmerged changes
+mod-stack - stack changed because "../modules/mod" changed because module "../modules/mod" has unmerged changes (uncommitted)
+staged-stack - stack has unmerged changes (uncommitted)
+triggered-stack - stack has been triggered by: /.tmtriggers/triggered-stack/`) + `\S+` + regexp.QuoteMeta(` (uncommitted)
+uncommitted-stack - stack has unmerged changes (uncommitted)
+untracked-stack - stack has unmerged changes (uncommitted)
+watch-stack - stack changed because watched file "/external/file.txt" changed (uncommitted)
+`) + "$",
+	})
+
+	assertRunResult(t, cli.run("run", "--changed", testHelperBin, "stack-abs-path", s.RootDir()), runExpected{
+		Status:      1,
+		StderrRegex: "repository has untracked files",
+	})
+
+	assertRunResult(t, cli.run("run", "--changed", "--changed-include-worktree",
+		testHelperBin, "stack-abs-path", s.RootDir()), runExpected{
+		Stdout: nljoin(
+			"/committed-stack",
+			"/mod-stack",
+			"/staged-stack",
+			"/triggered-stack",
+			"/uncommitted-stack",
+			"/untracked-stack",
+			"/watch-stack",
+		),
+	})
+
+	assertRunResult(t, cli.run("list", "--changed-include-worktree"), runExpected{
+		Status:      1,
+		StderrRegex: "--changed-include-worktree must be used together with --changed",
+	})
+}
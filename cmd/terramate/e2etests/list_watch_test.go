@@ -195,16 +195,19 @@ func TestListWatchExprWithFuncalls(t *testing.T) {
 	assertRunResult(t, cli.listChangedStacks(), want)
 }
 
-func TestListWatchDirectoryFails(t *testing.T) {
+func TestListWatchDirectory(t *testing.T) {
 	t.Parallel()
 
 	s := sandbox.New(t)
 
 	extDir := s.RootEntry().CreateDir("external")
-	extFile := extDir.CreateFile("file.txt", "anything")
+	extFile := extDir.CreateFile("config/file.txt", "anything")
+	s.RootEntry().CreateFile("external-other/file.txt", "anything")
 
 	s.BuildTree([]string{
 		`s:stack:watch=["/external"]`,
+		`s:stack-trailing-slash:watch=["/external/config/"]`,
+		`s:stack-not-changed:watch=["/external-other/"]`,
 	})
 
 	cli := newCLI(t, s.RootDir())
@@ -217,6 +220,69 @@ func TestListWatchDirectoryFails(t *testing.T) {
 	extFile.Write("changed")
 	git.CommitAll("external file changed")
 
+	want := runExpected{
+		Stdout: `stack - stack changed because file "/external/config/file.txt" matching the watched path "/external" changed
+stack-trailing-slash - stack changed because file "/external/config/file.txt" matching the watched path "/external/config" changed
+`,
+	}
+	assertRunResult(t, cli.listChangedStacks("--why"), want)
+}
+
+func TestListWatchGlobPatterns(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+
+	policy := s.RootEntry().CreateFile("policies/network/vpc/rules.rego", "package vpc")
+	s.RootEntry().CreateFile("policies/README.md", "# policies")
+	readme := s.RootEntry().CreateFile("docs/README.md", "# docs")
+
+	s.BuildTree([]string{
+		`s:rego:watch=["/policies/**/*.rego"]`,
+		`s:relative:watch=["../policies/*/vpc"]`,
+		`s:readme:watch=["/*/README.[mM][dD]"]`,
+		`s:not-changed:watch=["/policies/*.rego", "/docs/?.md"]`,
+	})
+
+	cli := newCLI(t, s.RootDir())
+
+	git := s.Git()
+	git.CommitAll("all")
+	git.Push("main")
+	git.CheckoutNew("change-the-policies")
+
+	policy.Write("package vpc # changed")
+	readme.Write("# changed")
+	git.CommitAll("policies changed")
+
+	want := runExpected{
+		Stdout: `readme - stack changed because file "/docs/README.md" matching the watched path "/*/README.[mM][dD]" changed
+rego - stack changed because file "/policies/network/vpc/rules.rego" matching the watched path "/policies/**/*.rego" changed
+relative - stack changed because file "/policies/network/vpc/rules.rego" matching the watched path "/policies/*/vpc" changed
+`,
+	}
+	assertRunResult(t, cli.listChangedStacks("--why"), want)
+}
+
+func TestListWatchInvalidPatternFails(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+
+	s.BuildTree([]string{
+		`s:stack:watch=["/policies/[*.rego"]`,
+	})
+
+	cli := newCLI(t, s.RootDir())
+
+	git := s.Git()
+	git.CommitAll("all")
+	git.Push("main")
+	git.CheckoutNew("change")
+
+	s.RootEntry().CreateFile("test.txt", "anything")
+	git.CommitAll("any change")
+
 	want := runExpected{
 		Status:      1,
 		StderrRegex: string(config.ErrStackInvalidWatch),
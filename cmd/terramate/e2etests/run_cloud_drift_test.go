@@ -227,6 +227,42 @@ func TestCLIRunWithCloudSyncDriftStatus(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "failed after hook -- set status=failed",
+			layout: []string{
+				"s:stack:id=stack",
+				fmt.Sprintf(`f:stack/hooks.tm:terramate {
+  config {
+    run {
+      after = [[%q, "false"]]
+    }
+  }
+}`, testHelperBin),
+			},
+			cmd: []string{
+				testHelperBin, "exit", "2",
+			},
+			want: want{
+				run: runExpected{
+					Status:      1,
+					StderrRegex: "running after hook",
+				},
+				drifts: expectedDriftStackPayloadRequests{
+					{
+						DriftStackPayloadRequest: cloud.DriftStackPayloadRequest{
+							Stack: cloud.Stack{
+								Repository:    "local",
+								DefaultBranch: "main",
+								Path:          "/stack",
+								MetaName:      "stack",
+								MetaID:        "stack",
+							},
+							Status: stack.Failed,
+						},
+					},
+				},
+			},
+		},
 		{
 			name: "only stacks inside working dir are synced",
 			layout: []string{
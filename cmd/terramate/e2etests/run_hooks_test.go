@@ -0,0 +1,230 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package e2etest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+func TestRunHooks(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:stacks/stack-a`,
+		`s:stacks/stack-b`,
+	})
+
+	s.RootEntry().CreateFile("hooks.tm", fmt.Sprintf(`
+terramate {
+  config {
+    run {
+      before_all = [[%[1]q, "echo", "before all"]]
+      after_all  = [[%[1]q, "echo", "after all"]]
+      before     = [[%[1]q, "echo", "before", terramate.stack.name]]
+      after      = [[%[1]q, "echo", "after", terramate.stack.name]]
+    }
+  }
+}
+`, testHelperBin))
+
+	// stack-b overrides the before hook inherited from the root.
+	s.RootEntry().CreateFile("stacks/stack-b/hooks.tm", fmt.Sprintf(`
+terramate {
+  config {
+    run {
+      before = [[%q, "echo", "overridden"]]
+    }
+  }
+}
+`, testHelperBin))
+
+	git := s.Git()
+	git.CommitAll("first commit")
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.run(
+		"run",
+		testHelperBin,
+		"echo",
+		"command",
+	), runExpected{
+		Stdout: nljoin(
+			"before all",
+			"before stack-a",
+			"command",
+			"after stack-a",
+			"overridden",
+			"command",
+			"after stack-b",
+			"after all",
+		),
+	})
+}
+
+func TestRunFailingBeforeHookFailsStack(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:stack-a`,
+		`s:stack-b`,
+	})
+
+	s.RootEntry().CreateFile("stack-a/hooks.tm", fmt.Sprintf(`
+terramate {
+  config {
+    run {
+      before = [[%q, "false"]]
+    }
+  }
+}
+`, testHelperBin))
+
+	git := s.Git()
+	git.CommitAll("first commit")
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.run(
+		"run",
+		"--continue-on-error",
+		testHelperBin,
+		"echo",
+		"command",
+	), runExpected{
+		Stdout:      nljoin("command"),
+		StderrRegex: "running before hook",
+		Status:      1,
+	})
+}
+
+func TestRunFailingAfterHookFailsStack(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:stack-a`,
+		`s:stack-b`,
+	})
+
+	s.RootEntry().CreateFile("stack-a/hooks.tm", fmt.Sprintf(`
+terramate {
+  config {
+    run {
+      after = [[%q, "false"]]
+    }
+  }
+}
+`, testHelperBin))
+
+	git := s.Git()
+	git.CommitAll("first commit")
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.run(
+		"run",
+		testHelperBin,
+		"echo",
+		"command",
+	), runExpected{
+		Stdout:      nljoin("command"),
+		StderrRegex: "running after hook",
+		Status:      1,
+	})
+}
+
+func TestRunHooksOutputIsPartOfTheStackOutput(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:stack-a`,
+	})
+
+	s.RootEntry().CreateFile("hooks.tm", fmt.Sprintf(`
+terramate {
+  config {
+    run {
+      before = [[%[1]q, "echo", "before"]]
+      after  = [[%[1]q, "echo", "after"]]
+    }
+  }
+}
+`, testHelperBin))
+
+	git := s.Git()
+	git.CommitAll("first commit")
+
+	outdir := t.TempDir()
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.run(
+		"run",
+		"--prefix-output",
+		"--output-dir", outdir,
+		testHelperBin,
+		"echo",
+		"command",
+	), runExpected{
+		Stdout: nljoin(
+			"[/stack-a] before",
+			"[/stack-a] command",
+			"[/stack-a] after",
+		),
+	})
+
+	got, err := os.ReadFile(filepath.Join(outdir, "stack-a", "output.log"))
+	assert.NoError(t, err)
+	assert.EqualStrings(t, nljoin("before", "command", "after"), string(got))
+}
+
+func TestRunParallelHookDoesNotBlockOtherStacks(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:stack-a`,
+		`s:stack-b`,
+	})
+
+	// the before hook of stack-a only finishes after stack-b runs.
+	readyFile := filepath.Join(t.TempDir(), "ready")
+	s.RootEntry().CreateFile("stack-a/hooks.tm", fmt.Sprintf(`
+terramate {
+  config {
+    run {
+      before = [[%q, "wait-file", %q, "30s"]]
+    }
+  }
+}
+`, testHelperBin, readyFile))
+	s.RootEntry().CreateFile("stack-b/hooks.tm", fmt.Sprintf(`
+terramate {
+  config {
+    run {
+      after = [[%q, "touch", %q]]
+    }
+  }
+}
+`, testHelperBin, readyFile))
+
+	git := s.Git()
+	git.CommitAll("first commit")
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.run(
+		"run",
+		"--parallel", "2",
+		testHelperBin,
+		"stack-abs-path",
+		s.RootDir(),
+	), runExpected{
+		Stdout: nljoin("/stack-b", "/stack-a"),
+	})
+}
@@ -0,0 +1,87 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package e2etest
+
+import (
+	"testing"
+
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+func TestIncludeOrderedStacksOfChangedStacks(t *testing.T) {
+	t.Parallel()
+
+	type testcase struct {
+		name     string
+		flags    []string
+		want     []string
+		runOrder []string
+	}
+
+	for _, tc := range []testcase{
+		{
+			name:     "only changed",
+			want:     []string{"network"},
+			runOrder: []string{"/network"},
+		},
+		{
+			name:     "include dependents",
+			flags:    []string{"--include-dependents"},
+			want:     []string{"app", "db", "monitor", "network"},
+			runOrder: []string{"/network", "/app", "/db", "/monitor"},
+		},
+		{
+			name:     "include dependencies",
+			flags:    []string{"--include-dependencies"},
+			want:     []string{"account", "network"},
+			runOrder: []string{"/account", "/network"},
+		},
+		{
+			name:     "include dependents and dependencies",
+			flags:    []string{"--include-dependents", "--include-dependencies"},
+			want:     []string{"account", "app", "db", "monitor", "network"},
+			runOrder: []string{"/account", "/network", "/app", "/db", "/monitor"},
+		},
+		{
+			name:     "dependents are not filtered by tags",
+			flags:    []string{"--tags", "net", "--include-dependents"},
+			want:     []string{"app", "db", "monitor", "network"},
+			runOrder: []string{"/network", "/app", "/db", "/monitor"},
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := sandbox.New(t)
+			s.BuildTree([]string{
+				`s:account:before=["/network"]`,
+				`s:network:tags=["net"]`,
+				`s:app:after=["/network"]`,
+				`s:db:after=["/network"]`,
+				`s:monitor:after=["/app"]`,
+				`s:other`,
+			})
+
+			git := s.Git()
+			git.CommitAll("first commit")
+			git.Push("main")
+			git.CheckoutNew("change-network")
+
+			s.DirEntry("network").CreateFile("main.tf", "# changed")
+			git.CommitAll("network changed")
+
+			cli := newCLI(t, s.RootDir())
+			args := append([]string{"--changed"}, tc.flags...)
+			assertRunResult(t, cli.listStacks(args...), runExpected{
+				Stdout: nljoin(tc.want...),
+			})
+
+			args = append(args, "run", testHelperBin, "stack-abs-path", s.RootDir())
+			assertRunResult(t, cli.run(args...), runExpected{
+				Stdout: nljoin(tc.runOrder...),
+			})
+		})
+	}
+}
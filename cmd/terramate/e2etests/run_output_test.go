@@ -0,0 +1,117 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package e2etest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+func TestRunPrefixOutput(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:stack-a`,
+		`s:stack-b`,
+		"f:stack-a/testfile:line 1\nline 2\n",
+		"f:stack-b/testfile:no line terminator",
+	})
+
+	git := s.Git()
+	git.CommitAll("first commit")
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.run(
+		"run",
+		"--prefix-output",
+		testHelperBin,
+		"cat",
+		"testfile",
+	), runExpected{
+		Stdout: nljoin(
+			"[/stack-a] line 1",
+			"[/stack-a] line 2",
+		) + "[/stack-b] no line terminator",
+	})
+}
+
+func TestRunOutputDir(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:stack-a`,
+		`s:stack-a/child`,
+		"f:stack-a/testfile:stack-a\n",
+		"f:stack-a/child/testfile:child\n",
+	})
+
+	git := s.Git()
+	git.CommitAll("first commit")
+
+	outdir := t.TempDir()
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.run(
+		"run",
+		"--output-dir", outdir,
+		testHelperBin,
+		"cat",
+		"testfile",
+	), runExpected{
+		Stdout: nljoin("stack-a", "child"),
+	})
+
+	assertOutput := func(stackpath, want string) {
+		t.Helper()
+
+		got, err := os.ReadFile(filepath.Join(outdir, stackpath, "output.log"))
+		assert.NoError(t, err)
+		assert.EqualStrings(t, want, string(got))
+	}
+
+	assertOutput("stack-a", "stack-a\n")
+	assertOutput("stack-a/child", "child\n")
+}
+
+func TestRunOutputDirKeepsRetryAttempts(t *testing.T) {
+	t.Parallel()
+
+	for _, parallel := range []string{"1", "2"} {
+		parallel := parallel
+		t.Run("parallel="+parallel, func(t *testing.T) {
+			t.Parallel()
+
+			s := sandbox.New(t)
+			s.BuildTree([]string{`s:stack`})
+
+			git := s.Git()
+			git.CommitAll("first commit")
+
+			outdir := t.TempDir()
+			cli := newCLI(t, s.RootDir())
+			assertRunResult(t, cli.run(
+				"run",
+				"--parallel", parallel,
+				"--output-dir", outdir,
+				"--retry-max-attempts", "2",
+				testHelperBin,
+				"fail-once",
+				"marker",
+				"rate limit exceeded",
+			), runExpected{
+				Stdout:       "succeeded\n",
+				IgnoreStderr: true,
+			})
+
+			got, err := os.ReadFile(filepath.Join(outdir, "stack", "output.log"))
+			assert.NoError(t, err)
+			assert.EqualStrings(t, "rate limit exceeded\n\n--- attempt 2 ---\n\nsucceeded\n", string(got))
+		})
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package e2etest
+
+import (
+	"testing"
+
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+func TestRunParallelRespectsOrder(t *testing.T) {
+	t.Parallel()
+
+	const testfile = "testfile"
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:stack-a`,
+		`s:stack-b`,
+		`s:stack-c:after=["/stack-a", "/stack-b"]`,
+		`f:stack-a/testfile:stack-a` + "\n",
+		`f:stack-b/testfile:stack-b` + "\n",
+		`f:stack-c/testfile:stack-c` + "\n",
+	})
+
+	git := s.Git()
+	git.CommitAll("first commit")
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.run(
+		"run",
+		"--parallel=2",
+		testHelperBin,
+		"cat",
+		testfile,
+	), runExpected{
+		StdoutRegex: `^(stack-a\nstack-b\n|stack-b\nstack-a\n)stack-c\n$`,
+	})
+
+	assertRunResult(t, cli.run(
+		"run",
+		"--parallel=2",
+		"--reverse",
+		testHelperBin,
+		"cat",
+		testfile,
+	), runExpected{
+		StdoutRegex: `^stack-c\n(stack-a\nstack-b\n|stack-b\nstack-a\n)$`,
+	})
+}
+
+func TestRunParallelCancelsDependentsOfFailedStacks(t *testing.T) {
+	t.Parallel()
+
+	const testfile = "testfile"
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:stack-a`,
+		`s:stack-b`,
+		`s:stack-c:after=["/stack-a"]`,
+		`s:stack-d:after=["/stack-c"]`,
+		`f:stack-b/testfile:stack-b` + "\n",
+		`f:stack-c/testfile:stack-c` + "\n",
+		`f:stack-d/testfile:stack-d` + "\n",
+	})
+
+	git := s.Git()
+	git.CommitAll("first commit")
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.run(
+		"run",
+		"--parallel=4",
+		"--continue-on-error",
+		testHelperBin,
+		"cat",
+		testfile,
+	), runExpected{
+		Stdout:       "stack-b\n",
+		IgnoreStderr: true,
+		Status:       1,
+	})
+}
+
+func TestRunParallelInvalidValue(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:stack`,
+	})
+
+	git := s.Git()
+	git.CommitAll("first commit")
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.run(
+		"run",
+		"--parallel=0",
+		testHelperBin,
+		"true",
+	), runExpected{
+		StderrRegex: "--parallel expects a number greater than zero",
+		Status:      1,
+	})
+}
@@ -0,0 +1,148 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package e2etest
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+type runReport struct {
+	Git *struct {
+		BaseRef    string `json:"base_ref"`
+		HeadCommit string `json:"head_commit"`
+	} `json:"git"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+	Stacks     []struct {
+		Path     string   `json:"path"`
+		Command  []string `json:"command"`
+		Status   string   `json:"status"`
+		Reason   string   `json:"reason"`
+		ExitCode *int     `json:"exit_code"`
+		Duration *float64 `json:"duration"`
+	} `json:"stacks"`
+}
+
+func TestRunReportJSON(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:stack-a`,
+		`s:stack-b`,
+		`s:stack-c`,
+		`f:stack-a/testfile:stack-a`,
+		`f:stack-c/testfile:stack-c`,
+	})
+
+	git := s.Git()
+	git.CommitAll("first commit")
+
+	loadReport := func(path string) runReport {
+		t.Helper()
+
+		data, err := os.ReadFile(path)
+		assert.NoError(t, err)
+
+		var report runReport
+		assert.NoError(t, json.Unmarshal(data, &report))
+		return report
+	}
+
+	reportFile := filepath.Join(t.TempDir(), "report.json")
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.run(
+		"run",
+		"--report-json", reportFile,
+		testHelperBin,
+		"cat",
+		"testfile",
+	), runExpected{
+		Stdout:       "stack-a",
+		IgnoreStderr: true,
+		Status:       1,
+	})
+
+	report := loadReport(reportFile)
+	if report.Git == nil {
+		t.Fatal("git information not reported")
+	}
+	assert.EqualStrings(t, git.RevParse("HEAD"), report.Git.HeadCommit)
+	if report.FinishedAt == nil {
+		t.Fatal("finished_at not reported")
+	}
+	assert.EqualInts(t, 3, len(report.Stacks))
+
+	want := []struct {
+		path     string
+		status   string
+		exitCode int
+		reason   string
+	}{
+		{path: "/stack-a", status: "ok", exitCode: 0},
+		{path: "/stack-b", status: "failed", exitCode: 1, reason: "execution failed"},
+		{path: "/stack-c", status: "skipped", exitCode: -1, reason: "a previous stack failed"},
+	}
+
+	for i, w := range want {
+		got := report.Stacks[i]
+		assert.EqualStrings(t, w.path, got.Path)
+		assert.EqualStrings(t, w.status, got.Status, "stack %s status", w.path)
+		assert.EqualInts(t, 3, len(got.Command))
+		assert.EqualStrings(t, testHelperBin, got.Command[0])
+
+		if w.exitCode == -1 {
+			if got.ExitCode != nil || got.Duration != nil {
+				t.Fatalf("stack %s: exit code and duration must not be reported", w.path)
+			}
+		} else {
+			if got.ExitCode == nil || got.Duration == nil {
+				t.Fatalf("stack %s: exit code and duration must be reported", w.path)
+			}
+			assert.EqualInts(t, w.exitCode, *got.ExitCode, "stack %s exit code", w.path)
+		}
+
+		if w.reason == "" {
+			assert.EqualStrings(t, "", got.Reason)
+		} else {
+			assert.IsTrue(t, len(got.Reason) > 0, "stack %s has no reason", w.path)
+			assertHasSubstring(t, got.Reason, w.reason)
+		}
+	}
+
+	assertRunResult(t, cli.run(
+		"run",
+		"--continue-on-error",
+		"--report-json", reportFile,
+		testHelperBin,
+		"cat",
+		"testfile",
+	), runExpected{
+		Stdout:       "stack-astack-c",
+		IgnoreStderr: true,
+		Status:       1,
+	})
+
+	report = loadReport(reportFile)
+	assert.EqualInts(t, 3, len(report.Stacks))
+	assert.EqualStrings(t, "ok", report.Stacks[0].Status)
+	assert.EqualStrings(t, "failed", report.Stacks[1].Status)
+	assert.EqualStrings(t, "ok", report.Stacks[2].Status)
+}
+
+func assertHasSubstring(t *testing.T, s, substr string) {
+	t.Helper()
+
+	if !strings.Contains(s, substr) {
+		t.Fatalf("%q does not contain %q", s, substr)
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package e2etest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+func TestRunResume(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:stack-a`,
+		`s:stack-b`,
+		`s:stack-c`,
+	})
+
+	git := s.Git()
+	git.CommitAll("first commit")
+
+	// every stack prints the file named after the stack in outdir, then the
+	// stacks without a file fail.
+	outdir := t.TempDir()
+	writeOutFile := func(name string) {
+		t.Helper()
+		assert.NoError(t, os.WriteFile(filepath.Join(outdir, name), []byte(name+"\n"), 0644))
+	}
+	writeOutFile("stack-a")
+	writeOutFile("stack-c")
+
+	runArgs := []string{
+		"--eval",
+		testHelperBin,
+		"cat",
+		filepath.Join(outdir, "${terramate.stack.name}"),
+	}
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.run(append([]string{"run"}, runArgs...)...), runExpected{
+		Stdout:       "stack-a\n",
+		IgnoreStderr: true,
+		Status:       1,
+	})
+
+	writeOutFile("stack-b")
+
+	assertRunResult(t, cli.run(append([]string{"run", "--resume"}, runArgs...)...), runExpected{
+		Stdout: nljoin("stack-b", "stack-c"),
+	})
+
+	assertRunResult(t, cli.run(append([]string{"run", "--resume"}, runArgs...)...), runExpected{
+		Stdout: "Nothing to resume, all stacks of the last run succeeded.\n",
+	})
+}
+
+func TestRunResumeFailsIfRunChanged(t *testing.T) {
+	t.Parallel()
+
+	setup := func(t *testing.T) (sandbox.S, []string) {
+		s := sandbox.New(t)
+		s.BuildTree([]string{
+			`s:stack-a`,
+			`s:stack-b`,
+			`f:stack-a/testfile:stack-a`,
+		})
+
+		git := s.Git()
+		git.CommitAll("first commit")
+
+		runArgs := []string{testHelperBin, "cat", "testfile"}
+
+		cli := newCLI(t, s.RootDir())
+		assertRunResult(t, cli.run(append([]string{"run"}, runArgs...)...), runExpected{
+			Stdout:       "stack-a",
+			IgnoreStderr: true,
+			Status:       1,
+		})
+		return s, runArgs
+	}
+
+	t.Run("git HEAD changed", func(t *testing.T) {
+		t.Parallel()
+
+		s, runArgs := setup(t)
+		s.RootEntry().CreateFile("stack-b/testfile", "stack-b")
+		s.Git().CommitAll("second commit")
+
+		cli := newCLI(t, s.RootDir())
+		assertRunResult(t, cli.run(append([]string{"run", "--resume"}, runArgs...)...), runExpected{
+			StderrRegex: "git HEAD changed since the last run",
+			Status:      1,
+		})
+	})
+
+	t.Run("selected stacks changed", func(t *testing.T) {
+		t.Parallel()
+
+		s, runArgs := setup(t)
+
+		cli := newCLI(t, filepath.Join(s.RootDir(), "stack-b"))
+		assertRunResult(t, cli.run(append([]string{"run", "--resume"}, runArgs...)...), runExpected{
+			StderrRegex: "the set of stacks to run changed since the last run",
+			Status:      1,
+		})
+	})
+
+	t.Run("command changed", func(t *testing.T) {
+		t.Parallel()
+
+		s, _ := setup(t)
+
+		cli := newCLI(t, s.RootDir())
+		assertRunResult(t, cli.run("run", "--resume", testHelperBin, "true"), runExpected{
+			StderrRegex: "command changed since the last run",
+			Status:      1,
+		})
+	})
+}
+
+func TestRunResumeWithoutPreviousRun(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{`s:stack`})
+	s.Git().CommitAll("first commit")
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.run("run", "--resume", testHelperBin, "true"), runExpected{
+		StderrRegex: "no previous run found",
+		Status:      1,
+	})
+}
+
+func TestRunResumeSkipsAllSucceededStacks(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:stack-a`,
+		`s:stack-b`,
+		`s:stack-c`,
+	})
+
+	git := s.Git()
+	git.CommitAll("first commit")
+
+	outdir := t.TempDir()
+	writeOutFile := func(name string) {
+		t.Helper()
+		assert.NoError(t, os.WriteFile(filepath.Join(outdir, name), []byte(name+"\n"), 0644))
+	}
+	writeOutFile("stack-a")
+	writeOutFile("stack-c")
+
+	runArgs := []string{
+		"--eval",
+		testHelperBin,
+		"cat",
+		filepath.Join(outdir, "${terramate.stack.name}"),
+	}
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.run(append([]string{"run", "--continue-on-error"}, runArgs...)...), runExpected{
+		Stdout:       nljoin("stack-a", "stack-c"),
+		IgnoreStderr: true,
+		Status:       1,
+	})
+
+	writeOutFile("stack-b")
+
+	assertRunResult(t, cli.run(append([]string{"run", "--resume"}, runArgs...)...), runExpected{
+		Stdout: "stack-b\n",
+	})
+}
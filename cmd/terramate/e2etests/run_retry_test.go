@@ -0,0 +1,142 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package e2etest
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+func TestRunRetryFailedStacks(t *testing.T) {
+	t.Parallel()
+
+	for _, parallel := range []string{"1", "2"} {
+		parallel := parallel
+		t.Run("parallel="+parallel, func(t *testing.T) {
+			t.Parallel()
+
+			s := sandbox.New(t)
+			s.BuildTree([]string{
+				`s:stack-a`,
+				`s:stack-b`,
+			})
+
+			git := s.Git()
+			git.CommitAll("first commit")
+
+			reportFile := filepath.Join(t.TempDir(), "report.json")
+			cli := newCLI(t, s.RootDir())
+			assertRunResult(t, cli.run(
+				"run",
+				"--parallel", parallel,
+				"--retry-max-attempts", "2",
+				"--report-json", reportFile,
+				testHelperBin,
+				"fail-once",
+				"marker",
+				"rate limit exceeded",
+			), runExpected{
+				Stdout:       nljoin("succeeded", "succeeded"),
+				IgnoreStderr: true,
+			})
+
+			data, err := os.ReadFile(reportFile)
+			assert.NoError(t, err)
+
+			var report struct {
+				Stacks []struct {
+					Path     string `json:"path"`
+					Status   string `json:"status"`
+					Attempts []struct {
+						Attempt  int    `json:"attempt"`
+						Status   string `json:"status"`
+						ExitCode int    `json:"exit_code"`
+					} `json:"attempts"`
+				} `json:"stacks"`
+			}
+			assert.NoError(t, json.Unmarshal(data, &report))
+			assert.EqualInts(t, 2, len(report.Stacks))
+
+			for _, st := range report.Stacks {
+				assert.EqualStrings(t, "ok", st.Status, "stack %s status", st.Path)
+				assert.EqualInts(t, 2, len(st.Attempts), "stack %s attempts", st.Path)
+				assert.EqualInts(t, 1, st.Attempts[0].Attempt)
+				assert.EqualStrings(t, "failed", st.Attempts[0].Status)
+				assert.EqualInts(t, 1, st.Attempts[0].ExitCode)
+				assert.EqualInts(t, 2, st.Attempts[1].Attempt)
+				assert.EqualStrings(t, "ok", st.Attempts[1].Status)
+				assert.EqualInts(t, 0, st.Attempts[1].ExitCode)
+			}
+		})
+	}
+}
+
+func TestRunRetryOnlyMatchingFailures(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:stack`,
+	})
+
+	git := s.Git()
+	git.CommitAll("first commit")
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.run(
+		"run",
+		"--retry-max-attempts", "3",
+		"--retry-on", "rate limit",
+		testHelperBin,
+		"fail-once",
+		"marker",
+		"permission denied",
+	), runExpected{
+		StderrRegex: "permission denied",
+		Status:      1,
+	})
+}
+
+func TestRunRetryFromConfig(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:stack`,
+	})
+
+	s.RootEntry().CreateFile("retry.tm", `
+terramate {
+  config {
+    run {
+      retry {
+        max_attempts = 2
+        backoff      = "10ms"
+        patterns     = ["state lock", "rate limit"]
+      }
+    }
+  }
+}
+`)
+
+	git := s.Git()
+	git.CommitAll("first commit")
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.run(
+		"run",
+		testHelperBin,
+		"fail-once",
+		"marker",
+		"error acquiring the state lock",
+	), runExpected{
+		Stdout:       nljoin("succeeded"),
+		IgnoreStderr: true,
+	})
+}
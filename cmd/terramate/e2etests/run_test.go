@@ -2367,6 +2367,46 @@ stack "/stack":
 	})
 }
 
+func TestRunEnvUnsetRemovesInheritedVars(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		"s:stack",
+		`f:stack/env.tm:terramate {
+  config {
+    run {
+      env {
+        TM_TEST_UNSET = unset
+      }
+    }
+  }
+}`,
+	})
+
+	git := s.Git()
+	git.CommitAll("first commit")
+
+	clienv := append(testEnviron(t),
+		"TM_TEST_UNSET=inherited",
+		"TM_TEST_KEPT=inherited",
+	)
+	tm := newCLI(t, s.RootDir(), clienv...)
+
+	res := tm.run("run", testHelperBin, "env")
+	assertRunResult(t, res, runExpected{IgnoreStdout: true})
+
+	gotenv := strings.Split(strings.Trim(res.Stdout, "\n"), "\n")
+	for _, env := range gotenv {
+		if strings.HasPrefix(env, "TM_TEST_UNSET=") {
+			t.Fatalf("unset variable inherited by the stack command: %s", env)
+		}
+	}
+	if !strings.Contains(res.Stdout, "TM_TEST_KEPT=inherited\n") {
+		t.Fatalf("variable not inherited by the stack command:\n%s", res.Stdout)
+	}
+}
+
 func nljoin(stacks ...string) string {
 	return strings.Join(stacks, "\n") + "\n"
 }
@@ -0,0 +1,140 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package e2etest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+func TestRunTimeoutEscalatesSignals(t *testing.T) {
+	t.Parallel()
+
+	for _, parallel := range []string{"1", "2"} {
+		parallel := parallel
+		t.Run("parallel="+parallel, func(t *testing.T) {
+			t.Parallel()
+
+			s := sandbox.New(t)
+			s.BuildTree([]string{
+				`s:stack:timeout=500ms`,
+			})
+
+			git := s.Git()
+			git.CommitAll("first commit")
+
+			cli := newCLI(t, s.RootDir())
+			assertRunResult(t, cli.run(
+				"run",
+				"--parallel", parallel,
+				"--timeout-interrupt-grace", "100ms",
+				"--timeout-terminate-grace", "100ms",
+				testHelperBin,
+				"hang",
+			), runExpected{
+				Stdout:      nljoin("ready", "interrupt", "terminated"),
+				StderrRegex: "timed out after 500ms",
+				Status:      1,
+			})
+		})
+	}
+}
+
+func TestRunTimeoutFromConfig(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:stack-a`,
+		`s:stack-b`,
+	})
+
+	s.RootEntry().CreateFile("timeout.tm", `
+terramate {
+  config {
+    run {
+      timeout {
+        duration        = "500ms"
+        interrupt_grace = "0s"
+        terminate_grace = "0s"
+      }
+    }
+  }
+}
+`)
+
+	git := s.Git()
+	git.CommitAll("first commit")
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.run(
+		"run",
+		"--continue-on-error",
+		testHelperBin,
+		"sleep",
+		"1m",
+	), runExpected{
+		Stdout:      nljoin("ready", "ready"),
+		StderrRegex: "timed out after 500ms",
+		Status:      1,
+	})
+}
+
+func TestRunTimeoutFlagOverridesStackTimeout(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:stack:timeout=1ms`,
+	})
+
+	git := s.Git()
+	git.CommitAll("first commit")
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.run(
+		"run",
+		"--timeout", "1m",
+		testHelperBin,
+		"sleep",
+		"100ms",
+	), runExpected{
+		Stdout: nljoin("ready"),
+	})
+}
+
+func TestRunTimeoutIsNotBlockedByChildProcesses(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:stack`,
+	})
+
+	git := s.Git()
+	git.CommitAll("first commit")
+
+	// the child process keeps the output of the stack command open.
+	cli := newCLI(t, s.RootDir())
+	start := time.Now()
+	assertRunResult(t, cli.run(
+		"run",
+		"--prefix-output",
+		"--timeout", "1s",
+		"--timeout-interrupt-grace", "100ms",
+		"--timeout-terminate-grace", "100ms",
+		testHelperBin,
+		"spawn-sleep",
+		"30s",
+	), runExpected{
+		Stdout:      "[/stack] ready\n",
+		StderrRegex: "timed out after 1s",
+		Status:      1,
+	})
+	if elapsed := time.Since(start); elapsed > 20*time.Second {
+		t.Fatalf("run took %s, the timeout is not enforced", elapsed)
+	}
+}
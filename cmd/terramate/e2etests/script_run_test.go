@@ -0,0 +1,277 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package e2etest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/cmd/terramate/cli"
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+func TestScriptRun(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:stacks/stack-a`,
+		`s:stacks/stack-b:after=["/stacks/stack-c"]`,
+		`s:stacks/stack-c`,
+		`s:other`,
+	})
+
+	s.RootEntry().CreateFile("globals.tm", `
+globals {
+  greeting = "hello"
+}
+`)
+
+	s.RootEntry().CreateFile("stacks/script.tm", fmt.Sprintf(`
+script "hello" {
+  description = "say hello"
+  job {
+    commands = [
+      [%[1]q, "echo", global.greeting, terramate.stack.name],
+    ]
+  }
+  job {
+    commands = [
+      [%[1]q, "echo", "bye"],
+    ]
+  }
+}
+`, testHelperBin))
+
+	// stack-c overrides the inherited script.
+	s.RootEntry().CreateFile("stacks/stack-c/script.tm", fmt.Sprintf(`
+script "hello" {
+  job {
+    commands = [[%q, "echo", "overridden"]]
+  }
+}
+`, testHelperBin))
+
+	git := s.Git()
+	git.CommitAll("first commit")
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.run("script", "run", "hello"), runExpected{
+		Stdout: nljoin(
+			"hello stack-a",
+			"bye",
+			"overridden",
+			"hello stack-b",
+			"bye",
+		),
+	})
+
+	assertRunResult(t, cli.run("script", "run", "--reverse", "hello"), runExpected{
+		Stdout: nljoin(
+			"hello stack-b",
+			"bye",
+			"overridden",
+			"hello stack-a",
+			"bye",
+		),
+	})
+
+	cli = newCLI(t, filepath.Join(s.RootDir(), "stacks", "stack-a"))
+	assertRunResult(t, cli.run("script", "run", "hello"), runExpected{
+		Stdout: nljoin(
+			"hello stack-a",
+			"bye",
+		),
+	})
+
+	cli = newCLI(t, filepath.Join(s.RootDir(), "other"))
+	assertRunResult(t, cli.run("script", "run", "hello"), runExpected{
+		StderrRegex: `script "hello" not found`,
+		Status:      1,
+	})
+}
+
+func TestScriptRunStopsOnFailure(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:stack-a`,
+		`s:stack-b`,
+	})
+
+	s.RootEntry().CreateFile("script.tm", fmt.Sprintf(`
+script "fail" {
+  job {
+    commands = [
+      [%[1]q, "echo", terramate.stack.name],
+      [%[1]q, "false"],
+      [%[1]q, "echo", "unreachable"],
+    ]
+  }
+}
+`, testHelperBin))
+
+	git := s.Git()
+	git.CommitAll("first commit")
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.run("script", "run", "fail"), runExpected{
+		Stdout:       "stack-a\n",
+		IgnoreStderr: true,
+		Status:       1,
+	})
+
+	assertRunResult(t, cli.run("script", "run", "--continue-on-error", "fail"), runExpected{
+		Stdout:       nljoin("stack-a", "stack-b"),
+		IgnoreStderr: true,
+		Status:       1,
+	})
+}
+
+func TestScriptRunCheckRemoteDisabled(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	stack := s.CreateStack("stack")
+
+	s.RootEntry().CreateFile("script.tm", fmt.Sprintf(`
+script "hello" {
+  job {
+    commands = [[%q, "echo", "hello"]]
+  }
+}
+`, testHelperBin))
+
+	git := s.Git()
+	git.CommitAll("all")
+
+	setupLocalMainBranchBehindOriginMain(git, func() {
+		stack.CreateFile("some-new-file", "testing")
+	})
+
+	tmcli := newCLI(t, s.RootDir())
+	assertRunResult(t, tmcli.run("script", "run", "hello"), runExpected{
+		Status:      1,
+		StderrRegex: string(cli.ErrCurrentHeadIsOutOfDate),
+	})
+
+	assertRunResult(t, tmcli.run(
+		"script", "run",
+		"--disable-check-git-remote",
+		"hello",
+	), runExpected{Stdout: "hello\n"})
+}
+
+func TestScriptRunIsSingleStackExecution(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:stack-a`,
+		`s:stack-b`,
+	})
+
+	s.RootEntry().CreateFile("hooks.tm", fmt.Sprintf(`
+terramate {
+  config {
+    run {
+      before = [[%[1]q, "echo", "before", terramate.stack.name]]
+      after  = [[%[1]q, "echo", "after", terramate.stack.name]]
+    }
+  }
+}
+
+script "deploy" {
+  job {
+    commands = [
+      [%[1]q, "echo", "init"],
+      [%[1]q, "echo", "apply"],
+    ]
+  }
+  job {
+    commands = [[%[1]q, "echo", "done"]]
+  }
+}
+`, testHelperBin))
+
+	git := s.Git()
+	git.CommitAll("first commit")
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.run("script", "run", "deploy"), runExpected{
+		Stdout: nljoin(
+			"before stack-a",
+			"init",
+			"apply",
+			"done",
+			"after stack-a",
+			"before stack-b",
+			"init",
+			"apply",
+			"done",
+			"after stack-b",
+		),
+	})
+}
+
+func TestScriptRunWithCloudSyncDeployment(t *testing.T) {
+	t.Parallel()
+
+	addr := startFakeTMCServer(t)
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		"s:s1:id=s1-id-script",
+		"s:s2:id=s2-id-script",
+	})
+
+	s.RootEntry().CreateFile("script.tm", fmt.Sprintf(`
+script "deploy" {
+  job {
+    commands = [
+      [%[1]q, "echo", terramate.stack.name],
+      [%[1]q, "exit", "1"],
+      [%[1]q, "echo", "unreachable"],
+    ]
+  }
+}
+`, testHelperBin))
+
+	// s1 overrides the failing script.
+	s.RootEntry().CreateFile("s1/script.tm", fmt.Sprintf(`
+script "deploy" {
+  job {
+    commands = [
+      [%[1]q, "echo", terramate.stack.name],
+      [%[1]q, "echo", "ok"],
+    ]
+  }
+}
+`, testHelperBin))
+	s.Git().CommitAll("all stacks committed")
+
+	env := removeEnv(os.Environ(), "CI")
+	env = append(env, "TMC_API_URL=http://"+addr)
+	cli := newCLI(t, s.RootDir(), env...)
+
+	id, err := uuid.NewRandom()
+	assert.NoError(t, err)
+	runid := id.String()
+	cli.appendEnv = []string{"TM_TEST_RUN_ID=" + runid}
+
+	assertRunResult(t, cli.run("script", "run", "--cloud-sync-deployment", "deploy"), runExpected{
+		Status:       1,
+		Stdout:       nljoin("s1", "ok", "s2"),
+		IgnoreStderr: true,
+	})
+	assertRunEvents(t, addr, runid, []string{"s1-id-script", "s2-id-script"}, eventsResponse{
+		"s1": []string{"pending", "running", "ok"},
+		"s2": []string{"pending", "running", "failed"},
+	})
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/terramate-io/terramate/config/filter"
 	"github.com/terramate-io/terramate/errors"
 	"github.com/terramate-io/terramate/hcl"
+	"github.com/terramate-io/terramate/hcl/info"
 	"github.com/terramate-io/terramate/project"
 	"github.com/zclconf/go-cty/cty"
 )
@@ -308,6 +309,24 @@ func (tree *Tree) IsStack() bool {
 	return tree.Node.Stack != nil
 }
 
+// StackEntryRanges returns the range of each entry of the given attribute of
+// the stack block, indexed by the entry. The attribute is one of after, before,
+// wants or wanted_by. It returns nil if the node is not a stack.
+func (tree *Tree) StackEntryRanges(attr string) map[string]info.Range {
+	if !tree.IsStack() {
+		return nil
+	}
+	return tree.Node.Stack.EntryRanges[attr]
+}
+
+// StackEntryRange returns the range where the entry of the given attribute of
+// the stack block is defined. It returns false if the node is not a stack or the
+// entry is not defined in the configuration.
+func (tree *Tree) StackEntryRange(attr, entry string) (info.Range, bool) {
+	r, ok := tree.StackEntryRanges(attr)[entry]
+	return r, ok
+}
+
 // Stacks returns the stack nodes from the tree.
 // The search algorithm is a Deep-First-Search (DFS).
 func (tree *Tree) Stacks() List[*Tree] {
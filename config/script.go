@@ -0,0 +1,131 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package config
+
+import (
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/hcl"
+	"github.com/terramate-io/terramate/hcl/ast"
+	"github.com/terramate-io/terramate/hcl/eval"
+	"github.com/terramate-io/terramate/project"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Script represents an evaluated script block.
+type Script struct {
+	Name        string
+	Description string
+	Jobs        []ScriptJob
+}
+
+// ScriptJob represents an evaluated job of a script.
+type ScriptJob struct {
+	// Commands is the list of commands of the job, each command being the
+	// program name followed by its arguments.
+	Commands [][]string
+}
+
+// LookupScript looks up the script with the given name, starting at the dir
+// and going up to the root directory. The scripts are inherited by the child
+// directories and the closest definition to dir is the one returned.
+func (root *Root) LookupScript(dir project.Path, name string) (hcl.Script, bool) {
+	for {
+		if cfg, ok := root.Lookup(dir); ok {
+			for _, script := range cfg.Node.Scripts {
+				if script.Name == name {
+					return script, true
+				}
+			}
+		}
+
+		parent := dir.Dir()
+		if parent == dir {
+			return hcl.Script{}, false
+		}
+		dir = parent
+	}
+}
+
+// EvalScript evaluates a given script configuration and returns its
+// evaluated form.
+func EvalScript(evalctx *eval.Context, cfg hcl.Script) (Script, error) {
+	script := Script{
+		Name: cfg.Name,
+	}
+	errs := errors.L()
+
+	if cfg.Description != nil {
+		desc, err := evalString(evalctx, cfg.Description.Expr, "script.description")
+		if err != nil {
+			errs.Append(err)
+		} else {
+			script.Description = desc
+		}
+	}
+
+	for _, jobCfg := range cfg.Jobs {
+		commands, err := EvalCommands(evalctx, jobCfg.Commands, "script.job.commands")
+		if err != nil {
+			errs.Append(err)
+			continue
+		}
+		script.Jobs = append(script.Jobs, ScriptJob{
+			Commands: commands,
+		})
+	}
+
+	if err := errs.AsError(); err != nil {
+		return Script{}, err
+	}
+	return script, nil
+}
+
+// EvalCommands evaluates the given attribute as a list of commands, each
+// command being a list of strings with the program name followed by its
+// arguments. The name is the fully qualified name of the attribute and is
+// used only for error reporting.
+func EvalCommands(evalctx *eval.Context, attr *ast.Attribute, name string) ([][]string, error) {
+	val, err := evalctx.Eval(attr.Expr)
+	if err != nil {
+		return nil, errors.E(err, "evaluating %s", name)
+	}
+
+	newErr := func() error {
+		return errors.E(ErrSchema, attr.Range,
+			"%s must be a list of commands (list of strings), got %s",
+			name, val.Type().FriendlyName())
+	}
+
+	if !val.Type().IsListType() && !val.Type().IsTupleType() {
+		return nil, newErr()
+	}
+
+	var commands [][]string
+	it := val.ElementIterator()
+	for it.Next() {
+		_, cmdVal := it.Element()
+		if !cmdVal.Type().IsListType() && !cmdVal.Type().IsTupleType() {
+			return nil, newErr()
+		}
+
+		var cmd []string
+		argsIt := cmdVal.ElementIterator()
+		for argsIt.Next() {
+			_, arg := argsIt.Element()
+			if arg.Type() != cty.String {
+				return nil, errors.E(ErrSchema, attr.Range,
+					"%s arguments must be strings, got %s",
+					name, arg.Type().FriendlyName())
+			}
+			cmd = append(cmd, arg.AsString())
+		}
+
+		if len(cmd) == 0 {
+			return nil, errors.E(ErrSchema, attr.Range,
+				"%s must not have empty commands", name)
+		}
+		commands = append(commands, cmd)
+	}
+	return commands, nil
+}
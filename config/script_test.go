@@ -0,0 +1,170 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package config_test
+
+import (
+	"testing"
+
+	hhcl "github.com/hashicorp/hcl/v2"
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/config"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/hcl"
+	"github.com/terramate-io/terramate/hcl/ast"
+	"github.com/terramate-io/terramate/hcl/eval"
+	"github.com/terramate-io/terramate/stdlib"
+	"github.com/terramate-io/terramate/test"
+)
+
+func TestScriptEval(t *testing.T) {
+	t.Parallel()
+	type testcase struct {
+		name       string
+		script     hcl.Script
+		namespaces namespaces
+		want       config.Script
+		wantErr    error
+	}
+
+	attr := func(s string) *ast.Attribute {
+		return &ast.Attribute{
+			Attribute: &hhcl.Attribute{
+				Name: "test",
+				Expr: test.NewExpr(t, s),
+			},
+		}
+	}
+
+	tcases := []testcase{
+		{
+			name: "using literals",
+			script: hcl.Script{
+				Name:        "deploy",
+				Description: attr(`"deploy stack"`),
+				Jobs: []hcl.ScriptJob{
+					{Commands: attr(`[["terraform", "init"], ["terraform", "apply"]]`)},
+					{Commands: attr(`[["echo", "done"]]`)},
+				},
+			},
+			want: config.Script{
+				Name:        "deploy",
+				Description: "deploy stack",
+				Jobs: []config.ScriptJob{
+					{
+						Commands: [][]string{
+							{"terraform", "init"},
+							{"terraform", "apply"},
+						},
+					},
+					{
+						Commands: [][]string{
+							{"echo", "done"},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "accessing namespace values",
+			namespaces: namespaces{
+				"global": nsvalues{
+					"planfile": "out.tfplan",
+				},
+			},
+			script: hcl.Script{
+				Name: "plan",
+				Jobs: []hcl.ScriptJob{
+					{Commands: attr(`[["terraform", "plan", "-out", global.planfile]]`)},
+				},
+			},
+			want: config.Script{
+				Name: "plan",
+				Jobs: []config.ScriptJob{
+					{
+						Commands: [][]string{
+							{"terraform", "plan", "-out", "out.tfplan"},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "commands is not a list fails",
+			script: hcl.Script{
+				Name: "fail",
+				Jobs: []hcl.ScriptJob{
+					{Commands: attr(`"terraform init"`)},
+				},
+			},
+			wantErr: errors.E(config.ErrSchema),
+		},
+		{
+			name: "command is not a list of strings fails",
+			script: hcl.Script{
+				Name: "fail",
+				Jobs: []hcl.ScriptJob{
+					{Commands: attr(`["terraform", "init"]`)},
+				},
+			},
+			wantErr: errors.E(config.ErrSchema),
+		},
+		{
+			name: "command argument is not string fails",
+			script: hcl.Script{
+				Name: "fail",
+				Jobs: []hcl.ScriptJob{
+					{Commands: attr(`[["sleep", {}]]`)},
+				},
+			},
+			wantErr: errors.E(config.ErrSchema),
+		},
+		{
+			name: "empty command fails",
+			script: hcl.Script{
+				Name: "fail",
+				Jobs: []hcl.ScriptJob{
+					{Commands: attr(`[[]]`)},
+				},
+			},
+			wantErr: errors.E(config.ErrSchema),
+		},
+		{
+			name: "commands eval fails",
+			script: hcl.Script{
+				Name: "fail",
+				Jobs: []hcl.ScriptJob{
+					{Commands: attr(`[["echo", unknown.val]]`)},
+				},
+			},
+			wantErr: errors.E(eval.ErrEval),
+		},
+		{
+			name: "description is not string fails",
+			script: hcl.Script{
+				Name:        "fail",
+				Description: attr(`[]`),
+				Jobs: []hcl.ScriptJob{
+					{Commands: attr(`[["echo"]]`)},
+				},
+			},
+			wantErr: errors.E(config.ErrSchema),
+		},
+	}
+
+	for _, tcase := range tcases {
+		tcase := tcase
+		t.Run(tcase.name, func(t *testing.T) {
+			t.Parallel()
+			hclctx := eval.NewContext(stdlib.Functions(t.TempDir()))
+
+			for k, v := range tcase.namespaces {
+				hclctx.SetNamespace(k, v.asCtyMap())
+			}
+
+			got, err := config.EvalScript(hclctx, tcase.script)
+			assert.IsError(t, err, tcase.wantErr)
+			test.AssertDiff(t, got, tcase.want)
+		})
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/rs/zerolog/log"
 	"github.com/terramate-io/terramate/config/tag"
@@ -51,9 +52,14 @@ type (
 		// whenever they are selected.
 		WantedBy []string
 
-		// Watch is the list of files to be watched for changes.
+		// Watch is the list of files, directories and glob patterns to be
+		// watched for changes.
 		Watch []project.Path
 
+		// Timeout is the maximum time a command can run in the stack.
+		// Zero means the project default is used.
+		Timeout time.Duration
+
 		// IsChanged tells if this is a changed stack.
 		IsChanged bool
 	}
@@ -106,6 +112,7 @@ func NewStackFromHCL(root string, cfg hcl.Config) (*Stack, error) {
 		Wants:       cfg.Stack.Wants,
 		WantedBy:    cfg.Stack.WantedBy,
 		Watch:       watchFiles,
+		Timeout:     cfg.Stack.Timeout,
 		Dir:         project.PrjAbsPath(root, cfg.AbsDir()),
 	}
 	err = stack.Validate()
@@ -271,23 +278,31 @@ func validateWatchPaths(rootdir string, stackpath string, paths []string) (proje
 		if !strings.HasPrefix(abspath, rootdir) {
 			return nil, errors.E("path %s is outside project root", pathstr)
 		}
-		st, err := os.Stat(abspath)
-		if err == nil {
-			if st.IsDir() {
-				return nil, errors.E("stack.watch must be a list of regular files "+
-					"but directory %q was provided", pathstr)
-			}
-
-			if !st.Mode().IsRegular() {
-				return nil, errors.E("stack.watch must be a list of regular files "+
-					"but file %q has mode %s", pathstr, st.Mode())
+		prjpath := project.PrjAbsPath(rootdir, abspath)
+		if IsWatchPattern(prjpath.String()) {
+			for _, elem := range strings.Split(prjpath.String(), "/") {
+				if _, err := path.Match(elem, ""); err != nil {
+					return nil, errors.E(err, "invalid stack.watch pattern %q", pathstr)
+				}
 			}
+			projectPaths = append(projectPaths, prjpath)
+			continue
 		}
-		projectPaths = append(projectPaths, project.PrjAbsPath(rootdir, abspath))
+		st, err := os.Stat(abspath)
+		if err == nil && !st.IsDir() && !st.Mode().IsRegular() {
+			return nil, errors.E("stack.watch must be a list of regular files, "+
+				"directories or patterns but file %q has mode %s", pathstr, st.Mode())
+		}
+		projectPaths = append(projectPaths, prjpath)
 	}
 	return projectPaths, nil
 }
 
+// IsWatchPattern tells if the stack.watch entry is a glob pattern.
+func IsWatchPattern(watch string) bool {
+	return strings.ContainsAny(watch, "*?[")
+}
+
 // StacksFromTrees converts a List[*Tree] into a List[*Stack].
 func StacksFromTrees(root string, trees List[*Tree]) (List[*SortableStack], error) {
 	var stacks List[*SortableStack]
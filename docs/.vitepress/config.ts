@@ -268,6 +268,9 @@ export default defineConfig({
           { text: 'run-graph', link: 'cmdline/run-graph' },
           { text: 'run-order', link: 'cmdline/run-order' },
           { text: 'run', link: 'cmdline/run' },
+          { text: 'script run', link: 'cmdline/script-run' },
+          { text: 'stack delete', link: 'cmdline/stack-delete' },
+          { text: 'stack move', link: 'cmdline/stack-move' },
           { text: 'trigger', link: 'cmdline/trigger' },
           { text: 'vendor download', link: 'cmdline/vendor-download' },
           { text: 'version', link: 'cmdline/version' },
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
@@ -577,6 +578,12 @@ func (git *Git) DiffNames(from, to string) ([]string, error) {
 	return removeEmptyLines(strings.Split(diff, "\n")), nil
 }
 
+// ShowFile returns the content of the file defined by path at the given
+// revision. The path is relative to the configuration WorkingDir.
+func (git *Git) ShowFile(rev, path string) (string, error) {
+	return git.exec("cat-file", "blob", rev+":./"+filepath.ToSlash(path))
+}
+
 // NewBranch creates a new branch reference pointing to current HEAD.
 func (git *Git) NewBranch(name string) error {
 	log.Trace().
@@ -742,6 +749,20 @@ func (git *Git) ListUncommitted(dirs ...string) ([]string, error) {
 	return removeEmptyLines(strings.Split(out, "\n")), nil
 }
 
+// ListStaged lists the files with changes staged for the next commit.
+func (git *Git) ListStaged() ([]string, error) {
+	log.Debug().
+		Str("action", "ListStaged()").
+		Str("workingDir", git.config.WorkingDir).
+		Msg("List staged files.")
+	out, err := git.exec("diff", "--cached", "--name-only")
+	if err != nil {
+		return nil, fmt.Errorf("diff: %w", err)
+	}
+
+	return removeEmptyLines(strings.Split(out, "\n")), nil
+}
+
 // ShowCommitMetadata returns common metadata associated with the given object.
 // An object name can be a commit SHA or a symbolic name, i.e. HEAD, branch-name, etc.
 func (git *Git) ShowCommitMetadata(objectName string) (*CommitMetadata, error) {
@@ -795,6 +816,11 @@ func (git *Git) Root() (string, error) {
 	return git.exec("rev-parse", "--show-toplevel")
 }
 
+// GitDir returns the absolute path of the git directory.
+func (git *Git) GitDir() (string, error) {
+	return git.exec("rev-parse", "--absolute-git-dir")
+}
+
 // IsRepository tell if the git wrapper setup is operating in a valid git
 // repository.
 func (git *Git) IsRepository() bool {
@@ -8,6 +8,7 @@ package git_test
 import (
 	"errors"
 	"fmt"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -136,6 +137,36 @@ func TestRevParse(t *testing.T) {
 	assert.EqualStrings(t, CookedCommitID, out, "commit mismatch")
 }
 
+func TestShowFile(t *testing.T) {
+	t.Parallel()
+	repodir := mkOneCommitRepo(t)
+
+	git := test.NewGitWrapper(t, repodir, []string{})
+	test.WriteFile(t, repodir, "README.md", "# Changed")
+
+	out, err := git.ShowFile("main", "README.md")
+	assert.NoError(t, err, "show file failed")
+	assert.EqualStrings(t, "# Test", out, "file content mismatch")
+
+	_, err = git.ShowFile("main", "non-existent.md")
+	assert.Error(t, err, "show file must fail for non-existent files")
+}
+
+func TestGitDir(t *testing.T) {
+	t.Parallel()
+	repodir := mkOneCommitRepo(t)
+
+	git := test.NewGitWrapper(t, repodir, []string{})
+	out, err := git.GitDir()
+	assert.NoError(t, err, "rev-parse failed")
+
+	want, err := filepath.EvalSymlinks(filepath.Join(repodir, ".git"))
+	assert.NoError(t, err)
+	got, err := filepath.EvalSymlinks(out)
+	assert.NoError(t, err)
+	assert.EqualStrings(t, want, got, "git dir mismatch")
+}
+
 func TestClone(t *testing.T) {
 	const (
 		filename = "test.txt"
@@ -8,8 +8,10 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclparse"
@@ -37,6 +39,17 @@ const (
 	StackBlockType = "stack"
 )
 
+// Default grace periods of the terramate.config.run.timeout block.
+const (
+	// DefaultTimeoutInterruptGrace is the default time waited between the
+	// SIGINT and the SIGTERM sent to a timed out command.
+	DefaultTimeoutInterruptGrace = 30 * time.Second
+
+	// DefaultTimeoutTerminateGrace is the default time waited between the
+	// SIGTERM and the SIGKILL sent to a timed out command.
+	DefaultTimeoutTerminateGrace = 10 * time.Second
+)
+
 // Config represents a Terramate configuration.
 type Config struct {
 	Terramate *Terramate
@@ -45,6 +58,7 @@ type Config struct {
 	Vendor    *VendorConfig
 	Asserts   []AssertConfig
 	Generate  GenerateConfig
+	Scripts   []Script
 
 	Imported RawConfig
 
@@ -67,13 +81,86 @@ type AssertConfig struct {
 	Message   hcl.Expression
 }
 
+// Script represents a parsed script block.
+type Script struct {
+	// Range is the range of the entire block definition.
+	Range info.Range
+	// Name of the script.
+	Name string
+	// Description attribute of the script, if any.
+	Description *ast.Attribute
+	// Jobs of the script, in the order they are defined.
+	Jobs []ScriptJob
+}
+
+// ScriptJob represents a parsed job block of a script.
+type ScriptJob struct {
+	// Range is the range of the entire block definition.
+	Range info.Range
+	// Commands attribute is a list of commands, each one a list of strings.
+	Commands *ast.Attribute
+}
+
 // RunConfig represents Terramate run configuration.
 type RunConfig struct {
 	// CheckGenCode enables generated code is up-to-date check on run.
 	CheckGenCode bool
 
+	// InferOrder enables the inference of the order of execution of the
+	// stacks from the Terraform remote states they read.
+	InferOrder bool
+
 	// Env contains environment definitions for run.
 	Env *RunEnv
+
+	// Before is the list of commands executed before the command of each stack.
+	Before *ast.Attribute
+
+	// After is the list of commands executed after the command of each stack.
+	After *ast.Attribute
+
+	// BeforeAll is the list of commands executed once before the whole run.
+	BeforeAll *ast.Attribute
+
+	// AfterAll is the list of commands executed once after the whole run.
+	AfterAll *ast.Attribute
+
+	// Retry contains the configuration for retrying failed stack commands.
+	Retry *RunRetryConfig
+
+	// Timeout contains the configuration for the timeout of stack commands.
+	Timeout *RunTimeoutConfig
+}
+
+// RunRetryConfig represents the retry configuration of failed stack commands.
+type RunRetryConfig struct {
+	// MaxAttempts is the maximum number of times a stack command is executed,
+	// including the first execution.
+	MaxAttempts int
+
+	// Backoff is the time waited before the first retry. The waiting time is
+	// doubled for each subsequent retry.
+	Backoff time.Duration
+
+	// Patterns is the list of regular expressions matched against the stderr
+	// of the failed command. The command is only retried if any of them match.
+	// If empty, all failures are retried.
+	Patterns []string
+}
+
+// RunTimeoutConfig represents the timeout configuration of stack commands.
+type RunTimeoutConfig struct {
+	// Duration is the maximum time a stack command can run. Zero means no
+	// timeout.
+	Duration time.Duration
+
+	// InterruptGrace is the time waited after the SIGINT is sent to the timed
+	// out command before sending a SIGTERM.
+	InterruptGrace time.Duration
+
+	// TerminateGrace is the time waited after the SIGTERM is sent to the timed
+	// out command before sending a SIGKILL.
+	TerminateGrace time.Duration
 }
 
 // RunEnv represents Terramate run environment.
@@ -183,6 +270,16 @@ type Stack struct {
 
 	// Watch is a list of files to be watched for changes.
 	Watch []string
+
+	// Timeout is the maximum time a command can run in the stack. Zero means
+	// the project default is used.
+	Timeout time.Duration
+
+	// EntryRanges has the range of each entry of the after, before, wants
+	// and wanted_by attributes, indexed by the attribute name and the entry.
+	// The entries not defined by a list literal have the range of the whole
+	// attribute expression.
+	EntryRanges map[string]map[string]info.Range
 }
 
 // GenHCLBlock represents a parsed generate_hcl block.
@@ -714,19 +811,41 @@ func (p *TerramateParser) parseStack(stackblock *ast.Block) (*Stack, error) {
 
 		case "after":
 			errs.Append(assignSet(attr.Name, &stack.After, attrVal))
+			p.setEntryRanges(stack, attr, stack.After)
 
 		case "before":
 			errs.Append(assignSet(attr.Name, &stack.Before, attrVal))
+			p.setEntryRanges(stack, attr, stack.Before)
 
 		case "wants":
 			errs.Append(assignSet(attr.Name, &stack.Wants, attrVal))
+			p.setEntryRanges(stack, attr, stack.Wants)
 
 		case "wanted_by":
 			errs.Append(assignSet(attr.Name, &stack.WantedBy, attrVal))
+			p.setEntryRanges(stack, attr, stack.WantedBy)
 
 		case "watch":
 			errs.Append(assignSet(attr.Name, &stack.Watch, attrVal))
 
+		case "timeout":
+			if attrVal.Type() != cty.String {
+				errs.Append(hclAttrErr(attr,
+					"field stack.timeout must be a duration string but given %q",
+					attrVal.Type().FriendlyName(),
+				))
+				continue
+			}
+			timeout, err := time.ParseDuration(attrVal.AsString())
+			if err != nil || timeout <= 0 {
+				errs.Append(hclAttrErr(attr,
+					"field stack.timeout has an invalid duration %q",
+					attrVal.AsString(),
+				))
+				continue
+			}
+			stack.Timeout = timeout
+
 		default:
 			errs.Append(errors.E(
 				attr.NameRange, "unrecognized attribute stack.%q", attr.Name,
@@ -741,6 +860,39 @@ func (p *TerramateParser) parseStack(stackblock *ast.Block) (*Stack, error) {
 	return stack, nil
 }
 
+// setEntryRanges sets the range of each one of the entries of the stack
+// attribute. The entries given as string literals of a list get their own
+// range and the rest get the range of the attribute expression.
+func (p *TerramateParser) setEntryRanges(stack *Stack, attr *hcl.Attribute, entries []string) {
+	if len(entries) == 0 {
+		return
+	}
+
+	ranges := map[string]info.Range{}
+	if tuple, ok := attr.Expr.(*hclsyntax.TupleConsExpr); ok {
+		for _, expr := range tuple.Exprs {
+			val, err := p.evalctx.Eval(expr)
+			if err != nil || val.IsNull() || val.Type() != cty.String {
+				continue
+			}
+			if _, ok := ranges[val.AsString()]; !ok {
+				ranges[val.AsString()] = info.NewRange(p.rootdir, expr.Range())
+			}
+		}
+	}
+
+	for _, entry := range entries {
+		if _, ok := ranges[entry]; !ok {
+			ranges[entry] = info.NewRange(p.rootdir, attr.Expr.Range())
+		}
+	}
+
+	if stack.EntryRanges == nil {
+		stack.EntryRanges = map[string]map[string]info.Range{}
+	}
+	stack.EntryRanges[attr.Name] = ranges
+}
+
 // NewConfig creates a new HCL config with dir as config directory path.
 func NewConfig(dir string) (Config, error) {
 	st, err := os.Stat(dir)
@@ -774,7 +926,8 @@ func (c Config) IsEmpty() bool {
 	return c.Stack == nil && c.Terramate == nil &&
 		c.Vendor == nil && len(c.Asserts) == 0 &&
 		len(c.Globals) == 0 &&
-		len(c.Generate.Files) == 0 && len(c.Generate.HCLs) == 0
+		len(c.Generate.Files) == 0 && len(c.Generate.HCLs) == 0 &&
+		len(c.Scripts) == 0
 }
 
 // HasGlobals tells if the configuration has any globals defined.
@@ -1323,6 +1476,93 @@ func parseAssertConfig(assert *ast.Block) (AssertConfig, error) {
 	return cfg, nil
 }
 
+func parseScriptBlock(block *ast.Block) (Script, error) {
+	errs := errors.L()
+
+	if len(block.Labels) != 1 {
+		errs.Append(errors.E(ErrTerramateSchema, block.LabelRanges(),
+			"script must have a single label but got %v", block.Labels))
+	}
+
+	script := Script{
+		Range: block.Range,
+	}
+	if len(block.Labels) > 0 {
+		script.Name = block.Labels[0]
+	}
+
+	for _, attr := range block.Attributes.SortedList() {
+		attr := attr
+		switch attr.Name {
+		case "description":
+			script.Description = &attr
+		default:
+			errs.Append(errors.E(ErrTerramateSchema, attr.NameRange,
+				"unrecognized attribute %s.%s", block.Type, attr.Name,
+			))
+		}
+	}
+
+	foundJob := false
+	for _, subBlock := range block.Blocks {
+		if subBlock.Type != "job" {
+			errs.Append(errors.E(ErrTerramateSchema, subBlock.DefRange(),
+				"unexpected block %s inside %s", subBlock.Type, block.Type))
+			continue
+		}
+
+		foundJob = true
+		job, err := parseScriptJobBlock(subBlock)
+		if err != nil {
+			errs.Append(err)
+			continue
+		}
+		script.Jobs = append(script.Jobs, job)
+	}
+
+	if !foundJob {
+		errs.Append(errors.E(ErrTerramateSchema, block.Range,
+			"script requires at least one job block"))
+	}
+
+	if err := errs.AsError(); err != nil {
+		return Script{}, err
+	}
+	return script, nil
+}
+
+func parseScriptJobBlock(block *ast.Block) (ScriptJob, error) {
+	errs := errors.L()
+
+	errs.Append(checkNoLabels(block))
+	errs.Append(checkNoBlocks(block))
+
+	job := ScriptJob{
+		Range: block.Range,
+	}
+	for _, attr := range block.Attributes.SortedList() {
+		attr := attr
+		switch attr.Name {
+		case "commands":
+			job.Commands = &attr
+		default:
+			errs.Append(errors.E(ErrTerramateSchema, attr.NameRange,
+				"unrecognized attribute script.job.%s", attr.Name,
+			))
+		}
+	}
+
+	if job.Commands == nil {
+		errs.Append(errors.E(ErrTerramateSchema, block.Range,
+			"script.job.commands is required"))
+	}
+
+	if err := errs.AsError(); err != nil {
+		return ScriptJob{}, err
+	}
+	return job, nil
+}
+
 func parseVendorConfig(cfg *VendorConfig, vendor *ast.Block) error {
 	logger := log.With().
 		Str("action", "hcl.parseVendorConfig()").
@@ -1472,6 +1712,22 @@ func parseRunConfig(runCfg *RunConfig, runBlock *ast.MergedBlock) error {
 
 	errs := errors.L()
 	for _, attr := range runBlock.Attributes.SortedList() {
+		attr := attr
+		switch attr.Name {
+		case "before":
+			runCfg.Before = &attr
+			continue
+		case "after":
+			runCfg.After = &attr
+			continue
+		case "before_all":
+			runCfg.BeforeAll = &attr
+			continue
+		case "after_all":
+			runCfg.AfterAll = &attr
+			continue
+		}
+
 		value, diags := attr.Expr.Value(nil)
 		if diags.HasErrors() {
 			errs.Append(errors.E(diags,
@@ -1492,13 +1748,23 @@ func parseRunConfig(runCfg *RunConfig, runBlock *ast.MergedBlock) error {
 				continue
 			}
 			runCfg.CheckGenCode = value.True()
+		case "infer_order":
+			if value.Type() != cty.Bool {
+				errs.Append(attrErr(attr,
+					"terramate.config.run.infer_order is not a bool but %q",
+					value.Type().FriendlyName(),
+				))
+
+				continue
+			}
+			runCfg.InferOrder = value.True()
 		default:
 			errs.Append(errors.E("unrecognized attribute terramate.config.run.env.%s",
 				attr.Name))
 		}
 	}
 
-	errs.AppendWrap(ErrTerramateSchema, runBlock.ValidateSubBlocks("env"))
+	errs.AppendWrap(ErrTerramateSchema, runBlock.ValidateSubBlocks("env", "retry", "timeout"))
 
 	block, ok := runBlock.Blocks[ast.NewEmptyLabelBlockType("env")]
 	if ok {
@@ -1506,6 +1772,156 @@ func parseRunConfig(runCfg *RunConfig, runBlock *ast.MergedBlock) error {
 		errs.Append(parseRunEnv(runCfg.Env, block))
 	}
 
+	block, ok = runBlock.Blocks[ast.NewEmptyLabelBlockType("retry")]
+	if ok {
+		runCfg.Retry = &RunRetryConfig{
+			MaxAttempts: 1,
+		}
+		errs.Append(parseRunRetry(runCfg.Retry, block))
+	}
+
+	block, ok = runBlock.Blocks[ast.NewEmptyLabelBlockType("timeout")]
+	if ok {
+		runCfg.Timeout = &RunTimeoutConfig{
+			InterruptGrace: DefaultTimeoutInterruptGrace,
+			TerminateGrace: DefaultTimeoutTerminateGrace,
+		}
+		errs.Append(parseRunTimeout(runCfg.Timeout, block))
+	}
+
+	return errs.AsError()
+}
+
+func parseRunTimeout(timeout *RunTimeoutConfig, timeoutBlock *ast.MergedBlock) error {
+	errs := errors.L()
+	errs.AppendWrap(ErrTerramateSchema, timeoutBlock.ValidateSubBlocks())
+
+	for _, attr := range timeoutBlock.Attributes.SortedList() {
+		value, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() {
+			errs.Append(errors.E(diags,
+				"failed to evaluate terramate.config.run.timeout.%s attribute", attr.Name,
+			))
+
+			continue
+		}
+
+		var target *time.Duration
+		switch attr.Name {
+		case "duration":
+			target = &timeout.Duration
+		case "interrupt_grace":
+			target = &timeout.InterruptGrace
+		case "terminate_grace":
+			target = &timeout.TerminateGrace
+		default:
+			errs.Append(errors.E(ErrTerramateSchema, attr.NameRange,
+				"unrecognized attribute terramate.config.run.timeout.%s", attr.Name,
+			))
+
+			continue
+		}
+
+		if value.Type() != cty.String {
+			errs.Append(attrErr(attr,
+				"terramate.config.run.timeout.%s must be a duration string but is %q",
+				attr.Name, value.Type().FriendlyName(),
+			))
+
+			continue
+		}
+		duration, err := time.ParseDuration(value.AsString())
+		if err != nil || duration < 0 {
+			errs.Append(attrErr(attr,
+				"terramate.config.run.timeout.%s has an invalid duration %q",
+				attr.Name, value.AsString(),
+			))
+
+			continue
+		}
+		*target = duration
+	}
+
+	return errs.AsError()
+}
+
+func parseRunRetry(retry *RunRetryConfig, retryBlock *ast.MergedBlock) error {
+	errs := errors.L()
+	errs.AppendWrap(ErrTerramateSchema, retryBlock.ValidateSubBlocks())
+
+	for _, attr := range retryBlock.Attributes.SortedList() {
+		value, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() {
+			errs.Append(errors.E(diags,
+				"failed to evaluate terramate.config.run.retry.%s attribute", attr.Name,
+			))
+
+			continue
+		}
+
+		switch attr.Name {
+		case "max_attempts":
+			if value.Type() != cty.Number || !value.AsBigFloat().IsInt() {
+				errs.Append(attrErr(attr,
+					"terramate.config.run.retry.max_attempts must be an integer but is %q",
+					value.Type().FriendlyName(),
+				))
+
+				continue
+			}
+			attempts, _ := value.AsBigFloat().Int64()
+			if attempts < 1 {
+				errs.Append(attrErr(attr,
+					"terramate.config.run.retry.max_attempts must be greater than zero",
+				))
+
+				continue
+			}
+			retry.MaxAttempts = int(attempts)
+		case "backoff":
+			if value.Type() != cty.String {
+				errs.Append(attrErr(attr,
+					"terramate.config.run.retry.backoff must be a duration string but is %q",
+					value.Type().FriendlyName(),
+				))
+
+				continue
+			}
+			backoff, err := time.ParseDuration(value.AsString())
+			if err != nil || backoff < 0 {
+				errs.Append(attrErr(attr,
+					"terramate.config.run.retry.backoff has an invalid duration %q",
+					value.AsString(),
+				))
+
+				continue
+			}
+			retry.Backoff = backoff
+		case "patterns":
+			patterns, err := ValueAsStringList(value)
+			if err != nil {
+				errs.Append(attrErr(attr,
+					"terramate.config.run.retry.patterns: %s", err,
+				))
+
+				continue
+			}
+			for _, pattern := range patterns {
+				if _, err := regexp.Compile(pattern); err != nil {
+					errs.Append(attrErr(attr,
+						"terramate.config.run.retry.patterns has an invalid regex %q: %s",
+						pattern, err,
+					))
+				}
+			}
+			retry.Patterns = patterns
+		default:
+			errs.Append(errors.E(ErrTerramateSchema, attr.NameRange,
+				"unrecognized attribute terramate.config.run.retry.%s", attr.Name,
+			))
+		}
+	}
+
 	return errs.AsError()
 }
 
@@ -1758,6 +2174,29 @@ func (p *TerramateParser) parseTerramateSchema() (Config, error) {
 			if err == nil {
 				config.Generate.Files = append(config.Generate.Files, genfile)
 			}
+
+		case "script":
+			logger.Trace().Msg("Found \"script\" block")
+
+			script, err := parseScriptBlock(block)
+			if err != nil {
+				errs.Append(err)
+				continue
+			}
+
+			duplicated := false
+			for _, other := range config.Scripts {
+				if other.Name == script.Name {
+					errs.Append(errors.E(errKind, block.DefRange(),
+						"duplicated script %q (first defined at %s)",
+						script.Name, other.Range.String()))
+					duplicated = true
+					break
+				}
+			}
+			if !duplicated {
+				config.Scripts = append(config.Scripts, script)
+			}
 		}
 	}
 
@@ -1828,9 +2267,14 @@ func (p *TerramateParser) checkConfigSanity(_ Config) error {
 	_ = rawconfig.Merge(p.Config)
 
 	errs := errors.L()
+	schemaErrs := errors.L()
 	tmblock := rawconfig.MergedBlocks["terramate"]
 	if tmblock != nil && p.dir != p.rootdir {
 		for _, raworigin := range tmblock.RawOrigins {
+			if isRunConfigOnly(raworigin) {
+				schemaErrs.Append(validateChildRunConfig(raworigin))
+				continue
+			}
 			if filepath.Dir(raworigin.Range.HostPath()) != p.dir {
 				errs.Append(
 					errors.E(ErrUnexpectedTerramate, raworigin.TypeRange,
@@ -1844,12 +2288,57 @@ func (p *TerramateParser) checkConfigSanity(_ Config) error {
 		}
 	}
 	if p.strict {
+		errs.Append(schemaErrs)
 		return errs.AsError()
 	}
 	for _, err := range errs.Errors() {
 		logger.Warn().Err(err).Send()
 	}
-	return nil
+	return schemaErrs.AsError()
+}
+
+// isRunConfigOnly tells if the terramate block only defines the
+// terramate.config.run block, which is allowed in any directory.
+func isRunConfigOnly(tmblock *ast.Block) bool {
+	if len(tmblock.Attributes) > 0 || len(tmblock.Blocks) == 0 {
+		return false
+	}
+	for _, cfgblock := range tmblock.Blocks {
+		if cfgblock.Type != "config" || len(cfgblock.Attributes) > 0 || len(cfgblock.Blocks) == 0 {
+			return false
+		}
+		for _, runblock := range cfgblock.Blocks {
+			if runblock.Type != "run" {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// validateChildRunConfig validates the terramate.config.run blocks of a
+// non-root directory, which can only define the env block and the before and
+// after hooks. The other attributes and blocks only have effect in the project
+// root.
+func validateChildRunConfig(tmblock *ast.Block) error {
+	errs := errors.L()
+	for _, cfgblock := range tmblock.Blocks {
+		for _, runblock := range cfgblock.Blocks {
+			for _, attr := range runblock.Attributes.SortedList() {
+				if attr.Name != "before" && attr.Name != "after" {
+					errs.Append(errors.E(ErrTerramateSchema, attr.NameRange,
+						"terramate.config.run.%s is only allowed in the project root", attr.Name))
+				}
+			}
+			for _, block := range runblock.Blocks {
+				if block.Type != "env" {
+					errs.Append(errors.E(ErrTerramateSchema, block.TypeRange,
+						"terramate.config.run.%s is only allowed in the project root", block.Type))
+				}
+			}
+		}
+	}
+	return errs.AsError()
 }
 
 func validateGlobals(block *ast.MergedBlock) error {
@@ -7,13 +7,16 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
+	hhcl "github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclparse"
 	"github.com/hashicorp/hcl/v2/hclsyntax"
 	"github.com/madlambda/spells/assert"
 	"github.com/terramate-io/terramate/errors"
 	"github.com/terramate-io/terramate/hcl"
 	"github.com/terramate-io/terramate/hcl/ast"
+	"github.com/terramate-io/terramate/test"
 	. "github.com/terramate-io/terramate/test/hclutils"
 )
 
@@ -61,6 +64,14 @@ func TestHCLParserConfigRun(t *testing.T) {
 		}
 	}
 
+	attr := func(exprStr string) *ast.Attribute {
+		return &ast.Attribute{
+			Attribute: &hhcl.Attribute{
+				Expr: test.NewExpr(t, exprStr),
+			},
+		}
+	}
+
 	for _, tc := range []testcase{
 		{
 			name: "empty run",
@@ -289,6 +300,57 @@ func TestHCLParserConfigRun(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "run.infer_order defined",
+			input: []cfgfile{
+				{
+					filename: "cfg.tm",
+					body: `
+						terramate {
+						  config {
+						    run {
+							infer_order = true
+						    }
+						  }
+						}
+					`,
+				},
+			},
+			want: want{
+				config: hcl.Config{
+					Terramate: &hcl.Terramate{
+						Config: &hcl.RootConfig{
+							Run: &hcl.RunConfig{
+								CheckGenCode: true,
+								InferOrder:   true,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "run.infer_order with wrong type fails",
+			input: []cfgfile{
+				{
+					filename: "cfg.tm",
+					body: `
+						terramate {
+						  config {
+						    run {
+							infer_order = "yes"
+						    }
+						  }
+						}
+					`,
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrTerramateSchema),
+				},
+			},
+		},
 		{
 			name: "attrs on run.env in single block/file",
 			input: []cfgfile{
@@ -656,6 +718,446 @@ func TestHCLParserConfigRun(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "run hooks defined",
+			input: []cfgfile{
+				{
+					filename: "cfg.tm",
+					body: `
+						terramate {
+						  config {
+						    run {
+						      before     = [["echo", "before"]]
+						      after      = [["echo", global.after]]
+						      before_all = [["echo", "before_all"]]
+						      after_all  = [["echo", "after_all"]]
+						    }
+						  }
+						}
+					`,
+				},
+			},
+			want: want{
+				config: hcl.Config{
+					Terramate: &hcl.Terramate{
+						Config: &hcl.RootConfig{
+							Run: &hcl.RunConfig{
+								CheckGenCode: true,
+								Before:       attr(`[["echo", "before"]]`),
+								After:        attr(`[["echo", global.after]]`),
+								BeforeAll:    attr(`[["echo", "before_all"]]`),
+								AfterAll:     attr(`[["echo", "after_all"]]`),
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "run.retry defined",
+			input: []cfgfile{
+				{
+					filename: "cfg.tm",
+					body: `
+						terramate {
+						  config {
+						    run {
+						      retry {
+						        max_attempts = 3
+						        backoff      = "10s"
+						        patterns     = ["rate limit", "state lock"]
+						      }
+						    }
+						  }
+						}
+					`,
+				},
+			},
+			want: want{
+				config: hcl.Config{
+					Terramate: &hcl.Terramate{
+						Config: &hcl.RootConfig{
+							Run: &hcl.RunConfig{
+								CheckGenCode: true,
+								Retry: &hcl.RunRetryConfig{
+									MaxAttempts: 3,
+									Backoff:     10 * time.Second,
+									Patterns:    []string{"rate limit", "state lock"},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "empty run.retry",
+			input: []cfgfile{
+				{
+					filename: "cfg.tm",
+					body: `
+						terramate {
+						  config {
+						    run {
+						      retry {
+						      }
+						    }
+						  }
+						}
+					`,
+				},
+			},
+			want: want{
+				config: hcl.Config{
+					Terramate: &hcl.Terramate{
+						Config: &hcl.RootConfig{
+							Run: &hcl.RunConfig{
+								CheckGenCode: true,
+								Retry: &hcl.RunRetryConfig{
+									MaxAttempts: 1,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "invalid run.retry attributes fail",
+			input: []cfgfile{
+				{
+					filename: "cfg.tm",
+					body: `
+						terramate {
+						  config {
+						    run {
+						      retry {
+						        max_attempts = 0
+						        backoff      = "soon"
+						        patterns     = ["("]
+						        unknown      = true
+						      }
+						    }
+						  }
+						}
+					`,
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrTerramateSchema),
+					errors.E(hcl.ErrTerramateSchema),
+					errors.E(hcl.ErrTerramateSchema),
+					errors.E(hcl.ErrTerramateSchema),
+				},
+			},
+		},
+		{
+			name: "run.timeout defined",
+			input: []cfgfile{
+				{
+					filename: "cfg.tm",
+					body: `
+						terramate {
+						  config {
+						    run {
+						      timeout {
+						        duration        = "1h"
+						        interrupt_grace = "1m"
+						        terminate_grace = "5s"
+						      }
+						    }
+						  }
+						}
+					`,
+				},
+			},
+			want: want{
+				config: hcl.Config{
+					Terramate: &hcl.Terramate{
+						Config: &hcl.RootConfig{
+							Run: &hcl.RunConfig{
+								CheckGenCode: true,
+								Timeout: &hcl.RunTimeoutConfig{
+									Duration:       time.Hour,
+									InterruptGrace: time.Minute,
+									TerminateGrace: 5 * time.Second,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "run.timeout with default grace periods",
+			input: []cfgfile{
+				{
+					filename: "cfg.tm",
+					body: `
+						terramate {
+						  config {
+						    run {
+						      timeout {
+						        duration = "30m"
+						      }
+						    }
+						  }
+						}
+					`,
+				},
+			},
+			want: want{
+				config: hcl.Config{
+					Terramate: &hcl.Terramate{
+						Config: &hcl.RootConfig{
+							Run: &hcl.RunConfig{
+								CheckGenCode: true,
+								Timeout: &hcl.RunTimeoutConfig{
+									Duration:       30 * time.Minute,
+									InterruptGrace: hcl.DefaultTimeoutInterruptGrace,
+									TerminateGrace: hcl.DefaultTimeoutTerminateGrace,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "invalid run.timeout attributes fail",
+			input: []cfgfile{
+				{
+					filename: "cfg.tm",
+					body: `
+						terramate {
+						  config {
+						    run {
+						      timeout {
+						        duration        = 10
+						        interrupt_grace = "-1s"
+						        terminate_grace = "soon"
+						        unknown         = "1s"
+						      }
+						    }
+						  }
+						}
+					`,
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrTerramateSchema),
+					errors.E(hcl.ErrTerramateSchema),
+					errors.E(hcl.ErrTerramateSchema),
+					errors.E(hcl.ErrTerramateSchema),
+				},
+			},
+		},
+		{
+			name:     "run config in non-root directory",
+			parsedir: "dir",
+			input: []cfgfile{
+				{
+					filename: "dir/cfg.tm",
+					body: `
+						terramate {
+						  config {
+						    run {
+						      before = [["echo", "before"]]
+						    }
+						  }
+						}
+					`,
+				},
+			},
+			want: want{
+				config: hcl.Config{
+					Terramate: &hcl.Terramate{
+						Config: &hcl.RootConfig{
+							Run: &hcl.RunConfig{
+								CheckGenCode: true,
+								Before:       attr(`[["echo", "before"]]`),
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:     "run config with other config in non-root directory fails",
+			parsedir: "dir",
+			input: []cfgfile{
+				{
+					filename: "dir/cfg.tm",
+					body: `
+						terramate {
+						  config {
+						    run {
+						      before = [["echo", "before"]]
+						    }
+						    git {
+						      default_branch = "trunk"
+						    }
+						  }
+						}
+					`,
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrUnexpectedTerramate),
+				},
+			},
+		},
+		{
+			name:     "run check_gen_code in non-root directory fails",
+			parsedir: "dir",
+			input: []cfgfile{
+				{
+					filename: "dir/cfg.tm",
+					body: `
+						terramate {
+						  config {
+						    run {
+						      before = [["echo", "before"]]
+						      check_gen_code = false
+						    }
+						  }
+						}
+					`,
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrTerramateSchema),
+				},
+			},
+		},
+		{
+			name:     "run infer_order in non-root directory fails",
+			parsedir: "dir",
+			input: []cfgfile{
+				{
+					filename: "dir/cfg.tm",
+					body: `
+						terramate {
+						  config {
+						    run {
+						      before = [["echo", "before"]]
+						      infer_order = true
+						    }
+						  }
+						}
+					`,
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrTerramateSchema),
+				},
+			},
+		},
+		{
+			name:     "run before_all in non-root directory fails",
+			parsedir: "dir",
+			input: []cfgfile{
+				{
+					filename: "dir/cfg.tm",
+					body: `
+						terramate {
+						  config {
+						    run {
+						      before = [["echo", "before"]]
+						      before_all = [["echo", "before all"]]
+						    }
+						  }
+						}
+					`,
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrTerramateSchema),
+				},
+			},
+		},
+		{
+			name:     "run after_all in non-root directory fails",
+			parsedir: "dir",
+			input: []cfgfile{
+				{
+					filename: "dir/cfg.tm",
+					body: `
+						terramate {
+						  config {
+						    run {
+						      before = [["echo", "before"]]
+						      after_all = [["echo", "after all"]]
+						    }
+						  }
+						}
+					`,
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrTerramateSchema),
+				},
+			},
+		},
+		{
+			name:     "run retry in non-root directory fails",
+			parsedir: "dir",
+			input: []cfgfile{
+				{
+					filename: "dir/cfg.tm",
+					body: `
+						terramate {
+						  config {
+						    run {
+						      before = [["echo", "before"]]
+						      retry {
+						        max_attempts = 3
+						      }
+						    }
+						  }
+						}
+					`,
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrTerramateSchema),
+				},
+			},
+		},
+		{
+			name:     "run timeout in non-root directory fails",
+			parsedir: "dir",
+			input: []cfgfile{
+				{
+					filename: "dir/cfg.tm",
+					body: `
+						terramate {
+						  config {
+						    run {
+						      before = [["echo", "before"]]
+						      timeout {
+						        duration = "1s"
+						      }
+						    }
+						  }
+						}
+					`,
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrTerramateSchema),
+				},
+			},
+		},
 	} {
 		testParser(t, tc)
 	}
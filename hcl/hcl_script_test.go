@@ -0,0 +1,244 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package hcl_test
+
+import (
+	"testing"
+
+	hhcl "github.com/hashicorp/hcl/v2"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/hcl"
+	"github.com/terramate-io/terramate/hcl/ast"
+	"github.com/terramate-io/terramate/test"
+	. "github.com/terramate-io/terramate/test/hclwrite/hclutils"
+)
+
+func TestHCLParserScript(t *testing.T) {
+	attr := func(exprStr string) *ast.Attribute {
+		return &ast.Attribute{
+			Attribute: &hhcl.Attribute{
+				Expr: test.NewExpr(t, exprStr),
+			},
+		}
+	}
+
+	tcases := []testcase{
+		{
+			name: "single script with single job",
+			input: []cfgfile{
+				{
+					filename: "script.tm",
+					body: Script(
+						Labels("deploy"),
+						Job(
+							Expr("commands", `[["terraform", "init"], ["terraform", "apply"]]`),
+						),
+					).String(),
+				},
+			},
+			want: want{
+				config: hcl.Config{
+					Scripts: []hcl.Script{
+						{
+							Name: "deploy",
+							Jobs: []hcl.ScriptJob{
+								{
+									Commands: attr(`[["terraform", "init"], ["terraform", "apply"]]`),
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "script with description and multiple jobs",
+			input: []cfgfile{
+				{
+					filename: "script.tm",
+					body: Script(
+						Labels("deploy"),
+						Str("description", "deploy the stack"),
+						Job(
+							Expr("commands", `[["terraform", "init"]]`),
+						),
+						Job(
+							Expr("commands", `[["terraform", "plan", "-out", global.planfile]]`),
+						),
+					).String(),
+				},
+			},
+			want: want{
+				config: hcl.Config{
+					Scripts: []hcl.Script{
+						{
+							Name:        "deploy",
+							Description: attr(`"deploy the stack"`),
+							Jobs: []hcl.ScriptJob{
+								{
+									Commands: attr(`[["terraform", "init"]]`),
+								},
+								{
+									Commands: attr(`[["terraform", "plan", "-out", global.planfile]]`),
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "multiple scripts on multiple files",
+			input: []cfgfile{
+				{
+					filename: "script.tm",
+					body: Script(
+						Labels("init"),
+						Job(
+							Expr("commands", `[["terraform", "init"]]`),
+						),
+					).String(),
+				},
+				{
+					filename: "script2.tm",
+					body: Script(
+						Labels("plan"),
+						Job(
+							Expr("commands", `[["terraform", "plan"]]`),
+						),
+					).String(),
+				},
+			},
+			want: want{
+				config: hcl.Config{
+					Scripts: []hcl.Script{
+						{
+							Name: "init",
+							Jobs: []hcl.ScriptJob{
+								{
+									Commands: attr(`[["terraform", "init"]]`),
+								},
+							},
+						},
+						{
+							Name: "plan",
+							Jobs: []hcl.ScriptJob{
+								{
+									Commands: attr(`[["terraform", "plan"]]`),
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "duplicated script name fails",
+			input: []cfgfile{
+				{
+					filename: "script.tm",
+					body: Script(
+						Labels("deploy"),
+						Job(
+							Expr("commands", `[["terraform", "init"]]`),
+						),
+					).String(),
+				},
+				{
+					filename: "script2.tm",
+					body: Script(
+						Labels("deploy"),
+						Job(
+							Expr("commands", `[["terraform", "apply"]]`),
+						),
+					).String(),
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrTerramateSchema),
+				},
+			},
+		},
+		{
+			name: "script without label fails",
+			input: []cfgfile{
+				{
+					filename: "script.tm",
+					body: Script(
+						Job(
+							Expr("commands", `[["terraform", "init"]]`),
+						),
+					).String(),
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrTerramateSchema),
+				},
+			},
+		},
+		{
+			name: "script without jobs fails",
+			input: []cfgfile{
+				{
+					filename: "script.tm",
+					body: Script(
+						Labels("deploy"),
+					).String(),
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrTerramateSchema),
+				},
+			},
+		},
+		{
+			name: "job without commands fails",
+			input: []cfgfile{
+				{
+					filename: "script.tm",
+					body: Script(
+						Labels("deploy"),
+						Job(),
+					).String(),
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrTerramateSchema),
+				},
+			},
+		},
+		{
+			name: "unknown attributes and blocks fail",
+			input: []cfgfile{
+				{
+					filename: "script.tm",
+					body: Script(
+						Labels("deploy"),
+						Expr("oopsie", "unknown"),
+						Block("something"),
+						Job(
+							Expr("commands", `[["terraform", "init"]]`),
+							Expr("command", `["terraform", "init"]`),
+						),
+					).String(),
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrTerramateSchema),
+					errors.E(hcl.ErrTerramateSchema),
+					errors.E(hcl.ErrTerramateSchema),
+				},
+			},
+		},
+	}
+
+	for _, tc := range tcases {
+		testParser(t, tc)
+	}
+}
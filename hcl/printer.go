@@ -99,6 +99,10 @@ func PrintConfig(w io.Writer, cfg Config) error {
 			stackBody.SetAttributeValue("watch", cty.SetVal(listToValue(stack.Watch)))
 		}
 
+		if stack.Timeout > 0 {
+			stackBody.SetAttributeValue("timeout", cty.StringVal(stack.Timeout.String()))
+		}
+
 		if stack.ID != "" {
 			stackBody.SetAttributeValue("id", cty.StringVal(stack.ID))
 		}
@@ -45,6 +45,7 @@ func NewTopLevelRawConfig() RawConfig {
 		"generate_file": (*RawConfig).addBlock,
 		"generate_hcl":  (*RawConfig).addBlock,
 		"assert":        (*RawConfig).addBlock,
+		"script":        (*RawConfig).addBlock,
 		"import":        func(r *RawConfig, b *ast.Block) error { return nil },
 	})
 }
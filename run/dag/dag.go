@@ -13,6 +13,7 @@ import (
 
 	"github.com/rs/zerolog/log"
 	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/hcl/info"
 )
 
 type (
@@ -26,6 +27,12 @@ type (
 		values map[ID]interface{}
 		cycles map[ID]bool
 
+		// edgeKinds is a map of descendantID -> ancestorID -> []kind
+		edgeKinds map[ID]map[ID][]string
+
+		// edgeRanges is a map of descendantID -> ancestorID -> []range
+		edgeRanges map[ID]map[ID][]info.Range
+
 		validated bool
 	}
 
@@ -44,8 +51,10 @@ const (
 // New creates a new empty Directed-Acyclic-Graph.
 func New() *DAG {
 	return &DAG{
-		dag:    make(map[ID][]ID),
-		values: make(map[ID]interface{}),
+		dag:        make(map[ID][]ID),
+		values:     make(map[ID]interface{}),
+		edgeKinds:  make(map[ID]map[ID][]string),
+		edgeRanges: make(map[ID]map[ID][]info.Range),
 	}
 }
 
@@ -118,6 +127,52 @@ func (d *DAG) addAncestor(node, ancestor ID) {
 	d.dag[node] = nodeAncestors
 }
 
+// AddEdgeKind records the kind of the edge between the node and its ancestor.
+// The kinds are informative only and they don't change the DAG semantics.
+// An edge can have multiple kinds.
+func (d *DAG) AddEdgeKind(node, ancestor ID, kind string) {
+	kinds, ok := d.edgeKinds[node]
+	if !ok {
+		kinds = make(map[ID][]string)
+		d.edgeKinds[node] = kinds
+	}
+	for _, k := range kinds[ancestor] {
+		if k == kind {
+			return
+		}
+	}
+	kinds[ancestor] = append(kinds[ancestor], kind)
+	sort.Strings(kinds[ancestor])
+}
+
+// EdgeKinds returns the sorted kinds of the edge between the node and its
+// ancestor.
+func (d *DAG) EdgeKinds(node, ancestor ID) []string {
+	return d.edgeKinds[node][ancestor]
+}
+
+// AddEdgeRange records the range of the configuration which defined the edge
+// between the node and its ancestor. An edge can be defined in multiple places.
+func (d *DAG) AddEdgeRange(node, ancestor ID, r info.Range) {
+	ranges, ok := d.edgeRanges[node]
+	if !ok {
+		ranges = make(map[ID][]info.Range)
+		d.edgeRanges[node] = ranges
+	}
+	for _, other := range ranges[ancestor] {
+		if other == r {
+			return
+		}
+	}
+	ranges[ancestor] = append(ranges[ancestor], r)
+}
+
+// EdgeRanges returns the ranges of the configuration which defined the edge
+// between the node and its ancestor, in the order they were added.
+func (d *DAG) EdgeRanges(node, ancestor ID) []info.Range {
+	return d.edgeRanges[node][ancestor]
+}
+
 // Validate the DAG looking for cycles.
 func (d *DAG) Validate() (reason string, err error) {
 	d.cycles = make(map[ID]bool)
@@ -180,6 +235,128 @@ func (d *DAG) hasCycle(branch []ID, children []ID, reason string) (bool, string)
 	return false, ""
 }
 
+// Cycles returns a cycle of each strongly connected component of the graph
+// which has cycles, so every group of nodes depending on each other is
+// reported once, whatever the number of cycles inside it. Each cycle is a
+// list of node ids where each node is a descendant of the next one and the
+// last node is a descendant of the first one. The cycles are the shortest
+// ones starting at the lowest id of their component and they are sorted.
+func (d *DAG) Cycles() [][]ID {
+	var cycles [][]ID
+	for _, component := range d.stronglyConnectedComponents() {
+		if cycle := d.shortestCycle(component); cycle != nil {
+			cycles = append(cycles, cycle)
+		}
+	}
+	sort.Slice(cycles, func(i, j int) bool {
+		return cycles[i][0] < cycles[j][0]
+	})
+	return cycles
+}
+
+// stronglyConnectedComponents returns the strongly connected components of
+// the graph, using the Tarjan's algorithm. The ids of each component are
+// sorted.
+func (d *DAG) stronglyConnectedComponents() [][]ID {
+	var (
+		index      int
+		indexes    = map[ID]int{}
+		lowlinks   = map[ID]int{}
+		onStack    = Visited{}
+		stack      []ID
+		components [][]ID
+	)
+
+	var connect func(id ID)
+	connect = func(id ID) {
+		indexes[id] = index
+		lowlinks[id] = index
+		index++
+		stack = append(stack, id)
+		onStack[id] = struct{}{}
+
+		for _, ancestor := range sortedIds(d.dag[id]) {
+			if _, ok := indexes[ancestor]; !ok {
+				connect(ancestor)
+				if lowlinks[ancestor] < lowlinks[id] {
+					lowlinks[id] = lowlinks[ancestor]
+				}
+			} else if _, ok := onStack[ancestor]; ok {
+				if indexes[ancestor] < lowlinks[id] {
+					lowlinks[id] = indexes[ancestor]
+				}
+			}
+		}
+
+		if lowlinks[id] != indexes[id] {
+			return
+		}
+
+		var component idList
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			delete(onStack, top)
+			component = append(component, top)
+			if top == id {
+				break
+			}
+		}
+		sort.Sort(component)
+		components = append(components, component)
+	}
+
+	for _, id := range d.IDs() {
+		if _, ok := indexes[id]; !ok {
+			connect(id)
+		}
+	}
+	return components
+}
+
+// shortestCycle returns the shortest cycle inside the strongly connected
+// component starting at its lowest id, or nil if the component has no cycle,
+// which happens when it has a single node without an edge to itself.
+func (d *DAG) shortestCycle(component []ID) []ID {
+	inComponent := Visited{}
+	for _, id := range component {
+		inComponent[id] = struct{}{}
+	}
+
+	// breadth-first search from the start node, following the edges inside
+	// the component, until the start node is reached again.
+	start := component[0]
+	parents := map[ID]ID{}
+	queue := []ID{start}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+
+		for _, ancestor := range sortedIds(d.dag[id]) {
+			if ancestor == start {
+				var cycle []ID
+				for node := id; node != start; node = parents[node] {
+					cycle = append(cycle, node)
+				}
+				cycle = append(cycle, start)
+				for i, j := 0, len(cycle)-1; i < j; i, j = i+1, j-1 {
+					cycle[i], cycle[j] = cycle[j], cycle[i]
+				}
+				return cycle
+			}
+			if _, ok := inComponent[ancestor]; !ok {
+				continue
+			}
+			if _, ok := parents[ancestor]; ok {
+				continue
+			}
+			parents[ancestor] = id
+			queue = append(queue, ancestor)
+		}
+	}
+	return nil
+}
+
 // IDs returns the sorted list of node ids.
 func (d *DAG) IDs() []ID {
 	idlist := make(idList, 0, len(d.dag))
@@ -4,6 +4,7 @@
 package dag_test
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/madlambda/spells/assert"
@@ -299,6 +300,131 @@ func TestValidatedDAG(t *testing.T) {
 	}
 }
 
+func TestDAGEdgeKinds(t *testing.T) {
+	d := dag.New()
+	assert.NoError(t, d.AddNode("A", nil, []dag.ID{"B"}, nil))
+	assert.NoError(t, d.AddNode("B", nil, nil, []dag.ID{"A"}))
+
+	d.AddEdgeKind("B", "A", "before")
+	d.AddEdgeKind("B", "A", "after")
+	d.AddEdgeKind("B", "A", "after")
+
+	kinds := d.EdgeKinds("B", "A")
+	assert.EqualInts(t, 2, len(kinds), "kinds: %v", kinds)
+	assert.EqualStrings(t, "after", kinds[0])
+	assert.EqualStrings(t, "before", kinds[1])
+
+	assert.EqualInts(t, 0, len(d.EdgeKinds("A", "B")))
+}
+
+func TestDAGCycles(t *testing.T) {
+	type cyclesTestcase struct {
+		name   string
+		nodes  map[string]node
+		cycles [][]dag.ID
+	}
+
+	testcases := []cyclesTestcase{
+		{
+			name: "no cycles",
+			nodes: map[string]node{
+				"A": {
+					ancestors: []dag.ID{"B", "C"},
+				},
+				"B": {
+					ancestors: []dag.ID{"C"},
+				},
+			},
+		},
+		{
+			name: "self cycle",
+			nodes: map[string]node{
+				"A": {
+					ancestors: []dag.ID{"A"},
+				},
+			},
+			cycles: [][]dag.ID{{"A"}},
+		},
+		{
+			name: "independent cycles",
+			nodes: map[string]node{
+				"A": {
+					ancestors: []dag.ID{"B"},
+				},
+				"B": {
+					ancestors: []dag.ID{"A"},
+				},
+				"C": {
+					ancestors:   []dag.ID{"D"},
+					descendants: []dag.ID{"E"},
+				},
+				"E": {
+					descendants: []dag.ID{"D"},
+				},
+			},
+			cycles: [][]dag.ID{
+				{"A", "B"},
+				{"C", "D", "E"},
+			},
+		},
+		{
+			name: "cycles sharing nodes",
+			nodes: map[string]node{
+				"A": {
+					ancestors: []dag.ID{"B", "C"},
+				},
+				"B": {
+					ancestors: []dag.ID{"A"},
+				},
+				"C": {
+					ancestors: []dag.ID{"A", "B"},
+				},
+			},
+			cycles: [][]dag.ID{
+				{"A", "B"},
+			},
+		},
+		{
+			name:   "fully connected nodes",
+			nodes:  fullyConnected(20),
+			cycles: [][]dag.ID{{"N00", "N01"}},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			d := dag.New()
+			for id, v := range tc.nodes {
+				assert.NoError(t, d.AddNode(dag.ID(id), nil, v.descendants, v.ancestors))
+			}
+
+			cycles := d.Cycles()
+			assert.EqualInts(t, len(tc.cycles), len(cycles), "cycles: %v", cycles)
+			for i, want := range tc.cycles {
+				assertOrder(t, want, cycles[i])
+			}
+		})
+	}
+}
+
+func fullyConnected(n int) map[string]node {
+	ids := make([]dag.ID, n)
+	for i := range ids {
+		ids[i] = dag.ID(fmt.Sprintf("N%02d", i))
+	}
+	nodes := map[string]node{}
+	for _, id := range ids {
+		var ancestors []dag.ID
+		for _, other := range ids {
+			if other != id {
+				ancestors = append(ancestors, other)
+			}
+		}
+		nodes[string(id)] = node{ancestors: ancestors}
+	}
+	return nodes
+}
+
 func assertOrder(t *testing.T, want, got []dag.ID) {
 	t.Helper()
 	assert.EqualInts(t, len(want), len(got), "length mismatch")
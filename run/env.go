@@ -5,14 +5,18 @@ package run
 
 import (
 	"os"
+	"sort"
 	"strings"
 
 	"github.com/terramate-io/terramate/config"
 	"github.com/terramate-io/terramate/errors"
 	"github.com/terramate-io/terramate/globals"
+	"github.com/terramate-io/terramate/hcl/ast"
 	"github.com/terramate-io/terramate/hcl/eval"
+	"github.com/terramate-io/terramate/project"
 	"github.com/terramate-io/terramate/stdlib"
 
+	hhcl "github.com/hashicorp/hcl/v2"
 	"github.com/rs/zerolog/log"
 	"github.com/zclconf/go-cty/cty"
 )
@@ -38,6 +42,10 @@ type EnvVars []string
 // LoadEnv will load environment variables to be exported when running any command
 // inside the given stack. The order of the env vars is guaranteed to be the same
 // and is ordered lexicographically.
+// The terramate.config.run.env blocks can be defined in any directory and are
+// merged from the root directory down to the stack directory, where variables
+// defined closer to the stack override the ones defined in parent directories.
+// A variable assigned to `unset` is not loaded, see [UnsetEnv].
 func LoadEnv(root *config.Root, st *config.Stack) (EnvVars, error) {
 	logger := log.With().
 		Str("action", "run.Env()").
@@ -47,7 +55,8 @@ func LoadEnv(root *config.Root, st *config.Stack) (EnvVars, error) {
 
 	logger.Trace().Msg("checking if we have run env config")
 
-	if !root.Tree().Node.HasRunEnv() {
+	attrs, _, found := loadEnvAttrs(root, st.Dir)
+	if !found {
 		logger.Trace().Msg("no run env config found, nothing to do")
 		return nil, nil
 	}
@@ -68,9 +77,7 @@ func LoadEnv(root *config.Root, st *config.Stack) (EnvVars, error) {
 
 	envVars := EnvVars{}
 
-	attrs := root.Tree().Node.Terramate.Config.Run.Env.Attributes.SortedList()
-
-	for _, attr := range attrs {
+	for _, attr := range attrs.SortedList() {
 		logger = logger.With().
 			Str("attribute", attr.Name).
 			Logger()
@@ -100,6 +107,64 @@ func LoadEnv(root *config.Root, st *config.Stack) (EnvVars, error) {
 	return envVars, nil
 }
 
+// UnsetEnv returns the names of the environment variables assigned to `unset`
+// in the terramate.config.run.env blocks of the stack. These variables must be
+// removed from the environment inherited by the commands executed in the
+// stack. The names are ordered lexicographically.
+func UnsetEnv(root *config.Root, st *config.Stack) []string {
+	_, unset, _ := loadEnvAttrs(root, st.Dir)
+	return unset
+}
+
+// loadEnvAttrs loads the env attributes defined from the root directory down to
+// the given dir, with the ones closer to the dir taking precedence, and the
+// names of the unset variables. It returns false if no env block is defined in
+// any of the directories.
+func loadEnvAttrs(root *config.Root, dir project.Path) (ast.Attributes, []string, bool) {
+	var envs []ast.Attributes
+	for {
+		if tree, ok := root.Lookup(dir); ok && tree.Node.HasRunEnv() {
+			envs = append(envs, tree.Node.Terramate.Config.Run.Env.Attributes)
+		}
+
+		parent := dir.Dir()
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	if len(envs) == 0 {
+		return nil, nil, false
+	}
+
+	attrs := ast.Attributes{}
+	unset := map[string]bool{}
+	for i := len(envs) - 1; i >= 0; i-- {
+		for name, attr := range envs[i] {
+			if isUnset(attr) {
+				delete(attrs, name)
+				unset[name] = true
+				continue
+			}
+			attrs[name] = attr
+			delete(unset, name)
+		}
+	}
+
+	var unsetNames []string
+	for name := range unset {
+		unsetNames = append(unsetNames, name)
+	}
+	sort.Strings(unsetNames)
+	return attrs, unsetNames, true
+}
+
+func isUnset(attr ast.Attribute) bool {
+	traversal, diags := hhcl.AbsTraversalForExpr(attr.Expr)
+	return !diags.HasErrors() && len(traversal) == 1 && traversal.RootName() == "unset"
+}
+
 func getEnv(key string, environ []string) (string, bool) {
 	for i := len(environ) - 1; i >= 0; i-- {
 		env := environ[i]
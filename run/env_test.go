@@ -31,6 +31,7 @@ func TestLoadRunEnv(t *testing.T) {
 		}
 		result struct {
 			env    run.EnvVars
+			unset  []string
 			enverr error
 			cfgerr error
 		}
@@ -132,6 +133,96 @@ func TestLoadRunEnv(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "stacks with env merged from parent directories",
+			layout: []string{
+				"s:accounts/prod/stack-1",
+				"s:accounts/prod/stack-2",
+				"s:accounts/dev/stack",
+				"s:other",
+			},
+			configs: []hclconfig{
+				{
+					path: "/",
+					add: runEnvCfg(
+						Str("AWS_PROFILE", "default"),
+						Str("TF_LOG", "info"),
+					),
+				},
+				{
+					path: "/accounts/prod",
+					add: runEnvCfg(
+						Str("AWS_PROFILE", "prod"),
+						Str("PROD_ONLY", "yes"),
+					),
+				},
+				{
+					path: "/accounts/prod/stack-2",
+					add: runEnvCfg(
+						Expr("PROD_ONLY", "unset"),
+						Expr("TF_LOG", "unset"),
+						Expr("STACK", "terramate.stack.name"),
+					),
+				},
+				{
+					path: "/accounts/dev",
+					add: runEnvCfg(
+						Str("AWS_PROFILE", "dev"),
+					),
+				},
+			},
+			want: map[string]result{
+				"accounts/prod/stack-1": {
+					env: run.EnvVars{
+						"AWS_PROFILE=prod",
+						"PROD_ONLY=yes",
+						"TF_LOG=info",
+					},
+				},
+				"accounts/prod/stack-2": {
+					env: run.EnvVars{
+						"AWS_PROFILE=prod",
+						"STACK=stack-2",
+					},
+					unset: []string{"PROD_ONLY", "TF_LOG"},
+				},
+				"accounts/dev/stack": {
+					env: run.EnvVars{
+						"AWS_PROFILE=dev",
+						"TF_LOG=info",
+					},
+				},
+				"other": {
+					env: run.EnvVars{
+						"AWS_PROFILE=default",
+						"TF_LOG=info",
+					},
+				},
+			},
+		},
+		{
+			name: "stacks with env defined only in a subdirectory",
+			layout: []string{
+				"s:accounts/prod/stack",
+				"s:other",
+			},
+			configs: []hclconfig{
+				{
+					path: "/accounts/prod",
+					add: runEnvCfg(
+						Str("AWS_PROFILE", "prod"),
+					),
+				},
+			},
+			want: map[string]result{
+				"accounts/prod/stack": {
+					env: run.EnvVars{
+						"AWS_PROFILE=prod",
+					},
+				},
+				"other": {},
+			},
+		},
 		{
 			name: "fails on invalid root config",
 			layout: []string{
@@ -244,6 +335,7 @@ func TestLoadRunEnv(t *testing.T) {
 				gotvars, err := run.LoadEnv(root, stack)
 				errorstest.Assert(t, err, wantres.enverr)
 				test.AssertDiff(t, gotvars, wantres.env)
+				test.AssertDiff(t, run.UnsetEnv(root, stack), wantres.unset)
 			}
 		})
 	}
@@ -0,0 +1,145 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package run
+
+import (
+	"os"
+
+	"github.com/rs/zerolog/log"
+	"github.com/terramate-io/terramate/config"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/globals"
+	"github.com/terramate-io/terramate/hcl"
+	"github.com/terramate-io/terramate/hcl/ast"
+	"github.com/terramate-io/terramate/hcl/eval"
+	"github.com/terramate-io/terramate/project"
+	"github.com/terramate-io/terramate/stdlib"
+)
+
+// ErrHooks indicates that an error happened while loading the
+// terramate.config.run hooks.
+const ErrHooks errors.Kind = "loading terramate.config.run hooks"
+
+// Hooks represents the commands executed before and after the run
+// of a command. Each command is the program name followed by its arguments.
+type Hooks struct {
+	Before [][]string
+	After  [][]string
+}
+
+// LoadStackHooks loads the terramate.config.run.before and
+// terramate.config.run.after hooks of the given stack.
+// The hooks can be defined in any directory and the definition closest to the
+// stack directory overrides the ones defined in the parent directories.
+func LoadStackHooks(root *config.Root, st *config.Stack) (Hooks, error) {
+	logger := log.With().
+		Str("action", "run.LoadStackHooks()").
+		Str("root", root.HostDir()).
+		Stringer("stack", st).
+		Logger()
+
+	before := lookupHook(root, st.Dir, func(cfg *hcl.RunConfig) *ast.Attribute {
+		return cfg.Before
+	})
+	after := lookupHook(root, st.Dir, func(cfg *hcl.RunConfig) *ast.Attribute {
+		return cfg.After
+	})
+
+	if before == nil && after == nil {
+		logger.Trace().Msg("no hooks defined for the stack")
+		return Hooks{}, nil
+	}
+
+	logger.Trace().Msg("loading globals")
+
+	globalsReport := globals.ForStack(root, st)
+	if err := globalsReport.AsError(); err != nil {
+		return Hooks{}, errors.E(ErrHooks, err)
+	}
+
+	evalctx := eval.NewContext(stdlib.Functions(st.HostDir(root)))
+	runtime := root.Runtime()
+	runtime.Merge(st.RuntimeValues(root))
+	evalctx.SetNamespace("terramate", runtime)
+	evalctx.SetNamespace("global", globalsReport.Globals.AsValueMap())
+	evalctx.SetEnv(os.Environ())
+
+	return evalHooks(evalctx, before, after, "before", "after")
+}
+
+// LoadRunHooks loads the terramate.config.run.before_all and
+// terramate.config.run.after_all hooks, which are executed once per run.
+// These hooks are only loaded from the project root configuration.
+func LoadRunHooks(root *config.Root) (Hooks, error) {
+	logger := log.With().
+		Str("action", "run.LoadRunHooks()").
+		Str("root", root.HostDir()).
+		Logger()
+
+	runCfg := runConfig(root.Tree())
+	if runCfg == nil || (runCfg.BeforeAll == nil && runCfg.AfterAll == nil) {
+		logger.Trace().Msg("no run hooks defined")
+		return Hooks{}, nil
+	}
+
+	evalctx := eval.NewContext(stdlib.Functions(root.HostDir()))
+	evalctx.SetNamespace("terramate", root.Runtime())
+
+	logger.Trace().Msg("loading globals")
+
+	globalsReport := globals.ForDir(root, project.NewPath("/"), evalctx)
+	if err := globalsReport.AsError(); err != nil {
+		return Hooks{}, errors.E(ErrHooks, err)
+	}
+
+	evalctx.SetNamespace("global", globalsReport.Globals.AsValueMap())
+	evalctx.SetEnv(os.Environ())
+
+	return evalHooks(evalctx, runCfg.BeforeAll, runCfg.AfterAll, "before_all", "after_all")
+}
+
+func evalHooks(evalctx *eval.Context, before, after *ast.Attribute, beforeName, afterName string) (Hooks, error) {
+	var hooks Hooks
+	errs := errors.L()
+	if before != nil {
+		cmds, err := config.EvalCommands(evalctx, before, "terramate.config.run."+beforeName)
+		errs.AppendWrap(ErrHooks, err)
+		hooks.Before = cmds
+	}
+	if after != nil {
+		cmds, err := config.EvalCommands(evalctx, after, "terramate.config.run."+afterName)
+		errs.AppendWrap(ErrHooks, err)
+		hooks.After = cmds
+	}
+	if err := errs.AsError(); err != nil {
+		return Hooks{}, err
+	}
+	return hooks, nil
+}
+
+func lookupHook(root *config.Root, dir project.Path, get func(*hcl.RunConfig) *ast.Attribute) *ast.Attribute {
+	for {
+		if tree, ok := root.Lookup(dir); ok {
+			if runCfg := runConfig(tree); runCfg != nil {
+				if attr := get(runCfg); attr != nil {
+					return attr
+				}
+			}
+		}
+
+		parent := dir.Dir()
+		if parent == dir {
+			return nil
+		}
+		dir = parent
+	}
+}
+
+func runConfig(tree *config.Tree) *hcl.RunConfig {
+	tm := tree.Node.Terramate
+	if tm == nil || tm.Config == nil {
+		return nil
+	}
+	return tm.Config.Run
+}
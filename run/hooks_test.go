@@ -0,0 +1,236 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package run_test
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/config"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/project"
+	"github.com/terramate-io/terramate/run"
+	"github.com/terramate-io/terramate/test"
+	errorstest "github.com/terramate-io/terramate/test/errors"
+	"github.com/terramate-io/terramate/test/hclwrite"
+	. "github.com/terramate-io/terramate/test/hclwrite/hclutils"
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+func TestLoadRunHooks(t *testing.T) {
+	type (
+		hclconfig struct {
+			path string
+			add  fmt.Stringer
+		}
+		result struct {
+			hooks run.Hooks
+			err   error
+		}
+		testcase struct {
+			name       string
+			layout     []string
+			configs    []hclconfig
+			want       map[string]result
+			wantRun    run.Hooks
+			wantRunErr error
+		}
+	)
+
+	runCfg := func(builders ...hclwrite.BlockBuilder) *hclwrite.Block {
+		return Terramate(Config(Run(builders...)))
+	}
+
+	tcases := []testcase{
+		{
+			name: "no hooks",
+			layout: []string{
+				"s:stack",
+			},
+			want: map[string]result{
+				"stack": {},
+			},
+		},
+		{
+			name: "hooks defined at root apply to all stacks",
+			layout: []string{
+				"s:stacks/stack-1",
+				"s:stacks/stack-2",
+			},
+			configs: []hclconfig{
+				{
+					path: "/",
+					add: runCfg(
+						Expr("before", `[["echo", "before", terramate.stack.name]]`),
+						Expr("after", `[["echo", "after"], ["echo", global.msg]]`),
+					),
+				},
+				{
+					path: "/",
+					add: Globals(
+						Str("msg", "done"),
+					),
+				},
+			},
+			want: map[string]result{
+				"stacks/stack-1": {
+					hooks: run.Hooks{
+						Before: [][]string{{"echo", "before", "stack-1"}},
+						After:  [][]string{{"echo", "after"}, {"echo", "done"}},
+					},
+				},
+				"stacks/stack-2": {
+					hooks: run.Hooks{
+						Before: [][]string{{"echo", "before", "stack-2"}},
+						After:  [][]string{{"echo", "after"}, {"echo", "done"}},
+					},
+				},
+			},
+		},
+		{
+			name: "closest hooks override parent hooks",
+			layout: []string{
+				"s:stacks/stack-1",
+				"s:stacks/stack-2",
+				"s:other/stack",
+			},
+			configs: []hclconfig{
+				{
+					path: "/",
+					add: runCfg(
+						Expr("before", `[["echo", "root before"]]`),
+						Expr("after", `[["echo", "root after"]]`),
+					),
+				},
+				{
+					path: "/stacks",
+					add: runCfg(
+						Expr("before", `[["echo", "stacks before"]]`),
+					),
+				},
+				{
+					path: "/stacks/stack-2",
+					add: runCfg(
+						Expr("after", `[]`),
+					),
+				},
+			},
+			want: map[string]result{
+				"stacks/stack-1": {
+					hooks: run.Hooks{
+						Before: [][]string{{"echo", "stacks before"}},
+						After:  [][]string{{"echo", "root after"}},
+					},
+				},
+				"stacks/stack-2": {
+					hooks: run.Hooks{
+						Before: [][]string{{"echo", "stacks before"}},
+					},
+				},
+				"other/stack": {
+					hooks: run.Hooks{
+						Before: [][]string{{"echo", "root before"}},
+						After:  [][]string{{"echo", "root after"}},
+					},
+				},
+			},
+		},
+		{
+			name: "hooks with invalid type fails",
+			layout: []string{
+				"s:stack",
+			},
+			configs: []hclconfig{
+				{
+					path: "/stack",
+					add: runCfg(
+						Expr("before", `["echo", "not a list of commands"]`),
+					),
+				},
+			},
+			want: map[string]result{
+				"stack": {
+					err: errors.E(run.ErrHooks),
+				},
+			},
+		},
+		{
+			name: "run hooks are loaded from the root",
+			layout: []string{
+				"s:stack",
+			},
+			configs: []hclconfig{
+				{
+					path: "/",
+					add: runCfg(
+						Expr("before_all", `[["echo", "start", global.msg]]`),
+						Expr("after_all", `[["echo", "end"]]`),
+					),
+				},
+				{
+					path: "/",
+					add: Globals(
+						Str("msg", "hello"),
+					),
+				},
+			},
+			want: map[string]result{
+				"stack": {},
+			},
+			wantRun: run.Hooks{
+				Before: [][]string{{"echo", "start", "hello"}},
+				After:  [][]string{{"echo", "end"}},
+			},
+		},
+		{
+			name: "run hooks with invalid type fails",
+			layout: []string{
+				"s:stack",
+			},
+			configs: []hclconfig{
+				{
+					path: "/",
+					add: runCfg(
+						Expr("after_all", `[["echo", {}]]`),
+					),
+				},
+			},
+			want: map[string]result{
+				"stack": {},
+			},
+			wantRunErr: errors.E(run.ErrHooks),
+		},
+	}
+
+	for _, tcase := range tcases {
+		tcase := tcase
+		t.Run(tcase.name, func(t *testing.T) {
+			s := sandbox.New(t)
+			s.BuildTree(tcase.layout)
+			for _, cfg := range tcase.configs {
+				path := filepath.Join(s.RootDir(), cfg.path)
+				test.AppendFile(t, path, "run_hooks_test_cfg.tm", cfg.add.String())
+			}
+
+			root, err := config.LoadRoot(s.RootDir())
+			assert.NoError(t, err)
+
+			for stackRelPath, wantres := range tcase.want {
+				stack, err := config.LoadStack(root, project.NewPath(path.Join("/", stackRelPath)))
+				assert.NoError(t, err)
+
+				got, err := run.LoadStackHooks(root, stack)
+				errorstest.Assert(t, err, wantres.err)
+				test.AssertDiff(t, got, wantres.hooks)
+			}
+
+			got, err := run.LoadRunHooks(root)
+			errorstest.Assert(t, err, tcase.wantRunErr)
+			test.AssertDiff(t, got, tcase.wantRun)
+		})
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package run
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/rs/zerolog/log"
+	"github.com/terramate-io/terramate/config"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/hcl/info"
+	"github.com/terramate-io/terramate/project"
+	"github.com/terramate-io/terramate/tf"
+)
+
+// InferredKind is the kind of the DAG edges inferred from the Terraform remote
+// states. See [dag.DAG.EdgeKinds].
+const InferredKind = "inferred"
+
+type (
+	// InferredOrder is a map of stack directories to the stacks which must run
+	// before them, inferred from the Terraform remote states they read.
+	InferredOrder map[project.Path][]InferredDep
+
+	// InferredDep is a stack whose state is read by a terraform_remote_state
+	// data source.
+	InferredDep struct {
+		// Stack is the directory of the stack which owns the state.
+		Stack project.Path
+
+		// Range is the range of the terraform_remote_state data source.
+		Range info.Range
+	}
+)
+
+// InferOrderEnabled tells if the inference of the order of execution from the
+// Terraform remote states is enabled in the root configuration.
+func InferOrderEnabled(root *config.Root) bool {
+	rootcfg := root.Tree().Node
+	return rootcfg.Terramate != nil &&
+		rootcfg.Terramate.Config != nil &&
+		rootcfg.Terramate.Config.Run != nil &&
+		rootcfg.Terramate.Config.Run.InferOrder
+}
+
+// InferOrder infers the order of execution of all the stacks of the project
+// from their Terraform files. A stack owns the state of its backend and every
+// stack reading that state with a terraform_remote_state data source must run
+// after it. The states which cannot be located statically, or which are owned
+// by multiple stacks, are ignored.
+func InferOrder(root *config.Root) (InferredOrder, error) {
+	logger := log.With().
+		Str("action", "run.InferOrder()").
+		Str("root", root.HostDir()).
+		Logger()
+
+	stacks, err := config.LoadAllStacks(root.Tree())
+	if err != nil {
+		return nil, err
+	}
+
+	type remoteState struct {
+		key   string
+		rng   info.Range
+		stack project.Path
+	}
+
+	owners := map[string]project.Paths{}
+	var remoteStates []remoteState
+
+	for _, st := range stacks {
+		stackdir := st.HostDir(root)
+		entries, err := os.ReadDir(stackdir)
+		if err != nil {
+			return nil, errors.E(err, "reading stack directory %s", stackdir)
+		}
+		for _, entry := range entries {
+			if !entry.Type().IsRegular() || filepath.Ext(entry.Name()) != ".tf" {
+				continue
+			}
+
+			cfg, err := tf.ParseStateConfig(filepath.Join(stackdir, entry.Name()))
+			if err != nil {
+				return nil, errors.E(err, "stack %s: parsing Terraform files", st.Dir())
+			}
+
+			if cfg.Backend != nil {
+				if key, ok := cfg.Backend.Key(stackdir); ok {
+					owners[key] = append(owners[key], st.Dir())
+				} else {
+					logger.Debug().
+						Stringer("stack", st.Dir()).
+						Msg("ignoring backend with unknown state location")
+				}
+			}
+
+			for _, rs := range cfg.RemoteStates {
+				key, ok := rs.Key(stackdir)
+				if !ok {
+					logger.Debug().
+						Stringer("stack", st.Dir()).
+						Str("remote_state", rs.Name).
+						Msg("ignoring remote state with unknown state location")
+					continue
+				}
+				remoteStates = append(remoteStates, remoteState{
+					key:   key,
+					rng:   info.NewRange(root.HostDir(), rs.Range),
+					stack: st.Dir(),
+				})
+			}
+		}
+	}
+
+	inferred := InferredOrder{}
+	for _, rs := range remoteStates {
+		owner := owners[rs.key]
+		if len(owner) != 1 {
+			if len(owner) > 1 {
+				logger.Warn().
+					Stringer("stack", rs.stack).
+					Str("state", rs.key).
+					Msgf("ignoring remote state owned by multiple stacks: %v", owner.Strings())
+			}
+			continue
+		}
+		if owner[0] == rs.stack {
+			continue
+		}
+		inferred[rs.stack] = append(inferred[rs.stack], InferredDep{
+			Stack: owner[0],
+			Range: rs.rng,
+		})
+	}
+
+	for _, deps := range inferred {
+		sort.Slice(deps, func(i, j int) bool {
+			return deps[i].Stack.String() < deps[j].Stack.String()
+		})
+	}
+	return inferred, nil
+}
+
+// Paths returns the directories of the stacks the given stack must run after.
+func (o InferredOrder) Paths(stackdir project.Path) []string {
+	var paths []string
+	for _, dep := range o[stackdir] {
+		paths = append(paths, dep.Stack.String())
+	}
+	return paths
+}
@@ -0,0 +1,141 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package run_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/config"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/project"
+	"github.com/terramate-io/terramate/run"
+	"github.com/terramate-io/terramate/run/dag"
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+func TestRunInferOrder(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.NoGit(t)
+	s.BuildTree([]string{
+		"s:network",
+		"s:app",
+		"s:db",
+		"s:dup-a",
+		"s:dup-b",
+		`f:terramate.tm:terramate {
+		  config {
+		    run {
+		      infer_order = true
+		    }
+		  }
+		}`,
+	})
+
+	s3Backend := func(key string) string {
+		return `terraform {
+  backend "s3" {
+    bucket = "states"
+    key    = "` + key + `"
+  }
+}
+`
+	}
+	s3RemoteState := func(name, key string) string {
+		return `data "terraform_remote_state" "` + name + `" {
+  backend = "s3"
+  config = {
+    bucket = "states"
+    key    = "` + key + `"
+  }
+}
+`
+	}
+
+	s.DirEntry("network").CreateFile("backend.tf", s3Backend("network"))
+	s.DirEntry("db").CreateFile("backend.tf", s3Backend("db"))
+	s.DirEntry("db").CreateFile("deps.tf", s3RemoteState("network", "network"))
+	s.DirEntry("app").CreateFile("main.tf",
+		s3Backend("app")+s3RemoteState("db", "db")+s3RemoteState("network", "network")+
+			s3RemoteState("dup", "dup")+s3RemoteState("other", "not-owned"))
+	s.DirEntry("dup-a").CreateFile("backend.tf", s3Backend("dup"))
+	s.DirEntry("dup-b").CreateFile("backend.tf", s3Backend("dup"))
+
+	root := s.Config()
+	assert.IsTrue(t, run.InferOrderEnabled(root))
+
+	inferred, err := run.InferOrder(root)
+	assert.NoError(t, err)
+	assert.EqualInts(t, 2, len(inferred), "inferred: %v", inferred)
+	assert.EqualStrings(t, "/db /network", strings.Join(inferred.Paths(project.NewPath("/app")), " "))
+	assert.EqualStrings(t, "/network", strings.Join(inferred.Paths(project.NewPath("/db")), " "))
+
+	dep := inferred[project.NewPath("/db")][0]
+	assert.EqualStrings(t, "/db/deps.tf", dep.Range.Path().String())
+	assert.EqualInts(t, 1, dep.Range.Start().Line())
+
+	stacks, err := config.LoadAllStacks(root.Tree())
+	assert.NoError(t, err)
+	ordered, _, err := run.Sort(root, stacks)
+	assert.NoError(t, err)
+
+	var got []string
+	for _, st := range ordered {
+		got = append(got, st.Dir().String())
+	}
+	assert.EqualStrings(t, "/network /db /app /dup-a /dup-b", strings.Join(got, " "))
+}
+
+func TestRunInferOrderCycleHasRemoteStateRange(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.NoGit(t)
+	s.BuildTree([]string{
+		`s:network:after=["/app"]`,
+		"s:app",
+		`f:terramate.tm:terramate {
+		  config {
+		    run {
+		      infer_order = true
+		    }
+		  }
+		}`,
+	})
+
+	s.DirEntry("network").CreateFile("backend.tf", `terraform {
+  backend "local" {}
+}
+`)
+	s.DirEntry("app").CreateFile("deps.tf", `data "terraform_remote_state" "network" {
+  backend = "local"
+  config = {
+    path = "../network/terraform.tfstate"
+  }
+}
+`)
+
+	root := s.Config()
+	stacks, err := config.LoadAllStacks(root.Tree())
+	assert.NoError(t, err)
+
+	_, _, err = run.Sort(root, stacks)
+	assert.IsError(t, err, errors.E(dag.ErrCycleDetected))
+
+	var errs *errors.List
+	assert.IsTrue(t, errors.As(err, &errs), "error is not a list: %v", err)
+
+	var found bool
+	for _, err := range errs.Errors() {
+		var e *errors.Error
+		if errors.As(err, &e) && strings.HasSuffix(e.FileRange.Filename, "deps.tf") {
+			assert.EqualStrings(t,
+				"stack /app runs after /network in the cycle /app -> /network -> /app",
+				e.Description)
+			found = true
+		}
+	}
+	assert.IsTrue(t, found, "no error for the remote state: %v", errs.Errors())
+}
@@ -0,0 +1,91 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package run
+
+import (
+	"time"
+
+	"github.com/terramate-io/terramate/config"
+	"github.com/terramate-io/terramate/project"
+)
+
+// Levels groups the ordered stacks into levels of execution. All the
+// dependencies of a stack are in previous levels, then the stacks of the same
+// level can run in parallel. The stacks and deps must be the ones returned by
+// [SortWithDeps].
+func Levels(stacks config.List[*config.SortableStack], deps Deps) []config.List[*config.SortableStack] {
+	var levels []config.List[*config.SortableStack]
+	stackLevel := map[project.Path]int{}
+	for _, s := range stacks {
+		level := 0
+		for _, dep := range deps[s.Dir()] {
+			if depLevel, ok := stackLevel[dep]; ok && depLevel+1 > level {
+				level = depLevel + 1
+			}
+		}
+		stackLevel[s.Dir()] = level
+		if level == len(levels) {
+			levels = append(levels, config.List[*config.SortableStack]{})
+		}
+		levels[level] = append(levels[level], s)
+	}
+	return levels
+}
+
+// CriticalPath returns the chain of dependent stacks with the longest total
+// duration, in the order of execution, and its total duration. The stacks
+// missing in the durations map are considered to take no time.
+// The stacks and deps must be the ones returned by [SortWithDeps].
+func CriticalPath(
+	stacks config.List[*config.SortableStack],
+	deps Deps,
+	durations map[project.Path]time.Duration,
+) (project.Paths, time.Duration) {
+	finish := map[project.Path]time.Duration{}
+	previous := map[project.Path]project.Path{}
+
+	if len(stacks) == 0 {
+		return nil, 0
+	}
+
+	var last project.Path
+	var total time.Duration
+	for i, s := range stacks {
+		// the deps are transitive, then in the case of a tie the dependency
+		// with more dependencies is preferred, so the chain has no gaps.
+		var start time.Duration
+		var prev project.Path
+		found := false
+		for _, dep := range deps[s.Dir()] {
+			depFinish, ok := finish[dep]
+			if !ok {
+				continue
+			}
+			if !found || depFinish > start ||
+				(depFinish == start && len(deps[dep]) > len(deps[prev])) {
+				start = depFinish
+				prev = dep
+				found = true
+			}
+		}
+		if found {
+			previous[s.Dir()] = prev
+		}
+		finish[s.Dir()] = start + durations[s.Dir()]
+		if i == 0 || finish[s.Dir()] > total {
+			last = s.Dir()
+			total = finish[s.Dir()]
+		}
+	}
+
+	path := project.Paths{last}
+	for {
+		prev, ok := previous[path[0]]
+		if !ok {
+			break
+		}
+		path = append(project.Paths{prev}, path...)
+	}
+	return path, total
+}
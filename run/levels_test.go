@@ -0,0 +1,143 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package run_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/config"
+	"github.com/terramate-io/terramate/project"
+	"github.com/terramate-io/terramate/run"
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+func TestRunLevelsAndCriticalPath(t *testing.T) {
+	t.Parallel()
+
+	type testcase struct {
+		name      string
+		layout    []string
+		durations map[string]time.Duration
+		levels    [][]string
+		critical  []string
+		total     time.Duration
+	}
+
+	for _, tc := range []testcase{
+		{
+			name: "independent stacks",
+			layout: []string{
+				"s:a",
+				"s:b",
+				"s:c",
+			},
+			durations: map[string]time.Duration{
+				"/a": time.Second,
+				"/b": 3 * time.Second,
+				"/c": 2 * time.Second,
+			},
+			levels:   [][]string{{"/a", "/b", "/c"}},
+			critical: []string{"/b"},
+			total:    3 * time.Second,
+		},
+		{
+			name: "diamond",
+			layout: []string{
+				"s:network",
+				`s:app:after=["/network"]`,
+				`s:db:after=["/network"]`,
+				`s:frontend:after=["/app", "/db"]`,
+			},
+			durations: map[string]time.Duration{
+				"/network":  time.Second,
+				"/app":      5 * time.Second,
+				"/db":       2 * time.Second,
+				"/frontend": time.Second,
+			},
+			levels: [][]string{
+				{"/network"},
+				{"/app", "/db"},
+				{"/frontend"},
+			},
+			critical: []string{"/network", "/app", "/frontend"},
+			total:    7 * time.Second,
+		},
+		{
+			name: "parent before child",
+			layout: []string{
+				"s:parent",
+				"s:parent/child",
+				"s:other",
+			},
+			durations: map[string]time.Duration{
+				"/parent":       2 * time.Second,
+				"/parent/child": 2 * time.Second,
+				"/other":        3 * time.Second,
+			},
+			levels: [][]string{
+				{"/other", "/parent"},
+				{"/parent/child"},
+			},
+			critical: []string{"/parent", "/parent/child"},
+			total:    4 * time.Second,
+		},
+		{
+			name: "missing durations keep the chain",
+			layout: []string{
+				"s:a",
+				`s:b:after=["/a"]`,
+				`s:c:after=["/b"]`,
+			},
+			durations: map[string]time.Duration{
+				"/c": time.Second,
+			},
+			levels: [][]string{
+				{"/a"},
+				{"/b"},
+				{"/c"},
+			},
+			critical: []string{"/a", "/b", "/c"},
+			total:    time.Second,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := sandbox.NoGit(t)
+			s.BuildTree(tc.layout)
+
+			root := s.Config()
+			stacks, err := config.LoadAllStacks(root.Tree())
+			assert.NoError(t, err)
+
+			ordered, deps, _, err := run.SortWithDeps(root, stacks)
+			assert.NoError(t, err)
+
+			var gotLevels [][]string
+			for _, level := range run.Levels(ordered, deps) {
+				var dirs []string
+				for _, st := range level {
+					dirs = append(dirs, st.Dir().String())
+				}
+				gotLevels = append(gotLevels, dirs)
+			}
+			assert.EqualInts(t, len(tc.levels), len(gotLevels), "levels: %v", gotLevels)
+			for i := range tc.levels {
+				assert.EqualStrings(t, strings.Join(tc.levels[i], " "), strings.Join(gotLevels[i], " "), "level %d", i)
+			}
+
+			durations := map[project.Path]time.Duration{}
+			for dir, d := range tc.durations {
+				durations[project.NewPath(dir)] = d
+			}
+			path, total := run.CriticalPath(ordered, deps, durations)
+			assert.EqualStrings(t, strings.Join(tc.critical, " "), strings.Join(path.Strings(), " "), "critical path")
+			assert.IsTrue(t, total == tc.total, "want total %s but got %s", tc.total, total)
+		})
+	}
+}
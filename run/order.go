@@ -14,13 +14,74 @@ import (
 	"github.com/rs/zerolog/log"
 	"github.com/terramate-io/terramate/config"
 	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/hcl/info"
+	"github.com/terramate-io/terramate/project"
 	"github.com/terramate-io/terramate/run/dag"
 )
 
+// Deps is a map of stack directories to the list of stacks that must finish
+// before the stack can be executed.
+type Deps map[project.Path]project.Paths
+
 // Sort computes the final execution order for the given list of stacks.
 // In the case of multiple possible orders, it returns the lexicographic sorted
 // path.
 func Sort(root *config.Root, stacks config.List[*config.SortableStack]) (config.List[*config.SortableStack], string, error) {
+	orderedStacks, _, reason, err := sortStacks(root, stacks)
+	return orderedStacks, reason, err
+}
+
+// SortWithDeps is like [Sort] but it also returns the dependencies of each one
+// of the ordered stacks. The dependencies of a stack are the stacks of the
+// returned list that must finish before the stack can be executed. All the
+// transitive ordering relations are taken into account, even the ones passing
+// through stacks that are not part of the given list.
+func SortWithDeps(root *config.Root, stacks config.List[*config.SortableStack]) (config.List[*config.SortableStack], Deps, string, error) {
+	orderedStacks, d, reason, err := sortStacks(root, stacks)
+	if err != nil {
+		return nil, nil, reason, err
+	}
+
+	selected := map[dag.ID]struct{}{}
+	for _, s := range orderedStacks {
+		selected[dag.ID(s.Dir().String())] = struct{}{}
+	}
+
+	ancestors := map[dag.ID]dag.Visited{}
+	deps := Deps{}
+	for _, s := range orderedStacks {
+		id := dag.ID(s.Dir().String())
+		var stackDeps project.Paths
+		for ancestorID := range transitiveAncestors(d, id, ancestors) {
+			if _, ok := selected[ancestorID]; ok {
+				stackDeps = append(stackDeps, project.NewPath(string(ancestorID)))
+			}
+		}
+		stackDeps.Sort()
+		deps[s.Dir()] = stackDeps
+	}
+	return orderedStacks, deps, "", nil
+}
+
+// transitiveAncestors returns the set of all ancestors of the node id. The
+// cache is used to memoize the results already computed.
+// The DAG must be validated (no cycles) before calling this function.
+func transitiveAncestors(d *dag.DAG, id dag.ID, cache map[dag.ID]dag.Visited) dag.Visited {
+	if res, ok := cache[id]; ok {
+		return res
+	}
+	res := dag.Visited{}
+	for _, ancestor := range d.AncestorsOf(id) {
+		res[ancestor] = struct{}{}
+		for transitive := range transitiveAncestors(d, ancestor, cache) {
+			res[transitive] = struct{}{}
+		}
+	}
+	cache[id] = res
+	return res
+}
+
+func sortStacks(root *config.Root, stacks config.List[*config.SortableStack]) (config.List[*config.SortableStack], *dag.DAG, string, error) {
 	d := dag.New()
 
 	logger := log.With().
@@ -49,6 +110,17 @@ func Sort(root *config.Root, stacks config.List[*config.SortableStack]) (config.
 		}
 	}
 
+	var inferred InferredOrder
+	if InferOrderEnabled(root) {
+		logger.Trace().Msg("Infer order from Terraform remote states.")
+
+		var err error
+		inferred, err = InferOrder(root)
+		if err != nil {
+			return nil, nil, "", err
+		}
+	}
+
 	logger.Trace().Msg("Sorting stacks.")
 
 	visited := dag.Visited{}
@@ -69,11 +141,12 @@ func Sort(root *config.Root, stacks config.List[*config.SortableStack]) (config.
 			func(s config.Stack) []string { return s.Before },
 			"after",
 			func(s config.Stack) []string { return s.After },
+			inferred,
 			visited,
 		)
 
 		if err != nil {
-			return nil, "", err
+			return nil, nil, "", err
 		}
 	}
 
@@ -81,7 +154,12 @@ func Sort(root *config.Root, stacks config.List[*config.SortableStack]) (config.
 
 	reason, err := d.Validate()
 	if err != nil {
-		return nil, reason, err
+		if errors.IsKind(err, dag.ErrCycleDetected) {
+			if errs := cycleErrors(d); errs.AsError() != nil {
+				err = errs
+			}
+		}
+		return nil, nil, reason, err
 	}
 
 	logger.Trace().Msg("Get topologically order DAG.")
@@ -109,7 +187,7 @@ func Sort(root *config.Root, stacks config.List[*config.SortableStack]) (config.
 	for _, id := range order {
 		val, err := d.Node(id)
 		if err != nil {
-			return nil, "", fmt.Errorf("calculating run-order: %w", err)
+			return nil, nil, "", fmt.Errorf("calculating run-order: %w", err)
 		}
 		s := val.(*config.Stack)
 		if !isSelectedStack(s) {
@@ -121,10 +199,14 @@ func Sort(root *config.Root, stacks config.List[*config.SortableStack]) (config.
 		orderedStacks = append(orderedStacks, s.Sortable())
 	}
 
-	return orderedStacks, "", nil
+	return orderedStacks, d, "", nil
 }
 
 // BuildDAG builds a run order DAG for the given stack.
+// The edges of the DAG are labeled with the descendantsName or ancestorsName
+// kind, depending on which attribute defined them. See [dag.DAG.EdgeKinds].
+// The inferred order, if not nil, adds the stacks of each stack inferred
+// dependencies as ancestors, labeled with the [InferredKind] kind.
 func BuildDAG(
 	d *dag.DAG,
 	root *config.Root,
@@ -133,6 +215,7 @@ func BuildDAG(
 	getDescendants func(config.Stack) []string,
 	ancestorsName string,
 	getAncestors func(config.Stack) []string,
+	inferred InferredOrder,
 	visited dag.Visited,
 ) error {
 	logger := log.With().
@@ -147,8 +230,16 @@ func BuildDAG(
 
 	visited[dag.ID(s.Dir.String())] = struct{}{}
 
+	// origins is a map of fieldname -> clean path -> entries which resolved
+	// to the path.
+	origins := map[string]map[string][]string{}
+
 	computePaths := func(fieldname string, paths []string) ([]string, error) {
 		uniqPaths := map[string]struct{}{}
+		origins[fieldname] = map[string][]string{}
+		addOrigin := func(path, entry string) {
+			origins[fieldname][path] = append(origins[fieldname][path], entry)
+		}
 		for _, pathstr := range paths {
 			if strings.HasPrefix(pathstr, "tag:") {
 				if fieldname != "before" && fieldname != "after" {
@@ -163,6 +254,7 @@ func BuildDAG(
 				}
 				for _, stackPath := range stacksPaths {
 					uniqPaths[stackPath.String()] = struct{}{}
+					addOrigin(stackPath.String(), pathstr)
 				}
 				continue
 			}
@@ -184,6 +276,7 @@ func BuildDAG(
 						fieldname, pathstr)
 			} else {
 				uniqPaths[pathstr] = struct{}{}
+				addOrigin(pathstr, pathstr)
 			}
 		}
 
@@ -215,16 +308,87 @@ func BuildDAG(
 			s, descendantsName)
 	}
 
+	var inferredTrees config.List[*config.Tree]
+	for _, dep := range inferred[s.Dir] {
+		if tree, ok := root.Lookup(dep.Stack); ok && tree.IsStack() {
+			inferredTrees = append(inferredTrees, tree)
+		}
+	}
+	inferredStacks, err := config.StacksFromTrees(root.HostDir(), inferredTrees)
+	if err != nil {
+		return errors.E(err, "stack %q: failed to load the inferred stacks", s)
+	}
+
 	logger.Debug().Msg("Add new node to DAG.")
 
-	err = d.AddNode(dag.ID(s.Dir.String()), s, toids(descendantStacks), toids(ancestorStacks))
+	ancestorIDs := append(toids(ancestorStacks), toids(inferredStacks)...)
+	err = d.AddNode(dag.ID(s.Dir.String()), s, toids(descendantStacks), ancestorIDs)
 	if err != nil {
 		return errors.E("stack %q: failed to build DAG: %w", s, err)
 	}
 
+	id := dag.ID(s.Dir.String())
+	for _, descendant := range toids(descendantStacks) {
+		d.AddEdgeKind(descendant, id, descendantsName)
+	}
+	for _, ancestor := range toids(ancestorStacks) {
+		d.AddEdgeKind(id, ancestor, ancestorsName)
+	}
+
+	// the edges defined by the stack configuration are annotated with the
+	// range of the entries which defined them.
+	stackTree, hasTree := root.Lookup(s.Dir)
+	addEdgeRanges := func(fieldname string, target dag.ID, addRange func(r info.Range)) {
+		if !hasTree {
+			return
+		}
+
+		var cleanpaths []string
+		for cleanpath := range origins[fieldname] {
+			cleanpaths = append(cleanpaths, cleanpath)
+		}
+		sort.Strings(cleanpaths)
+
+		for _, cleanpath := range cleanpaths {
+			dir := path.Clean(cleanpath)
+			if !path.IsAbs(dir) {
+				dir = path.Join(s.Dir.String(), dir)
+			}
+			targetdir := string(target)
+			if targetdir != dir && dir != "/" && !strings.HasPrefix(targetdir, dir+"/") {
+				continue
+			}
+			for _, entry := range origins[fieldname][cleanpath] {
+				if r, ok := stackTree.StackEntryRange(fieldname, entry); ok {
+					addRange(r)
+				}
+			}
+		}
+	}
+	for _, descendant := range toids(descendantStacks) {
+		addEdgeRanges(descendantsName, descendant, func(r info.Range) {
+			d.AddEdgeRange(descendant, id, r)
+		})
+	}
+	for _, ancestor := range toids(ancestorStacks) {
+		addEdgeRanges(ancestorsName, ancestor, func(r info.Range) {
+			d.AddEdgeRange(id, ancestor, r)
+		})
+	}
+
+	for _, dep := range inferred[s.Dir] {
+		if _, ok := root.Lookup(dep.Stack); !ok {
+			continue
+		}
+		ancestor := dag.ID(dep.Stack.String())
+		d.AddEdgeKind(id, ancestor, InferredKind)
+		d.AddEdgeRange(id, ancestor, dep.Range)
+	}
+
 	stacks := config.List[*config.SortableStack]{}
 	stacks = append(stacks, ancestorStacks...)
 	stacks = append(stacks, descendantStacks...)
+	stacks = append(stacks, inferredStacks...)
 
 	logger.Trace().Msg("Range over stacks.")
 
@@ -242,7 +406,7 @@ func BuildDAG(
 		logger.Trace().Msg("Build DAG.")
 
 		err = BuildDAG(d, root, elem.Stack, descendantsName, getDescendants,
-			ancestorsName, getAncestors, visited)
+			ancestorsName, getAncestors, inferred, visited)
 		if err != nil {
 			return errors.E(err, "stack %q: failed to build DAG", elem)
 		}
@@ -250,6 +414,45 @@ func BuildDAG(
 	return nil
 }
 
+// cycleErrors returns an error for each edge of a cycle of each strongly
+// connected component of the DAG. The errors have the range of the
+// configuration which defined the edge, so every group of stacks depending on
+// each other is reported at once.
+func cycleErrors(d *dag.DAG) *errors.List {
+	errs := errors.L()
+	for _, cycle := range d.Cycles() {
+		desc := make([]string, 0, len(cycle)+1)
+		for _, id := range cycle {
+			desc = append(desc, string(id))
+		}
+		desc = append(desc, string(cycle[0]))
+		cycleDesc := strings.Join(desc, " -> ")
+
+		for i, id := range cycle {
+			next := cycle[(i+1)%len(cycle)]
+			ranges := d.EdgeRanges(id, next)
+			if len(ranges) == 0 {
+				if strings.HasPrefix(string(id), string(next)+"/") {
+					errs.Append(errors.E(dag.ErrCycleDetected,
+						"stack %s runs after its parent stack %s in the cycle %s",
+						string(id), string(next), cycleDesc))
+				} else {
+					errs.Append(errors.E(dag.ErrCycleDetected,
+						"stack %s runs after %s in the cycle %s",
+						string(id), string(next), cycleDesc))
+				}
+				continue
+			}
+			for _, r := range ranges {
+				errs.Append(errors.E(dag.ErrCycleDetected, r,
+					"stack %s runs after %s in the cycle %s",
+					string(id), string(next), cycleDesc))
+			}
+		}
+	}
+	return errs
+}
+
 func toids(values config.List[*config.SortableStack]) []dag.ID {
 	ids := make([]dag.ID, 0, len(values))
 	for _, v := range values {
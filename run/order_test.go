@@ -0,0 +1,114 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package run_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/config"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/run"
+	"github.com/terramate-io/terramate/run/dag"
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+func TestRunSortReportsAllCycles(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.NoGit(t)
+	s.BuildTree([]string{
+		"d:a",
+		"d:b",
+		"d:c",
+		"d:d",
+		"d:e",
+		"s:e/child",
+	})
+
+	s.DirEntry("a").CreateFile("stack.tm", `stack {
+  after = [
+    "/b",
+  ]
+}
+`)
+	s.DirEntry("b").CreateFile("stack.tm", `stack {
+  after = ["/a"]
+}
+`)
+	s.DirEntry("c").CreateFile("stack.tm", `stack {
+  before = ["/d"]
+}
+`)
+	s.DirEntry("d").CreateFile("stack.tm", `stack {
+  before = ["/c"]
+}
+`)
+	s.DirEntry("e").CreateFile("stack.tm", `stack {
+  after = ["/e/child"]
+}
+`)
+
+	root := s.Config()
+	stacks, err := config.LoadAllStacks(root.Tree())
+	assert.NoError(t, err)
+
+	_, _, err = run.Sort(root, stacks)
+	assert.IsError(t, err, errors.E(dag.ErrCycleDetected))
+
+	var errs *errors.List
+	assert.IsTrue(t, errors.As(err, &errs), "error is not a list: %v", err)
+
+	type want struct {
+		desc string
+		file string
+		line int
+	}
+
+	wants := []want{
+		{
+			desc: "stack /a runs after /b in the cycle /a -> /b -> /a",
+			file: "a/stack.tm",
+			line: 3,
+		},
+		{
+			desc: "stack /b runs after /a in the cycle /a -> /b -> /a",
+			file: "b/stack.tm",
+			line: 2,
+		},
+		{
+			desc: "stack /c runs after /d in the cycle /c -> /d -> /c",
+			file: "d/stack.tm",
+			line: 2,
+		},
+		{
+			desc: "stack /d runs after /c in the cycle /c -> /d -> /c",
+			file: "c/stack.tm",
+			line: 2,
+		},
+		{
+			desc: "stack /e runs after /e/child in the cycle /e -> /e/child -> /e",
+			file: "e/stack.tm",
+			line: 2,
+		},
+		{
+			desc: "stack /e/child runs after its parent stack /e in the cycle /e -> /e/child -> /e",
+		},
+	}
+
+	got := errs.Errors()
+	assert.EqualInts(t, len(wants), len(got), "errors: %v", got)
+	for i, w := range wants {
+		var e *errors.Error
+		assert.IsTrue(t, errors.As(got[i], &e), "error %d is not an *errors.Error", i)
+		assert.EqualStrings(t, w.desc, e.Description, "error %d", i)
+		if w.file == "" {
+			assert.IsTrue(t, e.FileRange.Empty(), "error %d has range %s", i, e.FileRange)
+			continue
+		}
+		assert.EqualStrings(t, filepath.Join(s.RootDir(), filepath.FromSlash(w.file)), e.FileRange.Filename, "error %d", i)
+		assert.EqualInts(t, w.line, e.FileRange.Start.Line, "error %d", i)
+	}
+}
@@ -5,6 +5,7 @@ package stack
 
 import (
 	"os"
+	"path"
 	"strings"
 
 	"github.com/google/uuid"
@@ -32,6 +33,10 @@ const (
 // - All files and directories are copied  (except dotfiles/dirs)
 // - If cloned stack has an ID it will be adjusted to a generated UUID.
 // - If cloned stack has no ID the cloned stack also won't have an ID.
+// - The child stacks of srcdir are also cloned and the same ID rules apply.
+// - The relative after, before, wants and wanted_by entries of the cloned
+// stacks are rewritten, so the ones referring to stacks inside srcdir refer
+// to their cloned counterparts and the others keep referring to the same stacks.
 func Clone(root *config.Root, destdir, srcdir string) error {
 	rootdir := root.HostDir()
 
@@ -56,29 +61,75 @@ func Clone(root *config.Root, destdir, srcdir string) error {
 		return errors.E(ErrCloneDestDirExists, destdir)
 	}
 
-	srcStack, err := config.LoadStack(root, project.PrjAbsPath(root.HostDir(), srcdir))
+	src := project.PrjAbsPath(rootdir, srcdir)
+	dest := project.PrjAbsPath(rootdir, destdir)
+
+	_, err := config.LoadStack(root, src)
 	if err != nil {
 		return errors.E(ErrInvalidStackDir, err, "src dir %q must be a valid stack", srcdir)
 	}
 
+	srcNode, _ := root.Lookup(src)
+	stacks, err := config.LoadAllStacks(srcNode)
+	if err != nil {
+		return errors.E(ErrInvalidStackDir, err, "src dir %q must have valid stacks", srcdir)
+	}
+
 	logger.Trace().Msg("copying stack files")
 
 	if err := fs.CopyDir(destdir, srcdir, filterDotFiles); err != nil {
 		return err
 	}
 
-	if srcStack.ID == "" {
-		logger.Trace().Msg("stack has no ID, nothing else to do")
-		return nil
+	cloned := func(p project.Path) project.Path {
+		return rebasePath(p, src, dest)
 	}
 
-	logger.Trace().Msg("stack has ID, updating ID of the cloned stack")
-	_, err = UpdateStackID(destdir)
-	if err != nil {
-		return err
+	for _, elem := range stacks {
+		st := elem.Stack
+		clonedDir := cloned(st.Dir)
+
+		logger := logger.With().
+			Stringer("stack", st.Dir).
+			Stringer("clonedStack", clonedDir).
+			Logger()
+
+		files := map[project.Path]struct{}{}
+		for _, attr := range refAttrs {
+			for _, rng := range stackRefRanges(root, st, attr) {
+				files[cloned(project.PrjAbsPath(rootdir, rng.HostPath()))] = struct{}{}
+			}
+		}
+
+		for file := range files {
+			logger.Trace().
+				Stringer("file", file).
+				Msg("rewriting relative stacks references")
+
+			_, err := rewriteRefs(file.HostPath(rootdir), func(_, entry string) (string, bool) {
+				if path.IsAbs(entry) {
+					return entry, true
+				}
+				return relocateRef(st.Dir, clonedDir, entry, cloned), true
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		if st.ID == "" {
+			logger.Trace().Msg("stack has no ID, nothing else to do")
+			continue
+		}
+
+		logger.Trace().Msg("stack has ID, updating ID of the cloned stack")
+		_, err = UpdateStackID(clonedDir.HostPath(rootdir))
+		if err != nil {
+			return err
+		}
 	}
 
-	return root.LoadSubTree(project.PrjAbsPath(rootdir, destdir))
+	return root.LoadSubTree(dest)
 }
 
 func filterDotFiles(_ string, entry os.DirEntry) bool {
@@ -7,10 +7,13 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/config"
 	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/project"
 	"github.com/terramate-io/terramate/stack"
 	"github.com/terramate-io/terramate/test"
 	"github.com/terramate-io/terramate/test/sandbox"
@@ -273,3 +276,64 @@ func entriesNames(entries []os.DirEntry) []string {
 	}
 	return names
 }
+
+func TestStackCloneNestedStacks(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:stacks/a:id=a`,
+		`s:stacks/a/child:id=a-child;after=["../../../stacks/a/sibling"]`,
+		"d:stacks/a/sibling",
+		"s:stacks/other",
+	})
+	s.RootEntry().CreateFile("stacks/a/sibling/terramate.tm.hcl", `stack {
+  # comment kept
+  after = [
+    "../../other", # outside the cloned stack
+    "/stacks/a",
+    "..",
+  ]
+  before = ["../child"]
+}
+`)
+
+	srcdir := filepath.Join(s.RootDir(), "stacks/a")
+	destdir := filepath.Join(s.RootDir(), "apps/cloned/a")
+	assert.NoError(t, stack.Clone(s.Config(), destdir, srcdir))
+
+	root, err := config.LoadRoot(s.RootDir())
+	assert.NoError(t, err)
+
+	stacks, err := config.LoadAllStacks(root.Tree())
+	assert.NoError(t, err, "cloned stacks must have unique IDs")
+	assert.EqualInts(t, 7, len(stacks))
+
+	cloned, err := config.LoadStack(root, project.NewPath("/apps/cloned/a"))
+	assert.NoError(t, err)
+	if cloned.ID == "" || cloned.ID == "a" {
+		t.Fatalf("want cloned stack to have a new ID, got %q", cloned.ID)
+	}
+
+	clonedChild, err := config.LoadStack(root, project.NewPath("/apps/cloned/a/child"))
+	assert.NoError(t, err)
+	if clonedChild.ID == "" || clonedChild.ID == "a-child" {
+		t.Fatalf("want cloned child stack to have a new ID, got %q", clonedChild.ID)
+	}
+	assert.EqualStrings(t, "../sibling", strings.Join(clonedChild.After, " "))
+
+	clonedSibling, err := config.LoadStack(root, project.NewPath("/apps/cloned/a/sibling"))
+	assert.NoError(t, err)
+	assert.EqualStrings(t, "", clonedSibling.ID)
+
+	test.AssertFileContentEquals(t, filepath.Join(s.RootDir(), "apps/cloned/a/sibling/terramate.tm.hcl"), `stack {
+  # comment kept
+  after = [
+    "../../../../stacks/other", # outside the cloned stack
+    "/stacks/a",
+    "..",
+  ]
+  before = ["../child"]
+}
+`)
+}
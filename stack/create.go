@@ -45,20 +45,10 @@ func Create(root *config.Root, stack config.Stack, imports ...string) (err error
 		Str("action", "stack.Create()").
 		Logger()
 
-	err = stack.Validate()
-	if err != nil {
+	if err := checkCanCreate(root, stack); err != nil {
 		return err
 	}
 
-	if strings.HasPrefix(path.Base(stack.Dir.String()), ".") {
-		return errors.E(ErrInvalidStackDir, "dot directories not allowed")
-	}
-
-	targetNode, ok := root.Lookup(stack.Dir)
-	if ok && targetNode.IsStack() {
-		return errors.E(ErrStackAlreadyExists)
-	}
-
 	logger.Trace().Msg("creating stack dir if absent")
 
 	hostpath := stack.Dir.HostPath(root.HostDir())
@@ -119,3 +109,20 @@ func Create(root *config.Root, stack config.Stack, imports ...string) (err error
 
 	return nil
 }
+
+// checkCanCreate checks if the stack is valid and can be created.
+func checkCanCreate(root *config.Root, stack config.Stack) error {
+	if err := stack.Validate(); err != nil {
+		return err
+	}
+
+	if strings.HasPrefix(path.Base(stack.Dir.String()), ".") {
+		return errors.E(ErrInvalidStackDir, "dot directories not allowed")
+	}
+
+	targetNode, ok := root.Lookup(stack.Dir)
+	if ok && targetNode.IsStack() {
+		return errors.E(ErrStackAlreadyExists)
+	}
+	return nil
+}
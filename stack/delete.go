@@ -0,0 +1,307 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package stack
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/terramate-io/terramate/config"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/hcl/info"
+	"github.com/terramate-io/terramate/project"
+	"github.com/terramate-io/terramate/stack/trigger"
+)
+
+const (
+	// ErrDelete indicates that the stack can't be deleted.
+	ErrDelete errors.Kind = "deleting stack"
+
+	// ErrStackReferenced indicates that the stack being deleted is referenced
+	// by other stacks.
+	ErrStackReferenced errors.Kind = "stack is referenced by other stacks"
+
+	// ErrStackHasChildren indicates that the stack being deleted has child
+	// stacks.
+	ErrStackHasChildren errors.Kind = "stack has child stacks"
+
+	// ErrStackHasNonStackDirs indicates that the stack being deleted has
+	// directories which are not stacks, with files not generated by Terramate.
+	ErrStackHasNonStackDirs errors.Kind = "stack has non-stack directories"
+)
+
+// DeleteOptions are the options of [Delete].
+type DeleteOptions struct {
+	// Recursive allows deleting a stack with child stacks.
+	Recursive bool
+
+	// NonStackDirs allows deleting a stack with directories which are not
+	// stacks, like a modules directory, which may be used by other stacks.
+	NonStackDirs bool
+
+	// ForceRewrite removes the references to the deleted stacks from the
+	// other stacks instead of failing.
+	ForceRewrite bool
+}
+
+// DeleteReport is the report of a stack deletion.
+type DeleteReport struct {
+	// DeletedStacks are the deleted stacks, including the child stacks.
+	DeletedStacks project.Paths
+
+	// UpdatedFiles are the files which had references to the deleted stacks
+	// removed.
+	UpdatedFiles project.Paths
+
+	// RemovedTriggers tells if the triggers of the deleted stacks were removed.
+	RemovedTriggers bool
+}
+
+type stackRef struct {
+	stack  *config.Stack
+	attr   string
+	target project.Path
+	rng    info.Range
+}
+
+// Delete deletes the stack directory, with all its files and the generated
+// files, and the triggers of the stack.
+//
+// - dir must be a stack other than the project root (fail otherwise)
+// - dir must have no child stacks, unless opts.Recursive is set (fail otherwise)
+// - The deleted stacks must have no directories which are not stacks, except
+// the dot directories and the ones with only generated files, unless
+// opts.NonStackDirs is set (fail otherwise).
+// - The deleted stacks must not be referenced in the after, before, wants and
+// wanted_by attributes of the other stacks, unless opts.ForceRewrite is set
+// and then the references are removed (fail otherwise). The returned error
+// has the range of every reference.
+//
+// The root configuration is not reloaded, the caller must reload it before
+// using it again.
+func Delete(root *config.Root, dir project.Path, opts DeleteOptions) (DeleteReport, error) {
+	logger := log.With().
+		Str("action", "stack.Delete()").
+		Stringer("stack", dir).
+		Logger()
+
+	logger.Trace().Msg("deleting stack, checking invariants")
+
+	if dir.String() == "/" {
+		return DeleteReport{}, errors.E(ErrDelete, "the project root can't be deleted")
+	}
+	node, ok := root.Lookup(dir)
+	if !ok || !node.IsStack() {
+		return DeleteReport{}, errors.E(ErrDelete, "%s must be a stack", dir)
+	}
+
+	stacks, err := config.LoadAllStacks(root.Tree())
+	if err != nil {
+		return DeleteReport{}, errors.E(ErrDelete, err)
+	}
+
+	var report DeleteReport
+	deleted := map[project.Path]bool{}
+	for _, elem := range stacks {
+		if elem.Dir() == dir || isInside(elem.Dir(), dir) {
+			deleted[elem.Dir()] = true
+			report.DeletedStacks = append(report.DeletedStacks, elem.Dir())
+		}
+	}
+	sort.Slice(report.DeletedStacks, func(i, j int) bool {
+		return report.DeletedStacks[i].String() < report.DeletedStacks[j].String()
+	})
+
+	if len(report.DeletedStacks) > 1 && !opts.Recursive {
+		return DeleteReport{}, errors.E(ErrStackHasChildren,
+			"stack %s has child stacks: %v", dir, report.DeletedStacks[1:].Strings())
+	}
+
+	if !opts.NonStackDirs {
+		logger.Trace().Msg("checking non-stack directories of the deleted stacks")
+
+		dirs, err := nonStackDirs(root, dir)
+		if err != nil {
+			return DeleteReport{}, errors.E(ErrDelete, err)
+		}
+		if len(dirs) > 0 {
+			return DeleteReport{}, errors.E(ErrStackHasNonStackDirs,
+				"stack %s has non-stack directories: %v", dir, dirs.Strings())
+		}
+	}
+
+	logger.Trace().Msg("checking references to the deleted stacks")
+
+	var refs []stackRef
+	for _, elem := range stacks {
+		st := elem.Stack
+		if deleted[st.Dir] {
+			continue
+		}
+		for _, attr := range refAttrs {
+			for _, entry := range stackRefEntries(st, attr) {
+				target, ok := resolveRef(st.Dir, entry)
+				if !ok || !deleted[target] {
+					continue
+				}
+				rng := stackRefRanges(root, st, attr)[entry]
+				refs = append(refs, stackRef{
+					stack:  st,
+					attr:   attr,
+					target: target,
+					rng:    rng,
+				})
+			}
+		}
+	}
+
+	if len(refs) > 0 && !opts.ForceRewrite {
+		errs := errors.L()
+		for _, ref := range refs {
+			errs.Append(errors.E(ErrStackReferenced, ref.rng,
+				"stack %s refers to the deleted stack %s in stack.%s",
+				ref.stack.Dir, ref.target, ref.attr))
+		}
+		return DeleteReport{}, errs.AsError()
+	}
+
+	logger.Trace().Msg("removing references to the deleted stacks")
+
+	files := map[project.Path]project.Path{}
+	for _, ref := range refs {
+		files[ref.rng.Path()] = ref.stack.Dir
+	}
+	filenames := make(project.Paths, 0, len(files))
+	for file := range files {
+		filenames = append(filenames, file)
+	}
+	sort.Slice(filenames, func(i, j int) bool {
+		return filenames[i].String() < filenames[j].String()
+	})
+
+	for _, file := range filenames {
+		stackdir := files[file]
+		changed, err := rewriteRefs(file.HostPath(root.HostDir()), func(_, entry string) (string, bool) {
+			target, ok := resolveRef(stackdir, entry)
+			return entry, !ok || !deleted[target]
+		})
+		if err != nil {
+			return DeleteReport{}, errors.E(ErrDelete, err)
+		}
+		if changed {
+			logger.Debug().
+				Stringer("file", file).
+				Msg("references to the deleted stacks removed")
+
+			report.UpdatedFiles = append(report.UpdatedFiles, file)
+		}
+	}
+
+	logger.Trace().Msg("removing triggers")
+
+	report.RemovedTriggers, err = trigger.RemoveStack(root, dir)
+	if err != nil {
+		return DeleteReport{}, errors.E(ErrDelete, err)
+	}
+
+	logger.Trace().Msg("removing stack directory")
+
+	if err := os.RemoveAll(dir.HostPath(root.HostDir())); err != nil {
+		return DeleteReport{}, errors.E(ErrDelete, err, "removing stack directory")
+	}
+	return report, nil
+}
+
+// nonStackDirs returns the outermost directories inside the stack dir and its
+// child stacks which are not stacks and have files not generated by the
+// stacks. The dot directories are ignored.
+func nonStackDirs(root *config.Root, dir project.Path) (project.Paths, error) {
+	var dirs project.Paths
+	reported := map[project.Path]bool{}
+	stacks := map[project.Path]map[string]bool{}
+	err := filepath.WalkDir(dir.HostPath(root.HostDir()), func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		prjpath := project.PrjAbsPath(root.HostDir(), p)
+		if d.IsDir() {
+			if prjpath != dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			if tree, ok := root.Lookup(prjpath); ok && tree.IsStack() {
+				stacks[prjpath] = generatedFiles(tree)
+			}
+			return nil
+		}
+
+		stackdir := prjpath.Dir()
+		if _, ok := stacks[stackdir]; ok {
+			return nil
+		}
+		for {
+			if stackdir == dir || stackdir.String() == "/" {
+				// the walk starts at the stack dir, then it must be found.
+				return errors.E(errors.ErrInternal, "no stack found for the file %s inside %s", prjpath, dir)
+			}
+			stackdir = stackdir.Dir()
+			if _, ok := stacks[stackdir]; ok {
+				break
+			}
+		}
+		relpath := strings.TrimPrefix(prjpath.String(), stackdir.String()+"/")
+		if stacks[stackdir][relpath] {
+			return nil
+		}
+		outerDir := stackdir.Join(strings.SplitN(relpath, "/", 2)[0])
+		if !reported[outerDir] {
+			reported[outerDir] = true
+			dirs = append(dirs, outerDir)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.E(err, "checking the directories of stack %s", dir)
+	}
+	return dirs, nil
+}
+
+// generatedFiles returns the paths, relative to the stack, of the files
+// generated by the generate_file and generate_hcl blocks of the stack and its
+// parent directories.
+func generatedFiles(stackTree *config.Tree) map[string]bool {
+	files := map[string]bool{}
+	addLabel := func(label string) {
+		if !path.IsAbs(label) {
+			files[path.Clean(label)] = true
+		}
+	}
+	for tree := stackTree; tree != nil; tree = tree.Parent {
+		for _, block := range tree.Node.Generate.Files {
+			addLabel(block.Label)
+		}
+		for _, block := range tree.Node.Generate.HCLs {
+			addLabel(block.Label)
+		}
+	}
+	return files
+}
+
+func stackRefEntries(st *config.Stack, attr string) []string {
+	switch attr {
+	case "after":
+		return st.After
+	case "before":
+		return st.Before
+	case "wants":
+		return st.Wants
+	case "wanted_by":
+		return st.WantedBy
+	}
+	panic(errors.E(errors.ErrInternal, "unexpected stack attribute %s", attr))
+}
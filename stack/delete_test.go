@@ -0,0 +1,169 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package stack_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/config"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/project"
+	"github.com/terramate-io/terramate/stack"
+	"github.com/terramate-io/terramate/stack/trigger"
+	"github.com/terramate-io/terramate/test"
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+const deleteRefsStackFile = `stack {
+  # runs after the deleted stack
+  after = [
+    "/stacks/other", # other comment
+    "../deleted",    # deleted comment
+  ]
+  before    = ["/stacks/deleted", "/stacks/other"]
+  wants     = ["/stacks/other", "/stacks/deleted/child"]
+  wanted_by = ["/stacks/deleted"]
+}
+`
+
+func TestStackDeleteFailsWhenReferenced(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.NoGit(t)
+	s.BuildTree([]string{
+		"s:stacks/deleted",
+		"s:stacks/deleted/child",
+		"s:stacks/other",
+	})
+	s.RootEntry().CreateFile("stacks/refs/stack.tm.hcl", deleteRefsStackFile)
+
+	_, err := stack.Delete(s.Config(), project.NewPath("/stacks/deleted"), stack.DeleteOptions{})
+	assert.IsError(t, err, errors.E(stack.ErrStackHasChildren))
+
+	_, err = stack.Delete(s.Config(), project.NewPath("/stacks/deleted"), stack.DeleteOptions{
+		Recursive: true,
+	})
+	assert.IsError(t, err, errors.E(stack.ErrStackReferenced))
+
+	var errs *errors.List
+	assert.IsTrue(t, errors.As(err, &errs), "want an error list, got %v", err)
+	assert.EqualInts(t, 4, len(errs.Errors()), "want an error for each reference: %v", err)
+	for _, err := range errs.Errors() {
+		e := err.(*errors.Error)
+		assert.EqualStrings(t, filepath.Join(s.RootDir(), "stacks/refs/stack.tm.hcl"), e.FileRange.Filename)
+	}
+
+	_, err = os.Stat(filepath.Join(s.RootDir(), "stacks/deleted"))
+	assert.NoError(t, err, "stack must not be deleted")
+}
+
+func TestStackDeleteForceRewrite(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.NoGit(t)
+	s.BuildTree([]string{
+		"s:stacks/deleted",
+		"s:stacks/deleted/child",
+		"s:stacks/other",
+	})
+	s.RootEntry().CreateFile("stacks/refs/stack.tm.hcl", deleteRefsStackFile)
+
+	root := s.Config()
+	assert.NoError(t, trigger.Create(root, project.NewPath("/stacks/deleted/child"), "pending"))
+	assert.NoError(t, trigger.Create(root, project.NewPath("/stacks/other"), "pending"))
+
+	report, err := stack.Delete(root, project.NewPath("/stacks/deleted"), stack.DeleteOptions{
+		Recursive:    true,
+		ForceRewrite: true,
+	})
+	assert.NoError(t, err)
+	assert.IsTrue(t, report.RemovedTriggers, "triggers must be removed")
+	assert.EqualStrings(t, "/stacks/deleted /stacks/deleted/child",
+		strings.Join(report.DeletedStacks.Strings(), " "))
+	assert.EqualStrings(t, "/stacks/refs/stack.tm.hcl",
+		strings.Join(report.UpdatedFiles.Strings(), " "))
+
+	test.AssertFileContentEquals(t, filepath.Join(s.RootDir(), "stacks/refs/stack.tm.hcl"), `stack {
+  # runs after the deleted stack
+  after = [
+    "/stacks/other", # other comment
+  ]
+  before    = ["/stacks/other"]
+  wants     = ["/stacks/other"]
+  wanted_by = []
+}
+`)
+
+	_, err = os.Stat(filepath.Join(s.RootDir(), "stacks/deleted"))
+	assert.IsTrue(t, os.IsNotExist(err), "stack dir must be deleted: %v", err)
+
+	root, err = config.LoadRoot(s.RootDir())
+	assert.NoError(t, err)
+
+	triggers, err := trigger.List(root)
+	assert.NoError(t, err)
+	assert.EqualInts(t, 1, len(triggers))
+	assert.EqualStrings(t, "/stacks/other", triggers[0].Stack.String())
+}
+
+func TestStackDeleteFails(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.NoGit(t)
+	s.BuildTree([]string{
+		"s:stack",
+		"d:dir",
+	})
+
+	_, err := stack.Delete(s.Config(), project.NewPath("/"), stack.DeleteOptions{})
+	assert.IsError(t, err, errors.E(stack.ErrDelete))
+
+	_, err = stack.Delete(s.Config(), project.NewPath("/dir"), stack.DeleteOptions{})
+	assert.IsError(t, err, errors.E(stack.ErrDelete))
+
+	_, err = stack.Delete(s.Config(), project.NewPath("/non-existent"), stack.DeleteOptions{})
+	assert.IsError(t, err, errors.E(stack.ErrDelete))
+}
+
+func TestStackDeleteNonStackDirs(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.NoGit(t)
+	s.BuildTree([]string{
+		"s:stacks/app",
+		"s:stacks/app/child",
+		`f:stacks/generate.tm.hcl:generate_file "sub/generated.txt" {
+		  content = "generated"
+		}`,
+		"f:stacks/app/main.tf:# app",
+		"f:stacks/app/.terraform/cache:cache",
+		"f:stacks/app/modules/vpc/main.tf:# vpc",
+		"f:stacks/app/sub/generated.txt:generated",
+		"f:stacks/app/child/sub/generated.txt:generated",
+		"f:stacks/app/child/docs/README.md:# docs",
+	})
+
+	_, err := stack.Delete(s.Config(), project.NewPath("/stacks/app"), stack.DeleteOptions{
+		Recursive: true,
+	})
+	assert.IsError(t, err, errors.E(stack.ErrStackHasNonStackDirs))
+	assert.IsTrue(t, strings.Contains(err.Error(), "[/stacks/app/child/docs /stacks/app/modules]"),
+		"unexpected non-stack directories: %v", err)
+
+	_, err = os.Stat(filepath.Join(s.RootDir(), "stacks/app"))
+	assert.NoError(t, err, "stack must not be deleted")
+
+	_, err = stack.Delete(s.Config(), project.NewPath("/stacks/app"), stack.DeleteOptions{
+		Recursive:    true,
+		NonStackDirs: true,
+	})
+	assert.NoError(t, err)
+
+	_, err = os.Stat(filepath.Join(s.RootDir(), "stacks/app"))
+	assert.IsTrue(t, os.IsNotExist(err), "stack dir must be deleted: %v", err)
+}
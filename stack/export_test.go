@@ -0,0 +1,14 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package stack
+
+import "github.com/terramate-io/terramate/tf"
+
+// SetParseModules replaces the function parsing the modules of the Terraform
+// files and returns a function restoring the original one.
+func SetParseModules(parse func(path string) ([]tf.Module, error)) (restore func()) {
+	orig := tfParseModules
+	tfParseModules = parse
+	return func() { tfParseModules = orig }
+}
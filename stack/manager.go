@@ -11,6 +11,7 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/rs/zerolog/log"
 	"github.com/terramate-io/terramate/config"
@@ -29,6 +30,42 @@ type (
 		root       *config.Root // whole config
 		gitBaseRef string       // gitBaseRef is the git ref where we compare changes.
 
+		// realRootDir is the project root directory with the symlinks resolved.
+		realRootDir string
+
+		// includeWorktree tells if the staged, uncommitted and untracked files are
+		// considered as changed.
+		includeWorktree bool
+
+		// explainModuleBumps tells if the version bumps of the remote modules
+		// are added to the reasons of the changed stacks.
+		explainModuleBumps bool
+
+		// worktreeFiles are the staged, uncommitted and untracked files which are not
+		// changed in the commits, relative to the project root.
+		worktreeFiles map[string]bool
+
+		// The fields below are computed once per ListChanged call and shared
+		// by all the stacks.
+
+		// changedDirs maps the directories containing changed files, relative
+		// to the project root, to the first changed file inside them.
+		changedDirs map[string]string
+
+		// parsedModules are the modules of the parsed Terraform files.
+		parsedModules map[string][]tf.Module
+
+		// changedModules are the results of the moduleChanged calls of the
+		// stacks modules, indexed by module path and source.
+		changedModules map[string]moduleChange
+
+		// bumpedModules are the results of the remoteModuleBumped calls,
+		// indexed by directory.
+		bumpedModules map[project.Path]moduleChange
+
+		rootGit *git.Git
+		baseRev string
+
 		outerGit *git.Git
 	}
 
@@ -46,6 +83,11 @@ type (
 		UntrackedFiles   []string
 	}
 
+	moduleChange struct {
+		changed bool
+		why     string
+	}
+
 	// Entry is a stack entry result.
 	Entry struct {
 		Stack  *config.Stack
@@ -56,6 +98,10 @@ type (
 const errList errors.Kind = "listing stacks error"
 const errListChanged errors.Kind = "listing changed stacks error"
 
+// uncommittedSuffix is added to the reasons of the changes detected from the
+// uncommitted and untracked files.
+const uncommittedSuffix = " (uncommitted)"
+
 // NewManager creates a new stack manager.The root is the project root config
 // and and gitBaseRef is the git reference to compare for changes.
 func NewManager(root *config.Root, gitBaseRef string) *Manager {
@@ -65,6 +111,22 @@ func NewManager(root *config.Root, gitBaseRef string) *Manager {
 	}
 }
 
+// IncludeWorktree sets if the staged, uncommitted and untracked files must be
+// considered as changed by [Manager.ListChanged], in addition to the files
+// changed in the commits since the git base ref.
+func (m *Manager) IncludeWorktree(include bool) {
+	m.includeWorktree = include
+}
+
+// ExplainModuleBumps sets if [Manager.ListChanged] must compare the remote
+// modules of the changed stacks and local modules with the ones in the git base
+// ref, adding the version bumps to the reasons of the changes. It's disabled by
+// default because it reads the base revision of every Terraform file of the
+// changed directories.
+func (m *Manager) ExplainModuleBumps(explain bool) {
+	m.explainModuleBumps = explain
+}
+
 // List walks the basedir directory looking for terraform stacks.
 // It returns a lexicographic sorted list of stack directories.
 func (m *Manager) List() (*Report, error) {
@@ -149,6 +211,12 @@ func (m *Manager) ListChanged() (*Report, error) {
 		return nil, errors.E(errListChanged, err)
 	}
 
+	m.rootGit = g
+	m.baseRev = ""
+	m.parsedModules = map[string][]tf.Module{}
+	m.changedModules = map[string]moduleChange{}
+	m.bumpedModules = map[project.Path]moduleChange{}
+
 	logger.Debug().Msg("List changed files.")
 
 	changedFiles, err := m.listChangedFiles(m.root.HostDir(), m.gitBaseRef)
@@ -156,9 +224,53 @@ func (m *Manager) ListChanged() (*Report, error) {
 		return nil, errors.E(errListChanged, err)
 	}
 
+	m.worktreeFiles = map[string]bool{}
+	if m.includeWorktree {
+		logger.Debug().Msg("Add staged, uncommitted and untracked files.")
+
+		staged, err := g.ListStaged()
+		if err != nil {
+			return nil, errors.E(errListChanged, err, "listing staged files")
+		}
+
+		committed := map[string]bool{}
+		for _, file := range changedFiles {
+			committed[file] = true
+		}
+		for _, files := range [][]string{staged, checks.UncommittedFiles, checks.UntrackedFiles} {
+			for _, file := range files {
+				if committed[file] || m.worktreeFiles[file] {
+					continue
+				}
+				m.worktreeFiles[file] = true
+				changedFiles = append(changedFiles, file)
+			}
+		}
+	}
+
+	logger.Trace().Msg("Index changed files by directory.")
+
+	m.changedDirs = map[string]string{}
+	for _, file := range changedFiles {
+		for dir := path.Dir(file); ; dir = path.Dir(dir) {
+			if dir == "." {
+				dir = ""
+			}
+			if _, ok := m.changedDirs[dir]; ok {
+				break
+			}
+			m.changedDirs[dir] = file
+			if dir == "" {
+				break
+			}
+		}
+	}
+
 	stackSet := map[project.Path]Entry{}
+	changedReasons := map[project.Path]string{}
 
 	for _, path := range changedFiles {
+		suffix := m.reasonSuffix(path)
 		abspath := filepath.Join(m.root.HostDir(), path)
 		projpath := project.PrjAbsPath(m.root.HostDir(), abspath)
 		triggeredStack, isTriggerFile := trigger.StackPath(projpath)
@@ -186,6 +298,16 @@ func (m *Manager) ListChanged() (*Report, error) {
 				}
 			}
 
+			info, err := trigger.ParseFile(abspath)
+			if err != nil {
+				logger.Warn().Err(err).Msg("unable to parse trigger file, considering it valid")
+			} else if info.Expired(time.Now()) {
+				logger.Debug().
+					Int64("expires_at", info.ExpiresAt).
+					Msg("ignoring expired trigger file")
+				continue
+			}
+
 			cfg, found := m.root.Lookup(triggeredStack)
 			if !found || !cfg.IsStack() {
 				logger.Debug().Msg("trigger path is not a stack, nothing to do")
@@ -197,9 +319,13 @@ func (m *Manager) ListChanged() (*Report, error) {
 				return nil, errors.E(errListChanged, err)
 			}
 
+			if _, ok := stackSet[s.Dir]; ok && suffix != "" {
+				continue
+			}
+
 			stackSet[s.Dir] = Entry{
 				Stack:  s,
-				Reason: "stack has been triggered by: " + projpath.String(),
+				Reason: "stack has been triggered by: " + projpath.String() + suffix,
 			}
 			continue
 		}
@@ -239,9 +365,26 @@ func (m *Manager) ListChanged() (*Report, error) {
 			return nil, errors.E(errListChanged, err)
 		}
 
+		if _, ok := stackSet[s.Dir]; ok && suffix != "" {
+			continue
+		}
+
+		reason, ok := changedReasons[s.Dir]
+		if !ok {
+			reason = "stack has unmerged changes"
+			bumped, why, err := m.remoteModuleBumped(s.Dir)
+			if err != nil {
+				return nil, errors.E(errListChanged, err)
+			}
+			if bumped {
+				reason = "stack changed because " + why
+			}
+			changedReasons[s.Dir] = reason
+		}
+
 		stackSet[s.Dir] = Entry{
 			Stack:  s,
-			Reason: "stack has unmerged changes",
+			Reason: reason + suffix,
 		}
 	}
 
@@ -265,18 +408,50 @@ rangeStacks:
 			Stringer("stack", stack).
 			Msg("Check for changed watch files.")
 
-		if changed, ok := hasChangedWatchedFiles(stack, changedFiles); ok {
+		if watch, changed, ok := hasChangedWatchedFiles(stack, changedFiles); ok {
+			logger.Debug().
+				Stringer("stack", stack).
+				Stringer("watch", watch).
+				Str("watchfile", changed).
+				Msg("changed.")
+
+			reason := fmt.Sprintf("stack changed because watched file %q changed", watch)
+			if watch.String()[1:] != changed {
+				reason = fmt.Sprintf(
+					"stack changed because file %q matching the watched path %q changed",
+					"/"+changed, watch,
+				)
+			}
+
+			stack.IsChanged = true
+			stackSet[stack.Dir] = Entry{
+				Stack:  stack,
+				Reason: reason + m.reasonSuffix(changed),
+			}
+			continue rangeStacks
+		}
+
+		logger.Debug().
+			Stringer("stack", stack).
+			Msg("Check for changed symlink targets.")
+
+		link, target, changedFile, err := m.changedSymlinkTarget(stack, changedFiles)
+		if err != nil {
+			return nil, errors.E(errListChanged, err)
+		}
+		if changedFile != "" {
 			logger.Debug().
 				Stringer("stack", stack).
-				Stringer("watchfile", changed).
+				Stringer("symlink", link).
+				Stringer("target", target).
 				Msg("changed.")
 
 			stack.IsChanged = true
 			stackSet[stack.Dir] = Entry{
 				Stack: stack,
 				Reason: fmt.Sprintf(
-					"stack changed because watched file %q changed",
-					changed,
+					"stack changed because symlink %q target %q changed%s",
+					link, target, m.reasonSuffix(changedFile),
 				),
 			}
 			continue rangeStacks
@@ -286,7 +461,7 @@ rangeStacks:
 			Stringer("stack", stack).
 			Msg("Apply function to stack.")
 
-		err := m.filesApply(stack.HostDir(m.root), func(file fs.DirEntry) error {
+		err = m.filesApply(stack.HostDir(m.root), func(file fs.DirEntry) error {
 			if path.Ext(file.Name()) != ".tf" {
 				return nil
 			}
@@ -302,7 +477,7 @@ rangeStacks:
 				Str("configFile", tfpath).
 				Msg("Parse modules.")
 
-			modules, err := tf.ParseModules(tfpath)
+			modules, err := m.parseModules(tfpath)
 			if err != nil {
 				return errors.E(errListChanged, "parsing modules", err)
 			}
@@ -318,7 +493,7 @@ rangeStacks:
 					Str("configFile", tfpath).
 					Msg("Check if module changed.")
 
-				changed, why, err := m.moduleChanged(mod, stack.HostDir(m.root), make(map[string]bool))
+				changed, why, err := m.stackModuleChanged(mod, stack.HostDir(m.root))
 				if err != nil {
 					return errors.E(errListChanged, err, "checking module %q", mod.Source)
 				}
@@ -392,6 +567,7 @@ func (m *Manager) AddWantedOf(scopeStacks config.List[*config.SortableStack]) (c
 			func(s config.Stack) []string { return s.WantedBy },
 			"wants",
 			func(s config.Stack) []string { return s.Wants },
+			nil,
 			visited,
 		)
 
@@ -450,6 +626,139 @@ func (m *Manager) AddWantedOf(scopeStacks config.List[*config.SortableStack]) (c
 	return selectedStacks, nil
 }
 
+// AddDependentsOf returns the given stacks and all the stacks that must run
+// after them, transitively, as defined by the `after` and `before` ordering
+// attributes.
+func (m *Manager) AddDependentsOf(scopeStacks config.List[*config.SortableStack]) (config.List[*config.SortableStack], error) {
+	orderDag, err := m.buildOrderDAG()
+	if err != nil {
+		return nil, err
+	}
+
+	dependents := map[dag.ID][]dag.ID{}
+	for _, id := range orderDag.IDs() {
+		for _, ancestor := range orderDag.AncestorsOf(id) {
+			dependents[ancestor] = append(dependents[ancestor], id)
+		}
+	}
+
+	return addReachableOf(orderDag, scopeStacks, func(id dag.ID) []dag.ID {
+		return dependents[id]
+	}), nil
+}
+
+// AddDependenciesOf returns the given stacks and all the stacks that must run
+// before them, transitively, as defined by the `after` and `before` ordering
+// attributes.
+func (m *Manager) AddDependenciesOf(scopeStacks config.List[*config.SortableStack]) (config.List[*config.SortableStack], error) {
+	orderDag, err := m.buildOrderDAG()
+	if err != nil {
+		return nil, err
+	}
+	return addReachableOf(orderDag, scopeStacks, orderDag.AncestorsOf), nil
+}
+
+// buildOrderDAG builds the DAG of the ordering attributes of all the stacks.
+func (m *Manager) buildOrderDAG() (*dag.DAG, error) {
+	logger := log.With().
+		Str("action", "manager.buildOrderDAG").
+		Logger()
+
+	orderDag := dag.New()
+	allstacks, err := config.LoadAllStacks(m.root.Tree())
+	if err != nil {
+		return nil, errors.E(err, "loading all stacks")
+	}
+
+	var inferred run.InferredOrder
+	if run.InferOrderEnabled(m.root) {
+		inferred, err = run.InferOrder(m.root)
+		if err != nil {
+			return nil, errors.E(err, "inferring order of execution")
+		}
+	}
+
+	visited := dag.Visited{}
+	sort.Sort(allstacks)
+	for _, elem := range allstacks {
+		logger.Trace().
+			Stringer("stack", elem.Dir()).
+			Msg("Building dag")
+
+		err := run.BuildDAG(
+			orderDag,
+			m.root,
+			elem.Stack,
+			"before",
+			func(s config.Stack) []string { return s.Before },
+			"after",
+			func(s config.Stack) []string { return s.After },
+			inferred,
+			visited,
+		)
+
+		if err != nil {
+			return nil, errors.E(err, "building order DAG")
+		}
+	}
+
+	logger.Trace().Msg("Validating DAG.")
+
+	reason, err := orderDag.Validate()
+	if err != nil {
+		// the cycles are reported when computing the order of execution.
+		logger.Warn().
+			Str("reason", reason).
+			Err(err).
+			Msg("The stack ordering clauses (after/before) have errors (ignored)")
+	}
+	return orderDag, nil
+}
+
+// addReachableOf returns the scope stacks and all the stacks reachable from
+// them by following the edges returned by next.
+func addReachableOf(
+	d *dag.DAG,
+	scopeStacks config.List[*config.SortableStack],
+	next func(dag.ID) []dag.ID,
+) config.List[*config.SortableStack] {
+	var selectedStacks config.List[*config.SortableStack]
+	visited := dag.Visited{}
+
+	var pending []dag.ID
+	for _, s := range scopeStacks {
+		pending = append(pending, dag.ID(s.Dir().String()))
+	}
+
+	for len(pending) > 0 {
+		id := pending[0]
+		pending = pending[1:]
+
+		if _, ok := visited[id]; ok {
+			continue
+		}
+		visited[id] = struct{}{}
+
+		// a node only referenced by the edges of other nodes has no stack.
+		node, err := d.Node(id)
+		if err != nil {
+			continue
+		}
+		st, ok := node.(*config.Stack)
+		if !ok || st == nil {
+			continue
+		}
+		selectedStacks = append(selectedStacks, st.Sortable())
+
+		for _, other := range next(id) {
+			if _, ok := visited[other]; !ok {
+				pending = append(pending, other)
+			}
+		}
+	}
+	return selectedStacks
+}
+
 func (m *Manager) filesApply(dir string, apply func(file fs.DirEntry) error) error {
 	logger := log.With().
 		Str("action", "filesApply()").
@@ -492,10 +801,6 @@ func (m *Manager) moduleChanged(
 		Str("action", "moduleChanged()").
 		Logger()
 
-	if _, ok := visited[mod.Source]; ok {
-		return false, "", nil
-	}
-
 	logger.Trace().
 		Str("path", basedir).
 		Msg("Check if module source is local directory.")
@@ -514,28 +819,52 @@ func (m *Manager) moduleChanged(
 		Str("path", modPath).
 		Msg("Get module path info.")
 	st, err := os.Stat(modPath)
-
-	// TODO(i4k): resolve symlinks
-
 	if err != nil || !st.IsDir() {
 		return false, "", errors.E("\"source\" path %q is not a directory", modPath)
 	}
 
-	logger.Debug().
+	logger.Trace().
 		Str("path", modPath).
-		Msg("Get list of changed files.")
-	changedFiles, err := m.listChangedFiles(modPath, m.gitBaseRef)
+		Msg("Resolve module path symlinks.")
+	realModPath, symlinked, err := m.resolveSymlinks(modPath)
 	if err != nil {
-		return false, "", errors.E(err,
-			"listing changes in the module %q",
-			mod.Source)
+		return false, "", errors.E(err, "resolving symlinks of module %q", mod.Source)
 	}
 
-	if len(changedFiles) > 0 {
-		return true, fmt.Sprintf("module %q has unmerged changes", mod.Source), nil
+	modTarget, ok := m.projectPath(realModPath)
+	if !ok {
+		logger.Warn().
+			Str("path", modPath).
+			Str("target", realModPath).
+			Msg("ignoring module symlinked to outside the project root")
+		return false, "", nil
 	}
 
-	visited[mod.Source] = true
+	if _, ok := visited[realModPath]; ok {
+		return false, "", nil
+	}
+
+	modName := fmt.Sprintf("%q", mod.Source)
+	if symlinked {
+		modName = fmt.Sprintf("%q (symlink to %q)", mod.Source, modTarget)
+	}
+
+	logger.Debug().
+		Str("path", realModPath).
+		Msg("Check if module has changed files.")
+
+	if file, ok := m.changedDirs[modTarget.String()[1:]]; ok {
+		bumped, why, err := m.remoteModuleBumped(modTarget)
+		if err != nil {
+			return false, "", err
+		}
+		if bumped {
+			return true, fmt.Sprintf("module %s changed because %s", modName, why), nil
+		}
+		return true, fmt.Sprintf("module %s has unmerged changes%s", modName, m.reasonSuffix(file)), nil
+	}
+
+	visited[realModPath] = true
 
 	logger.Debug().
 		Str("path", modPath).
@@ -551,7 +880,7 @@ func (m *Manager) moduleChanged(
 		logger.Trace().
 			Str("path", modPath).
 			Msg("Parse modules.")
-		modules, err := tf.ParseModules(filepath.Join(modPath, file.Name()))
+		modules, err := m.parseModules(filepath.Join(modPath, file.Name()))
 		if err != nil {
 			return errors.E(err, "parsing module %q", mod.Source)
 		}
@@ -586,7 +915,259 @@ func (m *Manager) moduleChanged(
 		return false, "", err
 	}
 
-	return changed, fmt.Sprintf("module %q changed because %s", mod.Source, why), nil
+	return changed, fmt.Sprintf("module %s changed because %s", modName, why), nil
+}
+
+// stackModuleChanged is the memoized [Manager.moduleChanged] for the modules
+// used by the stacks. Only the results of the top level calls are memoized
+// because the results of the recursive calls depend on the visited modules.
+func (m *Manager) stackModuleChanged(mod tf.Module, stackdir string) (bool, string, error) {
+	key := filepath.Join(stackdir, mod.Source) + "|" + mod.Source
+	if res, ok := m.changedModules[key]; ok {
+		return res.changed, res.why, nil
+	}
+	changed, why, err := m.moduleChanged(mod, stackdir, make(map[string]bool))
+	if err != nil {
+		return false, "", err
+	}
+	m.changedModules[key] = moduleChange{changed: changed, why: why}
+	return changed, why, nil
+}
+
+// tfParseModules parses the modules of a Terraform file. It's replaced in the
+// tests for checking the memoization.
+var tfParseModules = tf.ParseModules
+
+// parseModules is the memoized [tf.ParseModules].
+func (m *Manager) parseModules(tfpath string) ([]tf.Module, error) {
+	if modules, ok := m.parsedModules[tfpath]; ok {
+		return modules, nil
+	}
+	modules, err := tfParseModules(tfpath)
+	if err != nil {
+		return nil, err
+	}
+	m.parsedModules[tfpath] = modules
+	return modules, nil
+}
+
+// remoteModuleBumped checks if the version or the source ref of any of the
+// remote modules used by the Terraform files of the prjdir directory changed
+// since the git base ref. Only the modules present in both revisions are
+// compared. It always returns false if [Manager.ExplainModuleBumps] is not set
+// and the results are memoized by directory.
+func (m *Manager) remoteModuleBumped(prjdir project.Path) (bumped bool, why string, err error) {
+	logger := log.With().
+		Str("action", "remoteModuleBumped()").
+		Stringer("path", prjdir).
+		Logger()
+
+	if !m.explainModuleBumps {
+		return false, "", nil
+	}
+	if res, ok := m.bumpedModules[prjdir]; ok {
+		return res.changed, res.why, nil
+	}
+
+	if m.baseRev == "" {
+		m.baseRev, err = m.rootGit.RevParse(m.gitBaseRef)
+		if err != nil {
+			return false, "", errors.E(err, "getting revision %q", m.gitBaseRef)
+		}
+	}
+
+	rootdir, err := m.rootRealPath()
+	if err != nil {
+		return false, "", err
+	}
+	dir := filepath.Join(rootdir, filepath.FromSlash(prjdir.String()))
+
+	err = m.filesApply(dir, func(file fs.DirEntry) error {
+		if bumped || path.Ext(file.Name()) != ".tf" {
+			return nil
+		}
+
+		modules, err := m.parseModules(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return errors.E(err, "parsing modules")
+		}
+
+		content, err := m.rootGit.ShowFile(m.baseRev, path.Join(prjdir.String()[1:], file.Name()))
+		if err != nil {
+			logger.Debug().
+				Str("file", file.Name()).
+				Msg("file not found in the base revision")
+			return nil
+		}
+
+		baseModules, err := tf.ParseModulesContent(file.Name(), []byte(content))
+		if err != nil {
+			logger.Debug().
+				Err(err).
+				Str("file", file.Name()).
+				Msg("ignoring file with errors in the base revision")
+			return nil
+		}
+
+		baseByName := map[string]tf.Module{}
+		for _, mod := range baseModules {
+			baseByName[mod.Name] = mod
+		}
+
+		for _, mod := range modules {
+			base, ok := baseByName[mod.Name]
+			if !ok || mod.IsLocal() || base.IsLocal() {
+				continue
+			}
+			if base.Ref() != mod.Ref() {
+				bumped = true
+				why = fmt.Sprintf("module %q ref %s -> %s",
+					mod.Name, noneIfEmpty(base.Ref()), noneIfEmpty(mod.Ref()))
+				return nil
+			}
+			if base.Version != mod.Version {
+				bumped = true
+				why = fmt.Sprintf("module %q version %s -> %s",
+					mod.Name, noneIfEmpty(base.Version), noneIfEmpty(mod.Version))
+				return nil
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return false, "", errors.E(err, "checking remote modules of %s", prjdir)
+	}
+	m.bumpedModules[prjdir] = moduleChange{changed: bumped, why: why}
+	return bumped, why, nil
+}
+
+// reasonSuffix returns the suffix of the change reasons of the changed file.
+func (m *Manager) reasonSuffix(file string) string {
+	if m.worktreeFiles[file] {
+		return uncommittedSuffix
+	}
+	return ""
+}
+
+func noneIfEmpty(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
+
+// changedSymlinkTarget checks if the target of any of the symlinks inside the
+// stack directory has changed. The symlinks inside child stacks and dot
+// directories, and the ones pointing to outside the project root, are ignored.
+// It returns the symlink and its target, both as project paths, and the changed
+// file, which is empty if no symlink target changed.
+func (m *Manager) changedSymlinkTarget(
+	stack *config.Stack, changedFiles []string,
+) (link project.Path, target project.Path, changedFile string, err error) {
+	logger := log.With().
+		Str("action", "changedSymlinkTarget()").
+		Stringer("stack", stack.Dir).
+		Logger()
+
+	stackdir := stack.HostDir(m.root)
+	err = filepath.WalkDir(stackdir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path == stackdir {
+				return nil
+			}
+			if strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			cfg, ok := m.root.Lookup(project.PrjAbsPath(m.root.HostDir(), path))
+			if ok && cfg.IsStack() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type()&fs.ModeSymlink == 0 {
+			return nil
+		}
+
+		realPath, _, err := m.resolveSymlinks(path)
+		if err != nil {
+			logger.Debug().
+				Err(err).
+				Str("path", path).
+				Msg("ignoring broken symlink")
+			return nil
+		}
+
+		linkTarget, ok := m.projectPath(realPath)
+		if !ok {
+			logger.Debug().
+				Str("path", path).
+				Str("target", realPath).
+				Msg("ignoring symlink to outside the project root")
+			return nil
+		}
+
+		relTarget := linkTarget.String()[1:]
+		for _, file := range changedFiles {
+			if relTarget == "" || file == relTarget || strings.HasPrefix(file, relTarget+"/") {
+				link = project.PrjAbsPath(m.root.HostDir(), path)
+				target = linkTarget
+				changedFile = file
+				return fs.SkipAll
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return project.Path{}, project.Path{}, "", errors.E(err, "checking symlinks of stack %s", stack.Dir)
+	}
+	return link, target, changedFile, nil
+}
+
+// resolveSymlinks returns the path with all its symlinks resolved and if any
+// symlink was resolved, ignoring the ones of the project root itself.
+func (m *Manager) resolveSymlinks(path string) (string, bool, error) {
+	realPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", false, err
+	}
+	rootdir, err := m.rootRealPath()
+	if err != nil {
+		return "", false, err
+	}
+	relpath, err := filepath.Rel(m.root.HostDir(), path)
+	if err != nil {
+		return "", false, errors.E(err, "path %q is not inside the project root", path)
+	}
+	return realPath, filepath.Join(rootdir, relpath) != realPath, nil
+}
+
+// projectPath returns the project path of the real path, which must have its
+// symlinks resolved. It returns false if the path is outside the project root.
+func (m *Manager) projectPath(realPath string) (project.Path, bool) {
+	rootdir, err := m.rootRealPath()
+	if err != nil {
+		return project.Path{}, false
+	}
+	relpath, err := filepath.Rel(rootdir, realPath)
+	if err != nil || relpath == ".." || strings.HasPrefix(relpath, ".."+string(filepath.Separator)) {
+		return project.Path{}, false
+	}
+	return project.PrjAbsPath(rootdir, realPath), true
+}
+
+func (m *Manager) rootRealPath() (string, error) {
+	if m.realRootDir != "" {
+		return m.realRootDir, nil
+	}
+	rootdir, err := filepath.EvalSymlinks(m.root.HostDir())
+	if err != nil {
+		return "", errors.E(err, "resolving symlinks of the project root")
+	}
+	m.realRootDir = rootdir
+	return rootdir, nil
 }
 
 // listChangedFiles lists all changed files in the dir directory.
@@ -658,15 +1239,42 @@ func (m *Manager) globalGit() (*git.Git, error) {
 	return m.outerGit, err
 }
 
-func hasChangedWatchedFiles(stack *config.Stack, changedFiles []string) (project.Path, bool) {
-	for _, watchFile := range stack.Watch {
+// hasChangedWatchedFiles returns the first changed file matching the watch
+// entries of the stack and the matched entry. An entry matches the files inside
+// the directories it matches, and a "**" element of a glob pattern matches
+// zero or more directories.
+func hasChangedWatchedFiles(stack *config.Stack, changedFiles []string) (project.Path, string, bool) {
+	for _, watch := range stack.Watch {
+		pattern := strings.Split(watch.String()[1:], "/") // project paths
 		for _, file := range changedFiles {
-			if file == watchFile.String()[1:] { // project paths
-				return watchFile, true
+			if matchWatch(pattern, strings.Split(file, "/")) {
+				return watch, file, true
 			}
 		}
 	}
-	return project.Path{}, false
+	return project.Path{}, "", false
+}
+
+func matchWatch(pattern, elems []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(elems); i++ {
+				if matchWatch(pattern[1:], elems[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(elems) == 0 {
+			return false
+		}
+		if matched, _ := path.Match(pattern[0], elems[0]); !matched {
+			return false
+		}
+		pattern, elems = pattern[1:], elems[1:]
+	}
+	// the remaining elements are inside the matched directory.
+	return true
 }
 
 func checkRepoIsClean(g *git.Git) (RepoChecks, error) {
@@ -0,0 +1,82 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package stack_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/config"
+	"github.com/terramate-io/terramate/stack"
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+func BenchmarkListChangedSharedModules(b *testing.B) {
+	// benchmarks the case of a lot of stacks using the same chain of local
+	// modules, where only one stack changed.
+	benchmarkListChangedSharedModules(b, "stacks/stack-0", 1)
+}
+
+func BenchmarkListChangedSharedModulesChanged(b *testing.B) {
+	// benchmarks the case of a lot of stacks using the same chain of local
+	// modules, where the last module of the chain changed.
+	benchmarkListChangedSharedModules(b, "modules/mod4", 100)
+}
+
+func benchmarkListChangedSharedModules(b *testing.B, changedDir string, wantChanged int) {
+	b.StopTimer()
+	s := newSharedModulesSandbox(b, 100, 5, changedDir)
+
+	root, err := config.LoadRoot(s.RootDir())
+	assert.NoError(b, err)
+
+	b.StartTimer()
+	for i := 0; i < b.N; i++ {
+		m := stack.NewManager(root, "origin/main")
+		report, err := m.ListChanged()
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(report.Stacks) != wantChanged {
+			b.Fatalf("want %d changed stacks but got %d", wantChanged, len(report.Stacks))
+		}
+	}
+}
+
+// newSharedModulesSandbox creates a project with nstacks stacks using the same
+// chain of nmodules local modules, with a commit changing the changedDir
+// compared to origin/main.
+func newSharedModulesSandbox(t testing.TB, nstacks, nmodules int, changedDir string) sandbox.S {
+	s := sandbox.New(t)
+
+	for i := 0; i < nmodules; i++ {
+		mod := s.CreateModule(fmt.Sprintf("modules/mod%d", i))
+		if i == nmodules-1 {
+			mod.CreateFile("main.tf", "# last module")
+			continue
+		}
+		mod.CreateFile("main.tf", `
+module "next" {
+  source = "../mod%d"
+}`, i+1)
+	}
+
+	for i := 0; i < nstacks; i++ {
+		st := s.CreateStack(fmt.Sprintf("stacks/stack-%d", i))
+		st.CreateFile("main.tf", `
+module "mod" {
+  source = "../../modules/mod0"
+}`)
+	}
+
+	git := s.Git()
+	git.CommitAll("all")
+	git.Push("main")
+	git.CheckoutNew("change")
+
+	s.DirEntry(changedDir).CreateFile("changed.txt", "changed")
+	git.CommitAll("change " + changedDir)
+	return s
+}
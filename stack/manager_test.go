@@ -14,6 +14,8 @@ import (
 	"github.com/terramate-io/terramate/project"
 	"github.com/terramate-io/terramate/stack"
 	"github.com/terramate-io/terramate/test"
+	"github.com/terramate-io/terramate/test/sandbox"
+	"github.com/terramate-io/terramate/tf"
 )
 
 type repository struct {
@@ -558,3 +560,57 @@ func createStack(t *testing.T, root *config.Root, absdir string) {
 	dir := project.PrjAbsPath(root.HostDir(), absdir)
 	assert.NoError(t, stack.Create(root, config.Stack{Dir: dir}), "terramate init failed")
 }
+
+func TestListChangedParsesSharedModulesOnce(t *testing.T) {
+	// not parallel: it replaces the module parser of the package.
+	parsed := map[string]int{}
+	restore := stack.SetParseModules(func(path string) ([]tf.Module, error) {
+		parsed[path]++
+		return tf.ParseModules(path)
+	})
+	defer restore()
+
+	// the stacks use the chain mod0 -> mod1 -> mod2 starting from different
+	// modules, then mod1 is reached by different paths.
+	s := sandbox.New(t)
+	s.CreateModule("modules/mod0").CreateFile("main.tf", `
+module "next" {
+  source = "../mod1"
+}`)
+	s.CreateModule("modules/mod1").CreateFile("main.tf", `
+module "next" {
+  source = "../mod2"
+}`)
+	s.CreateModule("modules/mod2").CreateFile("main.tf", "# last module")
+
+	const nstacks = 10
+	for i := 0; i < nstacks; i++ {
+		st := s.CreateStack(fmt.Sprintf("stacks/stack-%d", i))
+		st.CreateFile("main.tf", `
+module "mod" {
+  source = "../../modules/mod%d"
+}`, i%3)
+	}
+
+	git := s.Git()
+	git.CommitAll("all")
+	git.Push("main")
+	git.CheckoutNew("change")
+
+	s.DirEntry("modules/mod2").CreateFile("changed.txt", "changed")
+	git.CommitAll("change modules/mod2")
+
+	root, err := config.LoadRoot(s.RootDir())
+	assert.NoError(t, err)
+
+	m := stack.NewManager(root, "origin/main")
+	report, err := m.ListChanged()
+	assert.NoError(t, err)
+	assert.EqualInts(t, nstacks, len(report.Stacks))
+
+	// mod2 is not parsed because it's changed.
+	for _, mod := range []string{"mod0", "mod1"} {
+		path := filepath.Join(s.RootDir(), "modules", mod, "main.tf")
+		assert.EqualInts(t, 1, parsed[path], "module file %s parsed %d times", path, parsed[path])
+	}
+}
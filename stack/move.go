@@ -0,0 +1,158 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package stack
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/terramate-io/terramate/config"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/project"
+	"github.com/terramate-io/terramate/stack/trigger"
+)
+
+// ErrMove indicates that the stack can't be moved.
+const ErrMove errors.Kind = "moving stack"
+
+// MoveReport is the report of a stack move.
+type MoveReport struct {
+	// UpdatedFiles are the files which had references to the moved stacks
+	// rewritten, with their paths after the move.
+	UpdatedFiles project.Paths
+
+	// MovedTriggers tells if the triggers of the moved stacks were moved.
+	MovedTriggers bool
+}
+
+// Move moves the stack at src, together with its child stacks, to dst.
+//
+// - src must be a stack other than the project root (fail otherwise)
+// - dst must not exist and must not be inside src (fail otherwise)
+// - The after, before, wants and wanted_by entries of all the stacks which
+// refer to the moved stacks, and the relative entries of the moved stacks, are
+// rewritten keeping the comments of the files.
+// - The triggers of the moved stacks are moved.
+//
+// The root configuration is not reloaded, the caller must reload it before
+// using it again.
+func Move(root *config.Root, src, dst project.Path) (MoveReport, error) {
+	logger := log.With().
+		Str("action", "stack.Move()").
+		Stringer("src", src).
+		Stringer("dst", dst).
+		Logger()
+
+	logger.Trace().Msg("moving stack, checking invariants")
+
+	if src.String() == "/" {
+		return MoveReport{}, errors.E(ErrMove, "the project root can't be moved")
+	}
+	node, ok := root.Lookup(src)
+	if !ok || !node.IsStack() {
+		return MoveReport{}, errors.E(ErrMove, "src %s must be a stack", src)
+	}
+	if dst == src || isInside(dst, src) {
+		return MoveReport{}, errors.E(ErrMove, "dst %s must not be inside src %s", dst, src)
+	}
+	if strings.HasPrefix(path.Base(dst.String()), ".") {
+		return MoveReport{}, errors.E(ErrInvalidStackDir, "dot directories not allowed")
+	}
+	dstdir := dst.HostPath(root.HostDir())
+	if _, err := os.Lstat(dstdir); err == nil {
+		return MoveReport{}, errors.E(ErrMove, "dst %s already exists", dst)
+	}
+
+	stacks, err := config.LoadAllStacks(root.Tree())
+	if err != nil {
+		return MoveReport{}, errors.E(ErrMove, err)
+	}
+
+	moved := func(p project.Path) project.Path {
+		return rebasePath(p, src, dst)
+	}
+
+	// files maps the files defining the stacks references, with their paths
+	// after the move, to the directory of their stack before the move.
+	files := map[project.Path]project.Path{}
+	for _, elem := range stacks {
+		st := elem.Stack
+		for _, attr := range refAttrs {
+			for _, rng := range stackRefRanges(root, st, attr) {
+				files[moved(project.PrjAbsPath(root.HostDir(), rng.HostPath()))] = st.Dir
+			}
+		}
+	}
+
+	logger.Trace().Msg("moving stack directory")
+
+	if err := os.MkdirAll(filepath.Dir(dstdir), createDirMode); err != nil {
+		return MoveReport{}, errors.E(ErrMove, err, "creating parent directories of %s", dst)
+	}
+	if err := os.Rename(src.HostPath(root.HostDir()), dstdir); err != nil {
+		return MoveReport{}, errors.E(ErrMove, err)
+	}
+
+	var report MoveReport
+
+	logger.Trace().Msg("rewriting stacks references")
+
+	filenames := make(project.Paths, 0, len(files))
+	for file := range files {
+		filenames = append(filenames, file)
+	}
+	sort.Slice(filenames, func(i, j int) bool {
+		return filenames[i].String() < filenames[j].String()
+	})
+
+	for _, file := range filenames {
+		oldStackDir := files[file]
+		newStackDir := moved(oldStackDir)
+		changed, err := rewriteRefs(file.HostPath(root.HostDir()), func(_, entry string) (string, bool) {
+			return relocateRef(oldStackDir, newStackDir, entry, moved), true
+		})
+		if err != nil {
+			return MoveReport{}, errors.E(ErrMove, err)
+		}
+		if changed {
+			logger.Debug().
+				Stringer("file", file).
+				Msg("stacks references rewritten")
+
+			report.UpdatedFiles = append(report.UpdatedFiles, file)
+		}
+	}
+
+	logger.Trace().Msg("moving triggers")
+
+	report.MovedTriggers, err = trigger.MoveStack(root, src, dst)
+	if err != nil {
+		return MoveReport{}, errors.E(ErrMove, err)
+	}
+	return report, nil
+}
+
+// rebasePath returns the path p rebased from the src directory to dst, if p
+// is src or is inside it, or p otherwise.
+func rebasePath(p, src, dst project.Path) project.Path {
+	if p == src {
+		return dst
+	}
+	if isInside(p, src) {
+		return project.NewPath(dst.String() + strings.TrimPrefix(p.String(), src.String()))
+	}
+	return p
+}
+
+// isInside tells if the path p is inside the dir, not including the dir itself.
+func isInside(p, dir project.Path) bool {
+	if dir.String() == "/" {
+		return p.String() != "/"
+	}
+	return strings.HasPrefix(p.String(), dir.String()+"/")
+}
@@ -0,0 +1,136 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package stack_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/config"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/project"
+	"github.com/terramate-io/terramate/stack"
+	"github.com/terramate-io/terramate/stack/trigger"
+	"github.com/terramate-io/terramate/test"
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+func TestStackMove(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.NoGit(t)
+	s.BuildTree([]string{
+		`s:stacks/a:after=["/stacks/b"]`,
+		"s:stacks/b",
+		`s:stacks/b/child:after=["../../c"]`,
+	})
+	s.RootEntry().CreateFile("stacks/c/stack.tm.hcl", `stack {
+  # the child must run first
+  after = [
+    "../b/child", # child comment
+    "/stacks/a",
+  ]
+  wants = ["/stacks/b"]
+}
+`)
+
+	root, err := config.LoadRoot(s.RootDir())
+	assert.NoError(t, err)
+	assert.NoError(t, trigger.Create(root, project.NewPath("/stacks/b/child"), "pending"))
+
+	report, err := stack.Move(root, project.NewPath("/stacks/b"), project.NewPath("/apps/b2"))
+	assert.NoError(t, err)
+	assert.IsTrue(t, report.MovedTriggers, "triggers must be moved")
+	assert.EqualStrings(t,
+		"/apps/b2/child/terramate.tm.hcl /stacks/a/terramate.tm.hcl /stacks/c/stack.tm.hcl",
+		strings.Join(report.UpdatedFiles.Strings(), " "))
+
+	test.AssertFileContentEquals(t, filepath.Join(s.RootDir(), "stacks/c/stack.tm.hcl"), `stack {
+  # the child must run first
+  after = [
+    "../../apps/b2/child", # child comment
+    "/stacks/a",
+  ]
+  wants = ["/apps/b2"]
+}
+`)
+
+	root, err = config.LoadRoot(s.RootDir())
+	assert.NoError(t, err)
+
+	a, err := config.LoadStack(root, project.NewPath("/stacks/a"))
+	assert.NoError(t, err)
+	assert.EqualStrings(t, "/apps/b2", strings.Join(a.After, " "))
+
+	child, err := config.LoadStack(root, project.NewPath("/apps/b2/child"))
+	assert.NoError(t, err)
+	assert.EqualStrings(t, "../../../stacks/c", strings.Join(child.After, " "))
+
+	_, ok := root.Lookup(project.NewPath("/stacks/b"))
+	assert.IsTrue(t, !ok, "old stack dir must not exist")
+
+	triggers, err := trigger.List(root)
+	assert.NoError(t, err)
+	assert.EqualInts(t, 1, len(triggers))
+	assert.EqualStrings(t, "/apps/b2/child", triggers[0].Stack.String())
+}
+
+func TestStackMoveFails(t *testing.T) {
+	t.Parallel()
+
+	type testcase struct {
+		name string
+		src  string
+		dst  string
+		want error
+	}
+
+	for _, tc := range []testcase{
+		{
+			name: "root can't be moved",
+			src:  "/",
+			dst:  "/other",
+			want: errors.E(stack.ErrMove),
+		},
+		{
+			name: "src is not a stack",
+			src:  "/dir",
+			dst:  "/other",
+			want: errors.E(stack.ErrMove),
+		},
+		{
+			name: "dst exists",
+			src:  "/stack",
+			dst:  "/dir",
+			want: errors.E(stack.ErrMove),
+		},
+		{
+			name: "dst inside src",
+			src:  "/stack",
+			dst:  "/stack/inner",
+			want: errors.E(stack.ErrMove),
+		},
+		{
+			name: "dst is a dot dir",
+			src:  "/stack",
+			dst:  "/.stack",
+			want: errors.E(stack.ErrInvalidStackDir),
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := sandbox.NoGit(t)
+			s.BuildTree([]string{
+				"s:stack",
+				"d:dir",
+			})
+			_, err := stack.Move(s.Config(), project.NewPath(tc.src), project.NewPath(tc.dst))
+			assert.IsError(t, err, tc.want)
+		})
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package stack
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	hhcl "github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/terramate-io/terramate/config"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/hcl"
+	"github.com/terramate-io/terramate/hcl/info"
+	"github.com/terramate-io/terramate/project"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// refAttrs are the attributes of the stack block which refer to other stacks.
+var refAttrs = []string{"after", "before", "wants", "wanted_by"}
+
+// stackRefRanges returns the range of each entry of the stack attribute
+// referring to other stacks, indexed by the entry.
+func stackRefRanges(root *config.Root, st *config.Stack, attr string) map[string]info.Range {
+	tree, ok := root.Lookup(st.Dir)
+	if !ok {
+		return nil
+	}
+	return tree.StackEntryRanges(attr)
+}
+
+// refRewriter returns the new value of the entry of the given stack block
+// attribute, or false if the entry must be removed. Returning the same entry
+// keeps it unchanged.
+type refRewriter func(attr, entry string) (string, bool)
+
+// rewriteRefs rewrites the entries of the attributes referring to other stacks
+// of the stack blocks defined in the file. Only the entries which are plain
+// string literals are rewritten and the comments and formatting of the file
+// are kept. It returns true if the file was changed.
+func rewriteRefs(filename string, rewrite refRewriter) (bool, error) {
+	st, err := os.Lstat(filename)
+	if err != nil {
+		return false, errors.E(err, "stating file %s", filename)
+	}
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return false, errors.E(err, "reading file %s", filename)
+	}
+	parsed, diags := hclwrite.ParseConfig(content, filename, hhcl.InitialPos)
+	if diags.HasErrors() {
+		return false, errors.E(diags, "parsing file %s", filename)
+	}
+
+	changed := false
+	for _, block := range parsed.Body().Blocks() {
+		if block.Type() != hcl.StackBlockType {
+			continue
+		}
+		for _, attrName := range refAttrs {
+			attr := block.Body().GetAttribute(attrName)
+			if attr == nil {
+				continue
+			}
+			tokens := attr.Expr().BuildTokens(nil)
+			attrChanged := false
+
+			// the entries are visited backwards, so the removal of an entry
+			// doesn't change the position of the entries yet to be visited.
+			for i := len(tokens) - 3; i >= 0; i-- {
+				if tokens[i].Type != hclsyntax.TokenOQuote ||
+					tokens[i+1].Type != hclsyntax.TokenQuotedLit ||
+					tokens[i+2].Type != hclsyntax.TokenCQuote {
+					continue
+				}
+				lit := tokens[i+1]
+				entry := string(lit.Bytes)
+				if strings.ContainsAny(entry, `\$%`) {
+					// escaped or templated entries are kept as is.
+					continue
+				}
+				newEntry, keep := rewrite(attrName, entry)
+				if !keep {
+					tokens = removeListElem(tokens, i, i+2)
+					attrChanged = true
+					continue
+				}
+				if newEntry == entry {
+					continue
+				}
+				lit.Bytes = quotedLitBytes(newEntry)
+				attrChanged = true
+			}
+
+			if attrChanged {
+				block.Body().SetAttributeRaw(attrName, tokens)
+				changed = true
+			}
+		}
+	}
+
+	if !changed {
+		return false, nil
+	}
+	if err := os.WriteFile(filename, parsed.Bytes(), st.Mode()); err != nil {
+		return false, errors.E(err, "writing file %s", filename)
+	}
+	return true, nil
+}
+
+// removeListElem removes the list element defined by the tokens from start to
+// end, together with its separator. An element defined in its own line is
+// removed with the whole line, including its comments.
+func removeListElem(tokens hclwrite.Tokens, start, end int) hclwrite.Tokens {
+	isLineEnd := func(i int) bool {
+		return i < len(tokens) &&
+			(tokens[i].Type == hclsyntax.TokenNewline || tokens[i].Type == hclsyntax.TokenComment)
+	}
+
+	removePrevComma := -1
+	if end+1 < len(tokens) && tokens[end+1].Type == hclsyntax.TokenComma {
+		end++
+		if !isLineEnd(end+1) && end+1 < len(tokens) {
+			tokens[end+1].SpacesBefore = tokens[start].SpacesBefore
+		}
+	} else {
+		// last element without trailing comma, then the separator is the
+		// comma before it.
+		for j := start - 1; j >= 0; j-- {
+			if isLineEnd(j) {
+				continue
+			}
+			if tokens[j].Type == hclsyntax.TokenComma {
+				removePrevComma = j
+			}
+			break
+		}
+	}
+	if start > 0 && isLineEnd(start-1) && isLineEnd(end+1) {
+		end++
+	}
+
+	var res hclwrite.Tokens
+	for i, tok := range tokens {
+		if (i >= start && i <= end) || i == removePrevComma {
+			continue
+		}
+		res = append(res, tok)
+	}
+	return res
+}
+
+func quotedLitBytes(s string) []byte {
+	for _, tok := range hclwrite.TokensForValue(cty.StringVal(s)) {
+		if tok.Type == hclsyntax.TokenQuotedLit {
+			return append([]byte(nil), tok.Bytes...)
+		}
+	}
+	return nil
+}
+
+// resolveRef resolves the entry of a stack attribute referring to another
+// stack, as defined in the stack at stackdir. It returns false for the tag
+// filters, which don't refer to a single stack.
+func resolveRef(stackdir project.Path, entry string) (project.Path, bool) {
+	if strings.HasPrefix(entry, "tag:") {
+		return project.Path{}, false
+	}
+	if path.IsAbs(entry) {
+		return project.NewPath(path.Clean(entry)), true
+	}
+	return project.NewPath(path.Join(stackdir.String(), entry)), true
+}
+
+// relocateRef returns the entry of a stack attribute, defined in the stack at
+// oldStackDir, for the stack at newStackDir, where the referred stack is given
+// by the relocate function. The entry is kept unchanged if it already resolves
+// to the relocated stack.
+func relocateRef(oldStackDir, newStackDir project.Path, entry string, relocate func(project.Path) project.Path) string {
+	target, ok := resolveRef(oldStackDir, entry)
+	if !ok {
+		return entry
+	}
+	newTarget := relocate(target)
+	if resolved, _ := resolveRef(newStackDir, entry); resolved == newTarget {
+		return entry
+	}
+	return formatRef(newStackDir, newTarget, entry)
+}
+
+// formatRef formats the reference to the target stack, as defined in the stack
+// at stackdir, keeping the style of the original entry: absolute or relative.
+func formatRef(stackdir, target project.Path, original string) string {
+	if path.IsAbs(original) {
+		return target.String()
+	}
+	rel, err := filepath.Rel(filepath.FromSlash(stackdir.String()), filepath.FromSlash(target.String()))
+	if err != nil {
+		return target.String()
+	}
+	return filepath.ToSlash(rel)
+}
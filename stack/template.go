@@ -0,0 +1,271 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package stack
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	hhcl "github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/rs/zerolog/log"
+	"github.com/terramate-io/terramate/config"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/globals"
+	"github.com/terramate-io/terramate/hcl"
+	"github.com/terramate-io/terramate/hcl/eval"
+	"github.com/terramate-io/terramate/stdlib"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// ErrTemplate indicates that the stack template is invalid or failed to render.
+const ErrTemplate errors.Kind = "invalid stack template"
+
+type renderedFile struct {
+	relpath string
+	content []byte
+	mode    fs.FileMode
+}
+
+// CreateFromTemplate creates the provided stack on the filesystem with the
+// files of the templatedir. Every template file is rendered as an HCL template
+// with the terramate, global and var namespaces available, where the globals
+// are the ones of the stack directory and the var namespace has the provided
+// vars.
+//
+// If the template has no stack file (see [DefaultFilename]), the stack file is
+// created as in [Create], otherwise the rendered stack file must define the
+// stack block. The import paths are added to the stack file in both cases.
+//
+// The stack is not created if the template fails to render, if any of the
+// template files already exist in the stack directory or if the resulting
+// stack configuration is invalid, and then all the files and directories
+// created for the stack are removed.
+func CreateFromTemplate(
+	root *config.Root,
+	stack config.Stack,
+	templatedir string,
+	vars map[string]string,
+	imports ...string,
+) (err error) {
+	logger := log.With().
+		Str("action", "stack.CreateFromTemplate()").
+		Str("template", templatedir).
+		Stringer("stack", stack.Dir).
+		Logger()
+
+	if err := checkCanCreate(root, stack); err != nil {
+		return err
+	}
+
+	logger.Trace().Msg("rendering template files")
+
+	files, err := renderTemplate(root, stack, templatedir, vars)
+	if err != nil {
+		return err
+	}
+
+	hostpath := stack.Dir.HostPath(root.HostDir())
+	hasStackFile := false
+	for i, file := range files {
+		if _, err := os.Lstat(filepath.Join(hostpath, file.relpath)); err == nil {
+			return errors.E(ErrTemplate, "template file %s already exists in the stack", file.relpath)
+		}
+		if file.relpath == DefaultFilename {
+			hasStackFile = true
+			if len(imports) > 0 {
+				buf := bytes.NewBuffer(file.content)
+				buf.WriteString("\n")
+				if err := hcl.PrintImports(buf, imports); err != nil {
+					return errors.E(err, "writing stack imports to stack file")
+				}
+				files[i].content = buf.Bytes()
+			}
+		}
+	}
+
+	// created are the files and directories created for the stack, in the
+	// order they were created, so they can be removed if the creation fails.
+	var created []string
+	defer func() {
+		if err == nil {
+			return
+		}
+		for i := len(created) - 1; i >= 0; i-- {
+			if errRemove := os.Remove(created[i]); errRemove != nil {
+				logger.Warn().Err(errRemove).Msgf("removing %s", created[i])
+			}
+		}
+	}()
+
+	created = append(created, missingDirs(hostpath)...)
+
+	if !hasStackFile {
+		logger.Trace().Msg("template has no stack file, creating it")
+
+		if err := Create(root, stack, imports...); err != nil {
+			return err
+		}
+		created = append(created, filepath.Join(hostpath, DefaultFilename))
+	}
+
+	for _, file := range files {
+		filename := filepath.Join(hostpath, file.relpath)
+		created = append(created, missingDirs(filepath.Dir(filename))...)
+		if err := os.MkdirAll(filepath.Dir(filename), createDirMode); err != nil {
+			return errors.E(err, "creating directory of template file %s", file.relpath)
+		}
+		if err := os.WriteFile(filename, file.content, file.mode); err != nil {
+			return errors.E(err, "writing template file %s", file.relpath)
+		}
+		created = append(created, filename)
+
+		logger.Trace().
+			Str("file", file.relpath).
+			Msg("template file created")
+	}
+
+	cfg, err := hcl.ParseDir(root.HostDir(), hostpath)
+	if err != nil {
+		return errors.E(ErrTemplate, err, "parsing the configuration of the created stack")
+	}
+	if cfg.Stack == nil {
+		return errors.E(ErrTemplate, "template file %s must define a stack block", DefaultFilename)
+	}
+	return nil
+}
+
+// missingDirs returns the dir and its parent directories which don't exist, from
+// the outermost to the innermost one.
+func missingDirs(dir string) []string {
+	var missing []string
+	for {
+		if _, err := os.Lstat(dir); err == nil {
+			break
+		}
+		missing = append([]string{dir}, missing...)
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	return missing
+}
+
+func renderTemplate(
+	root *config.Root,
+	stack config.Stack,
+	templatedir string,
+	vars map[string]string,
+) ([]renderedFile, error) {
+	evalctx, err := templateEvalCtx(root, stack, templatedir, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []renderedFile
+	err = filepath.WalkDir(templatedir, func(filename string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return errors.E(ErrTemplate, err)
+		}
+		if filename == templatedir {
+			if !d.IsDir() {
+				return errors.E(ErrTemplate, "template %s is not a directory", templatedir)
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		relpath, err := filepath.Rel(templatedir, filename)
+		if err != nil {
+			return errors.E(ErrTemplate, err)
+		}
+		if !d.Type().IsRegular() {
+			return errors.E(ErrTemplate, "template file %s is not a regular file", relpath)
+		}
+		content, err := os.ReadFile(filename)
+		if err != nil {
+			return errors.E(ErrTemplate, err, "reading template file %s", relpath)
+		}
+		rendered, err := renderTemplateFile(evalctx, filepath.ToSlash(relpath), content)
+		if err != nil {
+			return err
+		}
+		finfo, err := d.Info()
+		if err != nil {
+			return errors.E(ErrTemplate, err)
+		}
+		files = append(files, renderedFile{
+			relpath: relpath,
+			content: rendered,
+			mode:    finfo.Mode().Perm(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+func renderTemplateFile(evalctx *eval.Context, filename string, content []byte) ([]byte, error) {
+	expr, diags := hclsyntax.ParseTemplate(content, filename, hhcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, errors.E(ErrTemplate, diags, "parsing template file %s", filename)
+	}
+	val, err := evalctx.Eval(expr)
+	if err != nil {
+		return nil, errors.E(ErrTemplate, err, "rendering template file %s", filename)
+	}
+	val, err = convert.Convert(val, cty.String)
+	if err != nil || val.IsNull() || !val.IsKnown() {
+		return nil, errors.E(ErrTemplate, "template file %s must render to a string", filename)
+	}
+	return []byte(val.AsString()), nil
+}
+
+// templateEvalCtx returns the evaluation context of the templates of the
+// stack. The stack directory may not exist yet, then the globals are the ones
+// of its nearest parent directory in the configuration tree and the functions
+// accessing files are relative to the templatedir.
+func templateEvalCtx(
+	root *config.Root,
+	stack config.Stack,
+	templatedir string,
+	vars map[string]string,
+) (*eval.Context, error) {
+	globalsDir := stack.Dir
+	for {
+		if _, ok := root.Lookup(globalsDir); ok {
+			break
+		}
+		globalsDir = globalsDir.Dir()
+	}
+
+	evalctx := eval.NewContext(stdlib.Functions(templatedir))
+	runtime := root.Runtime()
+	runtime.Merge(stack.RuntimeValues(root))
+	evalctx.SetNamespace("terramate", runtime)
+
+	report := globals.ForDir(root, globalsDir, evalctx)
+	if err := report.AsError(); err != nil {
+		return nil, errors.E(ErrTemplate, err, "evaluating the globals of the stack")
+	}
+	evalctx.SetNamespace("global", report.Globals.AsValueMap())
+
+	varsVals := map[string]cty.Value{}
+	for name, value := range vars {
+		varsVals[name] = cty.StringVal(value)
+	}
+	evalctx.SetNamespace("var", varsVals)
+	return evalctx, nil
+}
@@ -0,0 +1,190 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package stack_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/config"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/project"
+	"github.com/terramate-io/terramate/stack"
+	"github.com/terramate-io/terramate/test"
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+func TestStackCreationFromTemplate(t *testing.T) {
+	t.Parallel()
+
+	type testcase struct {
+		name     string
+		layout   []string
+		template map[string]string
+		stack    config.Stack
+		vars     map[string]string
+		imports  []string
+		want     map[string]string
+		wantErr  error
+	}
+
+	const stackfile = `stack {
+  id   = "${terramate.stack.id}"
+  name = "${terramate.stack.name}"
+  tags = ["team-${var.team}"]
+}
+`
+
+	for _, tc := range []testcase{
+		{
+			name: "template without stack file",
+			template: map[string]string{
+				"main.tf": `# owner: ${var.owner} ${global.env}` + "\n" +
+					`resource "null_resource" "a" { triggers = { x = "$${var.x}" } }`,
+				"modules/README.md": "stack ${terramate.stack.path.absolute}",
+			},
+			layout: []string{`f:globals.tm.hcl:globals {
+			  env = "prod"
+			}`},
+			stack: config.Stack{Dir: project.NewPath("/stacks/app"), ID: "app-id", Name: "app"},
+			vars:  map[string]string{"owner": "platform"},
+			want: map[string]string{
+				"main.tf": `# owner: platform prod` + "\n" +
+					`resource "null_resource" "a" { triggers = { x = "${var.x}" } }`,
+				"modules/README.md": "stack /stacks/app",
+			},
+		},
+		{
+			name: "template with stack file",
+			template: map[string]string{
+				stack.DefaultFilename: stackfile,
+			},
+			stack:   config.Stack{Dir: project.NewPath("/app"), ID: "app-id", Name: "app"},
+			vars:    map[string]string{"team": "core"},
+			imports: []string{"/common/something.tm.hcl"},
+			want: map[string]string{
+				stack.DefaultFilename: `stack {
+  id   = "app-id"
+  name = "app"
+  tags = ["team-core"]
+}
+
+import {
+  source = "/common/something.tm.hcl"
+}
+
+`,
+			},
+		},
+		{
+			name: "undefined variable fails",
+			template: map[string]string{
+				"main.tf": "${var.undefined}",
+			},
+			stack:   config.Stack{Dir: project.NewPath("/app")},
+			wantErr: errors.E(stack.ErrTemplate),
+		},
+		{
+			name: "invalid template syntax fails",
+			template: map[string]string{
+				"main.tf": "${var.",
+			},
+			stack:   config.Stack{Dir: project.NewPath("/app")},
+			wantErr: errors.E(stack.ErrTemplate),
+		},
+		{
+			name: "stack file without stack block fails",
+			template: map[string]string{
+				stack.DefaultFilename: "# no stack here\n",
+			},
+			stack:   config.Stack{Dir: project.NewPath("/app")},
+			wantErr: errors.E(stack.ErrTemplate),
+		},
+		{
+			name: "invalid stack file removes the created directories",
+			template: map[string]string{
+				stack.DefaultFilename: "# no stack here\n",
+				"modules/vpc/main.tf": "# vpc",
+			},
+			stack:   config.Stack{Dir: project.NewPath("/stacks/app")},
+			wantErr: errors.E(stack.ErrTemplate),
+		},
+		{
+			name:   "invalid stack file keeps the existing directory",
+			layout: []string{"f:app/README.md:# app"},
+			template: map[string]string{
+				stack.DefaultFilename: "# no stack here\n",
+				"modules/vpc/main.tf": "# vpc",
+			},
+			stack:   config.Stack{Dir: project.NewPath("/app")},
+			wantErr: errors.E(stack.ErrTemplate),
+		},
+		{
+			name:   "existing file fails",
+			layout: []string{"f:app/main.tf:# existing"},
+			template: map[string]string{
+				"main.tf": "# template",
+			},
+			stack:   config.Stack{Dir: project.NewPath("/app")},
+			wantErr: errors.E(stack.ErrTemplate),
+		},
+		{
+			name:   "existing stack fails",
+			layout: []string{"s:app"},
+			template: map[string]string{
+				"main.tf": "# template",
+			},
+			stack:   config.Stack{Dir: project.NewPath("/app")},
+			wantErr: errors.E(stack.ErrStackAlreadyExists),
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := sandbox.NoGit(t)
+			s.BuildTree(tc.layout)
+			buildImportedFiles(t, s.RootDir(), tc.imports)
+
+			templatedir := t.TempDir()
+			for name, content := range tc.template {
+				test.WriteFile(t, templatedir, name, content)
+			}
+
+			root, err := config.LoadRoot(s.RootDir())
+			assert.NoError(t, err)
+
+			stackdir := tc.stack.Dir.HostPath(s.RootDir())
+			_, err = os.Stat(stackdir)
+			stackdirExists := err == nil
+
+			err = stack.CreateFromTemplate(root, tc.stack, templatedir, tc.vars, tc.imports...)
+			assert.IsError(t, err, tc.wantErr)
+
+			if tc.wantErr != nil {
+				if _, ok := tc.template[stack.DefaultFilename]; ok {
+					_, err := os.Stat(filepath.Join(stackdir, stack.DefaultFilename))
+					assert.IsTrue(t, os.IsNotExist(err), "stack file must not be created: %v", err)
+				}
+				if !stackdirExists {
+					_, err := os.Stat(stackdir)
+					assert.IsTrue(t, os.IsNotExist(err), "stack dir must be removed: %v", err)
+				} else {
+					_, err := os.Stat(filepath.Join(stackdir, "modules"))
+					assert.IsTrue(t, os.IsNotExist(err), "template dirs must be removed: %v", err)
+				}
+				return
+			}
+
+			for name, want := range tc.want {
+				test.AssertFileContentEquals(t, filepath.Join(stackdir, name), want)
+			}
+
+			got := s.LoadStack(tc.stack.Dir)
+			assert.EqualStrings(t, tc.stack.ID, got.ID)
+		})
+	}
+}
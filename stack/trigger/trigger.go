@@ -9,6 +9,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -43,6 +44,19 @@ type Info struct {
 	Type string
 	// Context is the context of the trigger (only `stack` at the moment)
 	Context string
+	// ExpiresAt is the unix timestamp of when the trigger expires, if any.
+	// A zero value means the trigger never expires.
+	ExpiresAt int64
+}
+
+// Trigger is a trigger file found in the project.
+type Trigger struct {
+	// Path is the project path of the trigger file.
+	Path project.Path
+	// Stack is the path of the triggered stack.
+	Stack project.Path
+	// Info is the parsed content of the trigger file.
+	Info Info
 }
 
 const (
@@ -112,6 +126,10 @@ func ParseFile(path string) (Info, error) {
 				Name:     "context",
 				Required: false,
 			},
+			{
+				Name:     "expires_at",
+				Required: false,
+			},
 		},
 	})
 
@@ -164,6 +182,13 @@ func ParseFile(path string) (Info, error) {
 			}
 			v, _ := val.AsBigFloat().Int64()
 			info.Ctime = v
+		case "expires_at":
+			if val.Type() != cty.Number {
+				errs.Append(errors.E(ErrParsing, "trigger: %s must be a number", attribute.Name))
+				continue
+			}
+			v, _ := val.AsBigFloat().Int64()
+			info.ExpiresAt = v
 		case "reason":
 			if val.Type() != cty.String {
 				errs.Append(errors.E(ErrParsing, "trigger: %s must be a string", attribute.Name))
@@ -191,6 +216,16 @@ func ParseFile(path string) (Info, error) {
 	return info, nil
 }
 
+// Age returns how long ago the trigger was created, relative to now.
+func (info Info) Age(now time.Time) time.Duration {
+	return now.Sub(time.Unix(info.Ctime, 0))
+}
+
+// Expired tells if the trigger is expired at the given time.
+func (info Info) Expired(now time.Time) bool {
+	return info.ExpiresAt != 0 && now.Unix() >= info.ExpiresAt
+}
+
 // Dir will return the triggers directory for the project rooted at rootdir.
 // Both rootdir and the returned value are host absolute paths.
 func Dir(rootdir string) string {
@@ -208,6 +243,12 @@ func triggerFilename() (string, error) {
 // Create creates a trigger for a stack with the given path and the given reason
 // inside the project rootdir.
 func Create(root *config.Root, path project.Path, reason string) error {
+	return CreateWithExpiration(root, path, reason, time.Time{})
+}
+
+// CreateWithExpiration creates a trigger like [Create] but the trigger expires
+// at the given time. A zero expiresAt creates a trigger that never expires.
+func CreateWithExpiration(root *config.Root, path project.Path, reason string, expiresAt time.Time) error {
 	tree, ok := root.Lookup(path)
 	if !ok || !tree.IsStack() {
 		return errors.E(ErrTrigger, "path %s is not a stack directory", path)
@@ -229,6 +270,9 @@ func Create(root *config.Root, path project.Path, reason string) error {
 	triggerBody.SetAttributeValue("reason", cty.StringVal(reason))
 	triggerBody.SetAttributeRaw("type", hclwrite.TokensForIdentifier(DefaultType))
 	triggerBody.SetAttributeRaw("context", hclwrite.TokensForIdentifier(DefaultContext))
+	if !expiresAt.IsZero() {
+		triggerBody.SetAttributeValue("expires_at", cty.NumberIntVal(expiresAt.Unix()))
+	}
 
 	triggerPath := filepath.Join(triggerDir, filename)
 
@@ -244,3 +288,157 @@ func Create(root *config.Root, path project.Path, reason string) error {
 
 	return nil
 }
+
+// List returns all the triggers of the project, sorted by stack and creation
+// time.
+func List(root *config.Root) ([]Trigger, error) {
+	rootdir := root.HostDir()
+	triggersRoot := Dir(rootdir)
+
+	var triggers []Trigger
+	err := filepath.WalkDir(triggersRoot, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if path == triggersRoot && os.IsNotExist(err) {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if !d.Type().IsRegular() || !strings.HasSuffix(d.Name(), ".tm.hcl") {
+			return nil
+		}
+		info, err := ParseFile(path)
+		if err != nil {
+			return errors.E(err, "parsing trigger file %s", path)
+		}
+		triggerPath := project.PrjAbsPath(rootdir, path)
+		stackPath, _ := StackPath(triggerPath)
+		triggers = append(triggers, Trigger{
+			Path:  triggerPath,
+			Stack: stackPath,
+			Info:  info,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, errors.E(err, "listing triggers")
+	}
+
+	sort.SliceStable(triggers, func(i, j int) bool {
+		if triggers[i].Stack != triggers[j].Stack {
+			return triggers[i].Stack.String() < triggers[j].Stack.String()
+		}
+		if triggers[i].Info.Ctime != triggers[j].Info.Ctime {
+			return triggers[i].Info.Ctime < triggers[j].Info.Ctime
+		}
+		return triggers[i].Path.String() < triggers[j].Path.String()
+	})
+	return triggers, nil
+}
+
+// Remove removes the trigger file and the trigger directories left empty
+// by its removal.
+func Remove(root *config.Root, t Trigger) error {
+	rootdir := root.HostDir()
+	triggerPath := filepath.Join(rootdir, filepath.FromSlash(t.Path.String()))
+	if err := os.Remove(triggerPath); err != nil {
+		return errors.E(err, "removing trigger file %s", t.Path)
+	}
+
+	if err := removeEmptyDirs(rootdir, filepath.Dir(triggerPath)); err != nil {
+		return err
+	}
+
+	log.Debug().
+		Str("action", "trigger.Remove").
+		Stringer("trigger", t.Path).
+		Msg("trigger file removed")
+
+	return nil
+}
+
+// MoveStack moves the triggers of the stack at src, and of its child stacks,
+// to the stack at dst. It returns false if there are no triggers to move.
+func MoveStack(root *config.Root, src, dst project.Path) (bool, error) {
+	rootdir := root.HostDir()
+	srcdir := filepath.Join(Dir(rootdir), filepath.FromSlash(src.String()))
+	dstdir := filepath.Join(Dir(rootdir), filepath.FromSlash(dst.String()))
+
+	if _, err := os.Stat(srcdir); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, errors.E(err, "stating triggers dir %s", srcdir)
+	}
+	if _, err := os.Stat(dstdir); err == nil {
+		return false, errors.E(ErrTrigger, "triggers dir %s already exists", dstdir)
+	}
+	if err := os.MkdirAll(filepath.Dir(dstdir), 0775); err != nil {
+		return false, errors.E(err, "creating trigger dir")
+	}
+	if err := os.Rename(srcdir, dstdir); err != nil {
+		return false, errors.E(err, "moving triggers dir %s to %s", srcdir, dstdir)
+	}
+
+	if err := removeEmptyDirs(rootdir, filepath.Dir(srcdir)); err != nil {
+		return false, err
+	}
+
+	log.Debug().
+		Str("action", "trigger.MoveStack").
+		Stringer("src", src).
+		Stringer("dst", dst).
+		Msg("triggers moved")
+
+	return true, nil
+}
+
+// RemoveStack removes the triggers of the stack, and of its child stacks.
+// It returns false if there are no triggers to remove.
+func RemoveStack(root *config.Root, stack project.Path) (bool, error) {
+	rootdir := root.HostDir()
+	stackdir := filepath.Join(Dir(rootdir), filepath.FromSlash(stack.String()))
+
+	if _, err := os.Stat(stackdir); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, errors.E(err, "stating triggers dir %s", stackdir)
+	}
+	if err := os.RemoveAll(stackdir); err != nil {
+		return false, errors.E(err, "removing triggers dir %s", stackdir)
+	}
+	if stackdir != Dir(rootdir) {
+		if err := removeEmptyDirs(rootdir, filepath.Dir(stackdir)); err != nil {
+			return false, err
+		}
+	}
+
+	log.Debug().
+		Str("action", "trigger.RemoveStack").
+		Stringer("stack", stack).
+		Msg("triggers removed")
+
+	return true, nil
+}
+
+// removeEmptyDirs removes the dir and its parents, up to the triggers dir,
+// while they are empty.
+func removeEmptyDirs(rootdir, dir string) error {
+	triggersRoot := Dir(rootdir)
+	for ; dir != rootdir; dir = filepath.Dir(dir) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return errors.E(err, "reading trigger dir %s", dir)
+		}
+		if len(entries) > 0 {
+			break
+		}
+		if err := os.Remove(dir); err != nil {
+			return errors.E(err, "removing empty trigger dir %s", dir)
+		}
+		if dir == triggersRoot {
+			break
+		}
+	}
+	return nil
+}
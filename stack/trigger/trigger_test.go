@@ -6,8 +6,10 @@ package trigger_test
 import (
 	"fmt"
 	"math"
+	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/madlambda/spells/assert"
 	"github.com/terramate-io/terramate/config"
@@ -229,6 +231,25 @@ func TestTriggerParser(t *testing.T) {
 			),
 			err: errors.E(trigger.ErrParsing),
 		},
+		{
+			name: "valid file with expiration",
+			body: Trigger(
+				Number("ctime", 1000000),
+				Str("reason", "something"),
+				Expr("type", "changed"),
+				Expr("context", "stack"),
+				Number("expires_at", 2000000),
+			),
+		},
+		{
+			name: "expires_at not number",
+			body: Trigger(
+				Number("ctime", 1000000),
+				Str("reason", "something"),
+				Str("expires_at", "2000000"),
+			),
+			err: errors.E(trigger.ErrParsing),
+		},
 		{
 			name: "context not a keyword",
 			body: Trigger(
@@ -248,6 +269,79 @@ func TestTriggerParser(t *testing.T) {
 	}
 }
 
+func TestTriggerExpiration(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{"s:stack"})
+	root, err := config.LoadRoot(s.RootDir())
+	assert.NoError(t, err)
+
+	expiresAt := time.Now().Add(time.Hour)
+	err = trigger.CreateWithExpiration(root, project.NewPath("/stack"), "expiring", expiresAt)
+	assert.NoError(t, err)
+
+	triggers, err := trigger.List(root)
+	assert.NoError(t, err)
+	assert.EqualInts(t, 1, len(triggers))
+
+	info := triggers[0].Info
+	assert.IsTrue(t, info.ExpiresAt == expiresAt.Unix(), "want expires_at %d but got %d", expiresAt.Unix(), info.ExpiresAt)
+	assert.IsTrue(t, !info.Expired(time.Now()))
+	assert.IsTrue(t, info.Expired(expiresAt))
+	assert.IsTrue(t, info.Expired(expiresAt.Add(time.Second)))
+	assert.IsTrue(t, !trigger.Info{Ctime: 1000000}.Expired(time.Now()), "trigger without expiration must never expire")
+}
+
+func TestTriggerListAndRemove(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		"s:stack-b",
+		"s:stack-a",
+		"s:dir/stack-c",
+	})
+	root, err := config.LoadRoot(s.RootDir())
+	assert.NoError(t, err)
+
+	triggers, err := trigger.List(root)
+	assert.NoError(t, err)
+	assert.EqualInts(t, 0, len(triggers), "no triggers expected on a project without .tmtriggers")
+
+	for _, stack := range []string{"/stack-b", "/dir/stack-c", "/stack-a"} {
+		err := trigger.Create(root, project.NewPath(stack), "reason "+stack)
+		assert.NoError(t, err)
+	}
+
+	triggers, err = trigger.List(root)
+	assert.NoError(t, err)
+	assert.EqualInts(t, 3, len(triggers))
+
+	for i, want := range []string{"/dir/stack-c", "/stack-a", "/stack-b"} {
+		assert.EqualStrings(t, want, triggers[i].Stack.String())
+		assert.EqualStrings(t, "reason "+want, triggers[i].Info.Reason)
+
+		gotStack, ok := trigger.StackPath(triggers[i].Path)
+		assert.IsTrue(t, ok)
+		assert.EqualStrings(t, want, gotStack.String())
+	}
+
+	assert.NoError(t, trigger.Remove(root, triggers[0]))
+	_, err = os.Stat(filepath.Join(trigger.Dir(root.HostDir()), "dir"))
+	assert.IsTrue(t, os.IsNotExist(err), "empty trigger dirs must be removed: %v", err)
+
+	triggers, err = trigger.List(root)
+	assert.NoError(t, err)
+	assert.EqualInts(t, 2, len(triggers))
+
+	for _, tr := range triggers {
+		assert.NoError(t, trigger.Remove(root, tr))
+	}
+	_, err = os.Stat(trigger.Dir(root.HostDir()))
+	assert.IsTrue(t, os.IsNotExist(err), "empty triggers dir must be removed: %v", err)
+}
+
 func init() {
 	zerolog.SetGlobalLevel(zerolog.Disabled)
 }
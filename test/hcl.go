@@ -67,6 +67,7 @@ func AssertTerramateConfig(t *testing.T, got, want hcl.Config) {
 	AssertDiff(t, got.Vendor, want.Vendor, "terramate vendor")
 	assertGenHCLBlocks(t, got.Generate.HCLs, want.Generate.HCLs)
 	assertGenFileBlocks(t, got.Generate.Files, want.Generate.Files)
+	assertScriptBlocks(t, got.Scripts, want.Scripts)
 }
 
 // AssertDiff will compare the two values and fail if they are not the same
@@ -270,6 +271,37 @@ func assertGenFileBlocks(t *testing.T, got, want []hcl.GenFileBlock) {
 	}
 }
 
+func assertScriptBlocks(t *testing.T, got, want []hcl.Script) {
+	t.Helper()
+
+	assert.EqualInts(t, len(want), len(got), "script blocks differ in len")
+
+	for i, gotScript := range got {
+		wantScript := want[i]
+		assert.EqualStrings(t, wantScript.Name, gotScript.Name, "script name differs")
+		assert.EqualStrings(t,
+			attrExprAsStr(t, wantScript.Description), attrExprAsStr(t, gotScript.Description),
+			"script %q description differs", wantScript.Name)
+		assert.EqualInts(t, len(wantScript.Jobs), len(gotScript.Jobs),
+			"script %q jobs differ in len", wantScript.Name)
+
+		for j, gotJob := range gotScript.Jobs {
+			assert.EqualStrings(t,
+				attrExprAsStr(t, wantScript.Jobs[j].Commands), attrExprAsStr(t, gotJob.Commands),
+				"script %q job %d commands differ", wantScript.Name, j)
+		}
+	}
+}
+
+func attrExprAsStr(t *testing.T, attr *ast.Attribute) string {
+	t.Helper()
+
+	if attr == nil {
+		return ""
+	}
+	return exprAsStr(t, attr.Expr)
+}
+
 func assertTerramateRunBlock(t *testing.T, got, want *hcl.RunConfig) {
 	t.Helper()
 
@@ -284,6 +316,25 @@ func assertTerramateRunBlock(t *testing.T, got, want *hcl.RunConfig) {
 	assert.IsTrue(t, want.CheckGenCode == got.CheckGenCode,
 		"want.Run.CheckGenCode %v != got.Run.CheckGenCode %v",
 		want.CheckGenCode, got.CheckGenCode)
+	assert.IsTrue(t, want.InferOrder == got.InferOrder,
+		"want.Run.InferOrder %v != got.Run.InferOrder %v",
+		want.InferOrder, got.InferOrder)
+
+	assert.EqualStrings(t,
+		attrExprAsStr(t, want.Before), attrExprAsStr(t, got.Before),
+		"run.before differs")
+	assert.EqualStrings(t,
+		attrExprAsStr(t, want.After), attrExprAsStr(t, got.After),
+		"run.after differs")
+	assert.EqualStrings(t,
+		attrExprAsStr(t, want.BeforeAll), attrExprAsStr(t, got.BeforeAll),
+		"run.before_all differs")
+	assert.EqualStrings(t,
+		attrExprAsStr(t, want.AfterAll), attrExprAsStr(t, got.AfterAll),
+		"run.after_all differs")
+
+	AssertDiff(t, got.Retry, want.Retry, "run.retry differs")
+	AssertDiff(t, got.Timeout, want.Timeout, "run.timeout differs")
 
 	if (want.Env == nil) != (got.Env == nil) {
 		t.Fatalf(
@@ -347,6 +398,10 @@ func assertStackBlock(t *testing.T, got, want *hcl.Stack) {
 		return
 	}
 
+	if got.Timeout != want.Timeout {
+		t.Fatalf("want stack timeout %s but got %s", want.Timeout, got.Timeout)
+	}
+
 	assert.EqualInts(t, len(got.After), len(want.After), "After length mismatch")
 
 	for i, w := range want.After {
@@ -161,6 +161,16 @@ func Assert(builders ...hclwrite.BlockBuilder) *hclwrite.Block {
 	return Block("assert", builders...)
 }
 
+// Script is a helper for a "script" block.
+func Script(builders ...hclwrite.BlockBuilder) *hclwrite.Block {
+	return Block("script", builders...)
+}
+
+// Job is a helper for a "job" block.
+func Job(builders ...hclwrite.BlockBuilder) *hclwrite.Block {
+	return Block("job", builders...)
+}
+
 // Trigger is a helper for a "trigger" block.
 func Trigger(builders ...hclwrite.BlockBuilder) *hclwrite.Block {
 	return Block("trigger", builders...)
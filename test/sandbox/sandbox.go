@@ -22,6 +22,7 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/madlambda/spells/assert"
 	"github.com/terramate-io/terramate/config"
@@ -649,6 +650,10 @@ func buildTree(t testing.TB, root *config.Root, environ []string, layout []strin
 				cfg.Stack.Description = value
 			case "tags":
 				cfg.Stack.Tags = parseListSpec(t, name, value)
+			case "timeout":
+				timeout, err := time.ParseDuration(value)
+				assert.NoError(t, err, "invalid stack timeout %q", value)
+				cfg.Stack.Timeout = timeout
 			default:
 				t.Fatalf("attribute " + parts[0] + " not supported.")
 			}
@@ -0,0 +1,271 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package tf
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/rs/zerolog/log"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// StateConfig is the configuration related to the Terraform state found in a
+// file: the backend where the state is stored and the remote states it reads.
+type StateConfig struct {
+	// Backend is the terraform.backend or terraform.cloud block, if any.
+	Backend *Backend
+
+	// RemoteStates are the terraform_remote_state data sources.
+	RemoteStates []RemoteState
+}
+
+// Backend is a Terraform backend configuration.
+// Only the attributes which are known statically are kept in the Config map,
+// the attributes of nested blocks and objects have dot separated names,
+// eg.: workspaces.name.
+type Backend struct {
+	Type   string
+	Config map[string]string
+	Range  hcl.Range
+}
+
+// RemoteState is a terraform_remote_state data source.
+// The Config map follows the same rules of the [Backend] Config.
+type RemoteState struct {
+	Name      string
+	Backend   string
+	Workspace string
+	Config    map[string]string
+	Range     hcl.Range
+}
+
+// stateKeyAttrs are the backend attributes which identify the location of the
+// state, indexed by the backend type. All the required attributes must be
+// known to identify the state.
+var stateKeyAttrs = map[string]struct {
+	required []string
+	optional []string
+}{
+	"azurerm":    {required: []string{"storage_account_name", "container_name", "key"}},
+	"consul":     {required: []string{"path"}, optional: []string{"address"}},
+	"gcs":        {required: []string{"bucket"}, optional: []string{"prefix"}},
+	"http":       {required: []string{"address"}},
+	"kubernetes": {required: []string{"secret_suffix"}, optional: []string{"namespace"}},
+	"local":      {optional: []string{"path"}},
+	"pg":         {required: []string{"conn_str"}, optional: []string{"schema_name"}},
+	"remote":     {required: []string{"organization", "workspaces.name"}},
+	"s3":         {required: []string{"bucket", "key"}},
+}
+
+// ParseStateConfig parses the backend block and the terraform_remote_state
+// data sources of the file defined by path.
+func ParseStateConfig(path string) (StateConfig, error) {
+	logger := log.With().
+		Str("action", "ParseStateConfig()").
+		Str("path", path).
+		Logger()
+
+	p := hclparse.NewParser()
+
+	logger.Debug().Msg("Parse HCL file")
+
+	f, diags := p.ParseHCLFile(path)
+	if diags.HasErrors() {
+		return StateConfig{}, errors.E(ErrHCLSyntax, diags)
+	}
+
+	body := f.Body.(*hclsyntax.Body)
+
+	var cfg StateConfig
+	for _, block := range body.Blocks {
+		switch block.Type {
+		case "terraform":
+			for _, block := range block.Body.Blocks {
+				var backendType string
+				switch {
+				case block.Type == "backend" && len(block.Labels) == 1:
+					backendType = block.Labels[0]
+				case block.Type == "cloud" && len(block.Labels) == 0:
+					// the cloud block stores the state in the same place
+					// as the remote backend.
+					backendType = "remote"
+				default:
+					continue
+				}
+
+				logger.Trace().
+					Str("type", backendType).
+					Msg("found a backend block")
+
+				backend := &Backend{
+					Type:   backendType,
+					Config: map[string]string{},
+					Range:  block.Range(),
+				}
+				flattenBody("", block.Body, backend.Config)
+				cfg.Backend = backend
+			}
+		case "data":
+			if len(block.Labels) != 2 || block.Labels[0] != "terraform_remote_state" {
+				continue
+			}
+
+			logger.Trace().
+				Str("name", block.Labels[1]).
+				Msg("found a terraform_remote_state data source")
+
+			cfg.RemoteStates = append(cfg.RemoteStates, parseRemoteState(block))
+		}
+	}
+	return cfg, nil
+}
+
+func parseRemoteState(block *hclsyntax.Block) RemoteState {
+	rs := RemoteState{
+		Name:   block.Labels[1],
+		Config: map[string]string{},
+		Range:  block.Range(),
+	}
+
+	for name, attr := range block.Body.Attributes {
+		switch name {
+		case "backend":
+			if val, ok := staticString(attr.Expr); ok {
+				rs.Backend = val
+			}
+		case "workspace":
+			if val, ok := staticString(attr.Expr); ok {
+				rs.Workspace = val
+			} else {
+				// the state can't be known statically.
+				rs.Workspace = "<unknown>"
+			}
+		case "config":
+			flattenExpr("", attr.Expr, rs.Config)
+		}
+	}
+	return rs
+}
+
+// StateKey returns a key which identifies the location of the state defined
+// by the backend type and configuration. The dir is the host directory where
+// the configuration is defined and it's used to locate the local states.
+// It returns false if the location cannot be determined statically.
+func StateKey(backendType string, config map[string]string, dir string) (string, bool) {
+	attrs, ok := stateKeyAttrs[backendType]
+	if !ok {
+		return "", false
+	}
+
+	var parts []string
+	for _, name := range attrs.required {
+		val, ok := config[name]
+		if !ok {
+			return "", false
+		}
+		parts = append(parts, name+"="+val)
+	}
+	for _, name := range attrs.optional {
+		val := config[name]
+		if backendType == "local" && name == "path" {
+			if val == "" {
+				val = "terraform.tfstate"
+			}
+			if !filepath.IsAbs(val) {
+				val = filepath.Join(dir, val)
+			}
+			val = filepath.ToSlash(filepath.Clean(val))
+		}
+		parts = append(parts, name+"="+val)
+	}
+	sort.Strings(parts)
+	return backendType + ":" + strings.Join(parts, ","), true
+}
+
+// Key returns the key of the location of the backend state.
+// See [StateKey].
+func (b Backend) Key(dir string) (string, bool) {
+	return StateKey(b.Type, b.Config, dir)
+}
+
+// Key returns the key of the location of the state read by the remote state.
+// See [StateKey].
+func (rs RemoteState) Key(dir string) (string, bool) {
+	if rs.Workspace != "" && rs.Workspace != "default" {
+		return "", false
+	}
+	return StateKey(rs.Backend, rs.Config, dir)
+}
+
+func flattenBody(prefix string, body *hclsyntax.Body, res map[string]string) {
+	for name, attr := range body.Attributes {
+		flattenExpr(prefix+name, attr.Expr, res)
+	}
+	for _, block := range body.Blocks {
+		flattenBody(prefix+block.Type+".", block.Body, res)
+	}
+}
+
+// flattenExpr adds the values of the expression which can be evaluated
+// statically to res. An object which cannot be evaluated as a whole has each
+// one of its items evaluated separately.
+func flattenExpr(name string, expr hclsyntax.Expression, res map[string]string) {
+	val, diags := expr.Value(nil)
+	if !diags.HasErrors() {
+		flattenValue(name, val, res)
+		return
+	}
+
+	obj, ok := expr.(*hclsyntax.ObjectConsExpr)
+	if !ok {
+		return
+	}
+	for _, item := range obj.Items {
+		key, diags := item.KeyExpr.Value(nil)
+		if diags.HasErrors() || key.Type() != cty.String || !key.IsKnown() || key.IsNull() {
+			continue
+		}
+		flattenExpr(joinName(name, key.AsString()), item.ValueExpr, res)
+	}
+}
+
+func flattenValue(name string, val cty.Value, res map[string]string) {
+	if !val.IsWhollyKnown() || val.IsNull() {
+		return
+	}
+	if val.Type().IsObjectType() || val.Type().IsMapType() {
+		for it := val.ElementIterator(); it.Next(); {
+			key, elem := it.Element()
+			flattenValue(joinName(name, key.AsString()), elem, res)
+		}
+		return
+	}
+	str, err := convert.Convert(val, cty.String)
+	if err != nil || name == "" {
+		return
+	}
+	res[name] = str.AsString()
+}
+
+func joinName(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+func staticString(expr hclsyntax.Expression) (string, bool) {
+	val, diags := expr.Value(nil)
+	if diags.HasErrors() || !val.IsWhollyKnown() || val.IsNull() || val.Type() != cty.String {
+		return "", false
+	}
+	return val.AsString(), true
+}
@@ -0,0 +1,227 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package tf_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/test"
+	"github.com/terramate-io/terramate/tf"
+)
+
+func TestParseStateConfig(t *testing.T) {
+	t.Parallel()
+
+	type (
+		remoteState struct {
+			name string
+			key  string
+		}
+		testcase struct {
+			name         string
+			body         string
+			backendKey   string
+			remoteStates []remoteState
+		}
+	)
+
+	for _, tc := range []testcase{
+		{
+			name: "no backend and no remote states",
+			body: `resource "null_resource" "a" {}`,
+		},
+		{
+			name: "s3 backend",
+			body: `
+				terraform {
+				  backend "s3" {
+				    bucket = "states"
+				    key    = "network/terraform.tfstate"
+				    region = "us-east-1"
+				  }
+				}
+			`,
+			backendKey: "s3:bucket=states,key=network/terraform.tfstate",
+		},
+		{
+			name: "s3 backend without key is unknown",
+			body: `
+				terraform {
+				  backend "s3" {
+				    bucket = "states"
+				  }
+				}
+			`,
+		},
+		{
+			name: "remote backend with nested workspaces block",
+			body: `
+				terraform {
+				  backend "remote" {
+				    organization = "org"
+				    workspaces {
+				      name = "network"
+				    }
+				  }
+				}
+			`,
+			backendKey: "remote:organization=org,workspaces.name=network",
+		},
+		{
+			name: "cloud block is the same as the remote backend",
+			body: `
+				terraform {
+				  cloud {
+				    organization = "org"
+				    workspaces {
+				      name = "network"
+				    }
+				  }
+				}
+			`,
+			backendKey: "remote:organization=org,workspaces.name=network",
+		},
+		{
+			name: "local backend uses the default path",
+			body: `
+				terraform {
+				  backend "local" {}
+				}
+			`,
+			backendKey: "local:path=<dir>/terraform.tfstate",
+		},
+		{
+			name: "remote states",
+			body: `
+				data "terraform_remote_state" "network" {
+				  backend = "s3"
+				  config = {
+				    bucket = "states"
+				    key    = "network/terraform.tfstate"
+				    region = var.region
+				  }
+				}
+
+				data "terraform_remote_state" "db" {
+				  backend = "remote"
+				  config = {
+				    organization = "org"
+				    workspaces = {
+				      name = "db"
+				    }
+				  }
+				}
+
+				data "terraform_remote_state" "local" {
+				  backend = "local"
+				  config = {
+				    path = "../network/terraform.tfstate"
+				  }
+				}
+			`,
+			remoteStates: []remoteState{
+				{
+					name: "network",
+					key:  "s3:bucket=states,key=network/terraform.tfstate",
+				},
+				{
+					name: "db",
+					key:  "remote:organization=org,workspaces.name=db",
+				},
+				{
+					name: "local",
+					key:  "local:path=<parentdir>/network/terraform.tfstate",
+				},
+			},
+		},
+		{
+			name: "remote states with unknown location",
+			body: `
+				data "terraform_remote_state" "dynamic_key" {
+				  backend = "s3"
+				  config = {
+				    bucket = "states"
+				    key    = "${var.env}/terraform.tfstate"
+				  }
+				}
+
+				data "terraform_remote_state" "other_workspace" {
+				  backend   = "s3"
+				  workspace = "prod"
+				  config = {
+				    bucket = "states"
+				    key    = "network/terraform.tfstate"
+				  }
+				}
+
+				data "terraform_remote_state" "unsupported_backend" {
+				  backend = "unknown"
+				  config = {
+				    bucket = "states"
+				  }
+				}
+			`,
+			remoteStates: []remoteState{
+				{name: "dynamic_key"},
+				{name: "other_workspace"},
+				{name: "unsupported_backend"},
+			},
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			configdir := t.TempDir()
+			tfpath := test.WriteFile(t, configdir, "main.tf", tc.body)
+
+			replaceDirs := func(key string) string {
+				return replacePlaceholders(key, map[string]string{
+					"<dir>":       filepath.ToSlash(configdir),
+					"<parentdir>": filepath.ToSlash(filepath.Dir(configdir)),
+				})
+			}
+
+			cfg, err := tf.ParseStateConfig(tfpath)
+			assert.NoError(t, err)
+
+			if cfg.Backend == nil {
+				assert.EqualStrings(t, "", tc.backendKey, "backend not found")
+			} else {
+				key, _ := cfg.Backend.Key(configdir)
+				assert.EqualStrings(t, replaceDirs(tc.backendKey), key, "backend key mismatch")
+			}
+
+			assert.EqualInts(t, len(tc.remoteStates), len(cfg.RemoteStates), "remote states mismatch")
+			for i, want := range tc.remoteStates {
+				got := cfg.RemoteStates[i]
+				assert.EqualStrings(t, want.name, got.Name, "remote state %d name mismatch", i)
+
+				key, ok := got.Key(configdir)
+				assert.IsTrue(t, ok == (want.key != ""), "remote state %s: unexpected key %q", got.Name, key)
+				assert.EqualStrings(t, replaceDirs(want.key), key, "remote state %s key mismatch", got.Name)
+			}
+		})
+	}
+}
+
+func TestParseStateConfigSyntaxError(t *testing.T) {
+	t.Parallel()
+
+	configdir := t.TempDir()
+	tfpath := test.WriteFile(t, configdir, "main.tf", `terraform {`)
+	_, err := tf.ParseStateConfig(tfpath)
+	assert.IsError(t, err, errors.E(tf.ErrHCLSyntax))
+}
+
+func replacePlaceholders(s string, values map[string]string) string {
+	for placeholder, value := range values {
+		s = strings.ReplaceAll(s, placeholder, value)
+	}
+	return s
+}
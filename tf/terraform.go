@@ -4,7 +4,9 @@
 package tf
 
 import (
+	"net/url"
 	"os"
+	"strings"
 
 	"github.com/hashicorp/hcl/v2/hclparse"
 	"github.com/hashicorp/hcl/v2/hclsyntax"
@@ -17,7 +19,9 @@ import (
 // Module represents a terraform module.
 // Note that only the fields relevant for terramate are declared here.
 type Module struct {
-	Source string // Source is the module source path (eg.: directory, git path, etc).
+	Name    string // Name is the module block label.
+	Source  string // Source is the module source path (eg.: directory, git path, etc).
+	Version string // Version is the version constraint of registry modules, if any.
 }
 
 // ErrHCLSyntax represents a HCL syntax error
@@ -30,6 +34,20 @@ func (m Module) IsLocal() bool {
 		(len(m.Source) >= 3 && m.Source[0:3] == "../")
 }
 
+// Ref returns the ref argument of the module source, if any.
+// Eg.: v1.2.0 for git::https://example.com/vpc.git?ref=v1.2.0
+func (m Module) Ref() string {
+	_, query, found := strings.Cut(m.Source, "?")
+	if !found {
+		return ""
+	}
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return ""
+	}
+	return values.Get("ref")
+}
+
 // ParseModules parses blocks of type "module" containing a single label.
 func ParseModules(path string) ([]Module, error) {
 	logger := log.With().
@@ -55,7 +73,25 @@ func ParseModules(path string) ([]Module, error) {
 		return nil, errors.E(ErrHCLSyntax, diags)
 	}
 
-	body := f.Body.(*hclsyntax.Body)
+	return parseModules(f.Body.(*hclsyntax.Body), path), nil
+}
+
+// ParseModulesContent parses blocks of type "module" containing a single label
+// from the content of a file. The filename is only used in the errors.
+func ParseModulesContent(filename string, content []byte) ([]Module, error) {
+	p := hclparse.NewParser()
+	f, diags := p.ParseHCL(content, filename)
+	if diags.HasErrors() {
+		return nil, errors.E(ErrHCLSyntax, diags)
+	}
+	return parseModules(f.Body.(*hclsyntax.Body), filename), nil
+}
+
+func parseModules(body *hclsyntax.Body, path string) []Module {
+	logger := log.With().
+		Str("action", "parseModules()").
+		Str("path", path).
+		Logger()
 
 	logger.Trace().Msg("Parse modules")
 
@@ -92,10 +128,23 @@ func ParseModules(path string) ([]Module, error) {
 
 			continue
 		}
-		modules = append(modules, Module{Source: source})
+
+		logger.Trace().Msg("Get version attribute.")
+		version, _, err := findStringAttr(block, "version")
+		if err != nil {
+			logger.Debug().
+				Err(err).
+				Msg("ignoring module version which is not a string")
+		}
+
+		modules = append(modules, Module{
+			Name:    moduleName,
+			Source:  source,
+			Version: version,
+		})
 	}
 
-	return modules, nil
+	return modules
 }
 
 // IsStack tells if the file defined by path is a potential stack.
@@ -23,6 +23,7 @@ func TestHCLParserModules(t *testing.T) {
 	type (
 		want struct {
 			modules []tf.Module
+			refs    []string
 			errs    []error
 		}
 
@@ -134,6 +135,43 @@ module "bleh" {
 				},
 			},
 		},
+		{
+			name: "module version and source ref",
+			input: cfgfile{
+				filename: "main.tf",
+				body: `
+module "vpc" {
+	source  = "terraform-aws-modules/vpc/aws"
+	version = "5.1.0"
+}
+module "network" {
+	source = "git::https://example.com/network.git//modules/vpc?depth=1&ref=v1.2.0"
+}
+module "version_is_not_string" {
+	source  = "test"
+	version = 1
+}
+`,
+			},
+			want: want{
+				modules: []tf.Module{
+					{
+						Name:    "vpc",
+						Source:  "terraform-aws-modules/vpc/aws",
+						Version: "5.1.0",
+					},
+					{
+						Name:   "network",
+						Source: "git::https://example.com/network.git//modules/vpc?depth=1&ref=v1.2.0",
+					},
+					{
+						Name:   "version_is_not_string",
+						Source: "test",
+					},
+				},
+				refs: []string{"", "v1.2.0", ""},
+			},
+		},
 		{
 			name: "ignored if source is not a string",
 			input: cfgfile{
@@ -213,6 +251,25 @@ module "test" {
 			for i := 0; i < len(tc.want.modules); i++ {
 				assert.EqualStrings(t, tc.want.modules[i].Source, modules[i].Source,
 					"module source mismatch")
+				assert.EqualStrings(t, tc.want.modules[i].Version, modules[i].Version,
+					"module version mismatch")
+
+				if tc.want.refs != nil {
+					assert.EqualStrings(t, tc.want.modules[i].Name, modules[i].Name,
+						"module name mismatch")
+					assert.EqualStrings(t, tc.want.refs[i], modules[i].Ref(),
+						"module ref mismatch")
+				}
+			}
+
+			if err == nil {
+				fromContent, err := tf.ParseModulesContent(tc.input.filename, []byte(tc.input.body))
+				assert.NoError(t, err)
+				assert.EqualInts(t, len(modules), len(fromContent), "modules from content mismatch")
+				for i := range modules {
+					assert.IsTrue(t, modules[i] == fromContent[i],
+						"module from content mismatch: %v != %v", modules[i], fromContent[i])
+				}
 			}
 		})
 	}